@@ -39,13 +39,14 @@ func GetAllApiGroupByCategoryByRoleId(c *gin.Context) {
 	// 创建服务
 	s := cache_service.New(c)
 	// 绑定参数
-	apis, ids, err := s.GetAllApiGroupByCategoryByRoleId(utils.Str2Uint(c.Param("roleId")))
+	apis, ids, denyIds, err := s.GetAllApiGroupByCategoryByRoleId(utils.Str2Uint(c.Param("roleId")))
 	if err != nil {
 		response.FailWithMsg(err.Error())
 		return
 	}
 	var resp response.ApiTreeWithAccessResponseStruct
 	resp.AccessIds = ids
+	resp.DenyIds = denyIds
 	utils.Struct2StructByJson(apis, &resp.List)
 	response.SuccessWithData(resp)
 }
@@ -110,3 +111,24 @@ func BatchDeleteApiByIds(c *gin.Context) {
 	}
 	response.Success()
 }
+
+// 权限检查, 解释用户或角色能否访问指定接口
+func ExplainPermission(c *gin.Context) {
+	// 绑定参数
+	var req request.PermissionExplainRequestStruct
+	_ = c.Bind(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	resp, err := s.ExplainPermission(&req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.SuccessWithData(resp)
+}
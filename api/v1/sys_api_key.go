@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"gin-web/pkg/response"
+	"gin-web/pkg/service"
+	"gin-web/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 获取当前用户的API密钥列表
+func GetApiKeys(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 创建服务
+	s := service.New(c)
+	keys, err := s.GetApiKeysByUserId(user.Id)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 转为ResponseStruct, 隐藏部分字段
+	var respStruct []response.ApiKeyListResponseStruct
+	utils.Struct2StructByJson(keys, &respStruct)
+	response.SuccessWithData(respStruct)
+}
+
+// 创建API密钥
+func CreateApiKey(c *gin.Context) {
+	// API密钥不能再创建新的密钥, 避免绕过有效期限制
+	if _, exists := c.Get("apiKey"); exists {
+		response.FailWithCode(response.Forbidden)
+		return
+	}
+	user := GetCurrentUser(c)
+	// 绑定参数
+	var req request.CreateApiKeyRequestStruct
+	_ = c.ShouldBindJSON(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 记录当前创建人信息
+	req.Creator = user.Nickname + user.Username
+	// 创建服务
+	s := service.New(c)
+	key, err := s.CreateApiKey(user, &req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 密钥明文只返回一次
+	response.SuccessWithData(map[string]interface{}{
+		"key": key,
+	})
+}
+
+// 批量删除当前用户的API密钥
+func BatchDeleteApiKeyByIds(c *gin.Context) {
+	user := GetCurrentUser(c)
+	var req request.Req
+	_ = c.Bind(&req)
+	// 创建服务
+	s := service.New(c)
+	// 删除数据
+	err := s.DeleteApiKeyByIds(user.Id, req.GetUintIds())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
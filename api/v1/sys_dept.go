@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"gin-web/pkg/cache_service"
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"gin-web/pkg/response"
+	"gin-web/pkg/service"
+	"gin-web/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 查询部门树
+func GetDepts(c *gin.Context) {
+	// 创建服务
+	s := cache_service.New(c)
+	depts, err := s.GetDepts()
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 转为DeptTreeResponseStruct
+	var resp []response.DeptTreeResponseStruct
+	utils.Struct2StructByJson(depts, &resp)
+	response.SuccessWithData(resp)
+}
+
+// 创建部门
+func CreateDept(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 绑定参数
+	var req request.CreateDeptRequestStruct
+	_ = c.Bind(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 记录当前创建人信息
+	req.Creator = user.Nickname + user.Username
+	// 创建服务
+	s := service.New(c)
+	err = s.CreateDept(&req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 更新部门
+func UpdateDeptById(c *gin.Context) {
+	// 绑定参数
+	var req gin.H
+	_ = c.Bind(&req)
+	// 获取path中的deptId
+	deptId := utils.Str2Uint(c.Param("deptId"))
+	if deptId == 0 {
+		response.FailWithMsg("部门编号不正确")
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	// 更新数据
+	err := s.UpdateDeptById(deptId, req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 批量删除部门
+func BatchDeleteDeptByIds(c *gin.Context) {
+	var req request.Req
+	_ = c.Bind(&req)
+	// 创建服务
+	s := service.New(c)
+	// 删除数据
+	err := s.DeleteDeptByIds(req.GetUintIds())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
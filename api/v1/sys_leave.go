@@ -16,11 +16,15 @@ func GetLeaves(c *gin.Context) {
 	// 绑定参数
 	var req request.LeaveListRequestStruct
 	_ = c.Bind(&req)
-	// 获取当前登录用户
-	user := GetCurrentUser(c)
-	req.UserId = user.Id
 	// 创建服务
 	s := cache_service.New(c)
+	// 按当前用户的数据范围过滤
+	scope, err := s.GetDataScope(GetCurrentUser(c))
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	req.DataScope = &scope
 	leaves, err := s.GetLeaves(&req)
 	if err != nil {
 		response.FailWithMsg(err.Error())
@@ -70,6 +74,11 @@ func GetLeaveApprovalLogs(c *gin.Context) {
 				ApprovalUsername:      log.ApprovalUser.Username,
 				ApprovalUserNickname:  log.ApprovalUser.Nickname,
 				ApprovalOpinion:       log.ApprovalOpinion,
+				Actor:                 log.Actor,
+				OnBehalfOfUsername:    log.OnBehalfOfUser.Username,
+				OnBehalfOfNickname:    log.OnBehalfOfUser.Nickname,
+				AssigneeUsername:      log.AssigneeUser.Username,
+				AssigneeNickname:      log.AssigneeUser.Nickname,
 				CreatedAt:             log.Model.CreatedAt,
 				UpdatedAt:             log.Model.UpdatedAt,
 			},
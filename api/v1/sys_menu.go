@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"fmt"
 	"gin-web/pkg/cache_service"
 	"gin-web/pkg/global"
 	"gin-web/pkg/request"
@@ -15,7 +16,7 @@ func GetMenuTree(c *gin.Context) {
 	user := GetCurrentUser(c)
 	// 创建服务
 	s := cache_service.New(c)
-	menus, err := s.GetMenuTree(user.RoleId)
+	menus, err := s.GetMenuTree(user.RoleIds())
 	if err != nil {
 		response.FailWithMsg(err.Error())
 		return
@@ -112,3 +113,49 @@ func BatchDeleteMenuByIds(c *gin.Context) {
 	}
 	response.Success()
 }
+
+// 查询菜单(按钮)依赖的接口
+func GetMenuApisById(c *gin.Context) {
+	// 获取path中的menuId
+	menuId := utils.Str2Uint(c.Param("menuId"))
+	if menuId == 0 {
+		response.FailWithMsg("菜单编号不正确")
+		return
+	}
+	// 创建服务
+	s := cache_service.New(c)
+	apiIds, err := s.GetMenuApiIds(menuId)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	var resp response.MenuApisResponseStruct
+	resp.ApiIds = apiIds
+	response.SuccessWithData(resp)
+}
+
+// 更新菜单(按钮)依赖的接口
+func UpdateMenuApisById(c *gin.Context) {
+	// 绑定参数
+	var req request.UpdateIncrementalIdsRequestStruct
+	err := c.Bind(&req)
+	if err != nil {
+		response.FailWithMsg(fmt.Sprintf("参数绑定失败, %v", err))
+		return
+	}
+	// 获取path中的menuId
+	menuId := utils.Str2Uint(c.Param("menuId"))
+	if menuId == 0 {
+		response.FailWithMsg("菜单编号不正确")
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	// 更新数据
+	err = s.UpdateMenuApisById(menuId, req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
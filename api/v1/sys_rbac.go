@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/request"
+	"gin-web/pkg/response"
+	"gin-web/pkg/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// 导出权限配置包
+func ExportRbac(c *gin.Context) {
+	// 绑定参数
+	var req request.RbacExportRequestStruct
+	_ = c.Bind(&req)
+	// 创建服务
+	s := service.New(c)
+	bundle, err := s.ExportRbac()
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	if req.Format != "csv" {
+		response.SuccessWithData(bundle)
+		return
+	}
+	data, err := bundle.Csv()
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// csv以文件方式下载
+	filename := fmt.Sprintf("rbac_v%d_%s.csv", bundle.Version, time.Now().Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+// 对比权限配置包与当前配置
+func DiffRbac(c *gin.Context) {
+	bundle, err := bindRbacBundle(c)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	diff, err := s.DiffRbac(bundle)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.SuccessWithData(diff)
+}
+
+// 应用权限配置包
+func ApplyRbac(c *gin.Context) {
+	bundle, err := bindRbacBundle(c)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	diff, err := s.ApplyRbac(bundle)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.SuccessWithData(diff)
+}
+
+// 绑定权限配置包, Content-Type为text/csv时按csv解析, 否则按json解析
+func bindRbacBundle(c *gin.Context) (models.SysRbacBundle, error) {
+	var bundle models.SysRbacBundle
+	if c.ContentType() == "text/csv" {
+		data, err := c.GetRawData()
+		if err != nil {
+			return bundle, err
+		}
+		return models.ParseRbacBundleCsv(data)
+	}
+	err := c.ShouldBindJSON(&bundle)
+	return bundle, err
+}
@@ -26,6 +26,13 @@ func GetRoles(c *gin.Context) {
 	// 转为ResponseStruct, 隐藏部分字段
 	var respStruct []response.RoleListResponseStruct
 	utils.Struct2StructByJson(roles, &respStruct)
+	// 设置自定义数据范围的部门
+	for i, role := range roles {
+		respStruct[i].DeptIds = make([]uint, 0)
+		for _, dept := range role.Depts {
+			respStruct[i].DeptIds = append(respStruct[i].DeptIds, dept.Id)
+		}
+	}
 	// 返回分页数据
 	var resp response.PageData
 	// 设置分页参数
@@ -110,7 +117,7 @@ func UpdateRoleMenusById(c *gin.Context) {
 // 更新角色的权限接口
 func UpdateRoleApisById(c *gin.Context) {
 	// 绑定参数
-	var req request.UpdateIncrementalIdsRequestStruct
+	var req request.UpdateRoleApisRequestStruct
 	err := c.Bind(&req)
 	if err != nil {
 		response.FailWithMsg(fmt.Sprintf("参数绑定失败, %v", err))
@@ -133,6 +140,63 @@ func UpdateRoleApisById(c *gin.Context) {
 	response.Success()
 }
 
+// 查询角色继承的父角色
+func GetRoleParentsById(c *gin.Context) {
+	// 获取path中的roleId
+	roleId := utils.Str2Uint(c.Param("roleId"))
+	if roleId == 0 {
+		response.FailWithMsg("角色编号不正确")
+		return
+	}
+	// 创建服务
+	s := cache_service.New(c)
+	parentIds, err := s.GetRoleParentIds(roleId)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	roles, err := s.GetImplicitRoles([]uint{roleId})
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	var resp response.RoleParentsResponseStruct
+	resp.ParentIds = parentIds
+	resp.InheritedIds = make([]uint, 0)
+	for _, role := range roles {
+		if role.Id != roleId {
+			resp.InheritedIds = append(resp.InheritedIds, role.Id)
+		}
+	}
+	response.SuccessWithData(resp)
+}
+
+// 更新角色继承的父角色
+func UpdateRoleParentsById(c *gin.Context) {
+	// 绑定参数
+	var req request.UpdateIncrementalIdsRequestStruct
+	err := c.Bind(&req)
+	if err != nil {
+		response.FailWithMsg(fmt.Sprintf("参数绑定失败, %v", err))
+		return
+	}
+	// 获取path中的roleId
+	roleId := utils.Str2Uint(c.Param("roleId"))
+	if roleId == 0 {
+		response.FailWithMsg("角色编号不正确")
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	// 更新数据
+	err = s.UpdateRoleParentsById(roleId, req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
 // 批量删除角色
 func BatchDeleteRoleByIds(c *gin.Context) {
 	var req request.Req
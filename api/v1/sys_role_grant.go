@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"gin-web/pkg/response"
+	"gin-web/pkg/service"
+	"gin-web/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// 获取临时授权列表
+func GetRoleGrants(c *gin.Context) {
+	// 绑定参数
+	var req request.RoleGrantListRequestStruct
+	_ = c.Bind(&req)
+	// 创建服务
+	s := service.New(c)
+	grants, err := s.GetRoleGrants(&req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 转为ResponseStruct, 隐藏部分字段
+	now := time.Now().Unix()
+	respStruct := make([]response.RoleGrantListResponseStruct, 0)
+	for _, grant := range grants {
+		var item response.RoleGrantListResponseStruct
+		utils.Struct2StructByJson(grant, &item)
+		item.Username = grant.User.Username
+		item.UserNickname = grant.User.Nickname
+		item.RoleName = grant.Role.Name
+		item.RoleKeyword = grant.Role.Keyword
+		item.Active = grant.Active(now)
+		respStruct = append(respStruct, item)
+	}
+	// 返回分页数据
+	var resp response.PageData
+	// 设置分页参数
+	resp.PageInfo = req.PageInfo
+	// 设置数据列表
+	resp.List = respStruct
+	response.SuccessWithData(resp)
+}
+
+// 获取临时授权审计日志
+func GetRoleGrantLogs(c *gin.Context) {
+	// 绑定参数
+	var req request.RoleGrantLogListRequestStruct
+	_ = c.Bind(&req)
+	// 创建服务
+	s := service.New(c)
+	logs, err := s.GetRoleGrantLogs(&req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 转为ResponseStruct, 隐藏部分字段
+	var respStruct []response.RoleGrantLogListResponseStruct
+	utils.Struct2StructByJson(logs, &respStruct)
+	// 返回分页数据
+	var resp response.PageData
+	// 设置分页参数
+	resp.PageInfo = req.PageInfo
+	// 设置数据列表
+	resp.List = respStruct
+	response.SuccessWithData(resp)
+}
+
+// 创建临时授权
+func CreateRoleGrant(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 绑定参数
+	var req request.CreateRoleGrantRequestStruct
+	_ = c.Bind(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 记录当前创建人信息
+	req.Creator = user.Nickname + user.Username
+	// 创建服务
+	s := service.New(c)
+	err = s.CreateRoleGrant(&req, user)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 批量撤销临时授权
+func BatchRevokeRoleGrantByIds(c *gin.Context) {
+	user := GetCurrentUser(c)
+	var req request.Req
+	_ = c.Bind(&req)
+	// 创建服务
+	s := service.New(c)
+	// 撤销授权
+	err := s.RevokeRoleGrantByIds(req.GetUintIds(), user)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
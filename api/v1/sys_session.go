@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"gin-web/pkg/cache_service"
+	"gin-web/pkg/request"
+	"gin-web/pkg/response"
+	"gin-web/pkg/utils"
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// 获取当前用户的登录会话
+func GetSessions(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 绑定参数
+	var req request.SessionListRequestStruct
+	_ = c.Bind(&req)
+	// 只能查看自己的会话
+	req.UserId = user.Id
+	req.Username = ""
+	getSessions(c, &req)
+}
+
+// 批量注销当前用户的登录会话
+func BatchKillSessions(c *gin.Context) {
+	user := GetCurrentUser(c)
+	var req request.Req
+	_ = c.Bind(&req)
+	// 创建服务
+	s := cache_service.New(c)
+	err := s.KillSessions(user.Id, req.GetStringIds(), "用户注销会话")
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 获取在线用户(全部有效会话)
+func GetOnlineSessions(c *gin.Context) {
+	// 绑定参数
+	var req request.SessionListRequestStruct
+	_ = c.Bind(&req)
+	getSessions(c, &req)
+}
+
+// 批量强制下线
+func BatchKillOnlineSessions(c *gin.Context) {
+	var req request.Req
+	_ = c.Bind(&req)
+	// 创建服务
+	s := cache_service.New(c)
+	err := s.KillSessions(0, req.GetStringIds(), "管理员强制下线")
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 查询会话并返回分页数据
+func getSessions(c *gin.Context, req *request.SessionListRequestStruct) {
+	// 创建服务
+	s := cache_service.New(c)
+	sessions, err := s.GetSessions(req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 转为ResponseStruct, 隐藏部分字段
+	var respStruct []response.SessionListResponseStruct
+	utils.Struct2StructByJson(sessions, &respStruct)
+	// 标记当前请求使用的会话(API密钥请求没有会话)
+	jti, _ := jwt.ExtractClaims(c)["jti"].(string)
+	for i, session := range respStruct {
+		respStruct[i].Current = jti != "" && session.Jti == jti
+	}
+	// 返回分页数据
+	var resp response.PageData
+	// 设置分页参数
+	resp.PageInfo = req.PageInfo
+	// 设置数据列表
+	resp.List = respStruct
+	response.SuccessWithData(resp)
+}
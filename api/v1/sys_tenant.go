@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"gin-web/pkg/response"
+	"gin-web/pkg/service"
+	"gin-web/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 获取租户列表
+func GetTenants(c *gin.Context) {
+	// 绑定参数
+	var req request.TenantListRequestStruct
+	_ = c.Bind(&req)
+	// 创建服务
+	s := service.New(c)
+	tenants, err := s.GetTenants(&req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 转为ResponseStruct, 隐藏部分字段
+	var respStruct []response.TenantListResponseStruct
+	utils.Struct2StructByJson(tenants, &respStruct)
+	// 返回分页数据
+	var resp response.PageData
+	// 设置分页参数
+	resp.PageInfo = req.PageInfo
+	// 设置数据列表
+	resp.List = respStruct
+	response.SuccessWithData(resp)
+}
+
+// 创建租户
+func CreateTenant(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 绑定参数
+	var req request.CreateTenantRequestStruct
+	_ = c.Bind(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 记录当前创建人信息
+	req.Creator = user.Nickname + user.Username
+	// 创建服务
+	s := service.New(c)
+	err = s.CreateTenant(&req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 更新租户
+func UpdateTenantById(c *gin.Context) {
+	// 绑定参数
+	var req gin.H
+	_ = c.Bind(&req)
+	// 获取path中的tenantId
+	tenantId := utils.Str2Uint(c.Param("tenantId"))
+	if tenantId == 0 {
+		response.FailWithMsg("租户编号不正确")
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	// 更新数据
+	err := s.UpdateTenantById(tenantId, req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 批量删除租户
+func BatchDeleteTenantByIds(c *gin.Context) {
+	var req request.Req
+	_ = c.Bind(&req)
+	// 创建服务
+	s := service.New(c)
+	// 删除数据
+	err := s.DeleteTenantByIds(req.GetUintIds())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
@@ -9,6 +9,7 @@ import (
 	"gin-web/pkg/service"
 	"gin-web/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"time"
 )
 
 // 获取当前用户信息
@@ -17,12 +18,18 @@ func GetUserInfo(c *gin.Context) {
 	// 转为UserInfoResponseStruct, 隐藏部分字段
 	var resp response.UserInfoResponseStruct
 	utils.Struct2StructByJson(user, &resp)
-	resp.Roles = []string{
-		"admin",
-	}
-	resp.Permissions = []string{
-		"***",
+	resp.TotpRequired = user.TotpRequired()
+	resp.PwdExpired = user.PwdExpired(global.Conf.PwdPolicy.ExpireDays, time.Now())
+	resp.MustChangePwd = user.NeedChangePwd(global.Conf.PwdPolicy.ExpireDays)
+	resp.Roles = user.RoleKeywords()
+	// 创建服务
+	s := cache_service.New(c)
+	permissions, err := s.GetUserPermissions(user)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
 	}
+	resp.Permissions = permissions
 	response.SuccessWithData(resp)
 }
 
@@ -33,6 +40,13 @@ func GetUsers(c *gin.Context) {
 	_ = c.Bind(&req)
 	// 创建服务
 	s := cache_service.New(c)
+	// 按当前用户的数据范围过滤
+	scope, err := s.GetDataScope(GetCurrentUser(c))
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	req.DataScope = &scope
 	users, err := s.GetUsers(&req)
 	if err != nil {
 		response.FailWithMsg(err.Error())
@@ -41,6 +55,14 @@ func GetUsers(c *gin.Context) {
 	// 转为ResponseStruct, 隐藏部分字段
 	var respStruct []response.UserListResponseStruct
 	utils.Struct2StructByJson(users, &respStruct)
+	// 设置锁定状态及附加角色
+	for i, user := range users {
+		respStruct[i].Locked = user.IsLocked()
+		respStruct[i].RoleIds = make([]uint, 0)
+		for _, role := range user.Roles {
+			respStruct[i].RoleIds = append(respStruct[i].RoleIds, role.Id)
+		}
+	}
 	// 返回分页数据
 	var resp response.PageData
 	// 设置分页参数
@@ -52,31 +74,21 @@ func GetUsers(c *gin.Context) {
 
 // 修改密码
 func ChangePwd(c *gin.Context) {
-	var msg string
 	// 请求json绑定
 	var req request.ChangePwdRequestStruct
 	_ = c.ShouldBindJSON(&req)
 	// 获取当前用户
 	user := GetCurrentUser(c)
-	query := global.Mysql.Where("username = ?", user.Username).First(&user)
-	// 查询用户
-	err := query.Error
-	if err != nil {
-		msg = err.Error()
-	} else {
-		// 校验密码
-		if ok := utils.ComparePwd(req.OldPassword, user.Password); !ok {
-			msg = "原密码错误"
-		} else {
-			// 更新密码
-			err = query.Update("password", utils.GenPwd(req.NewPassword)).Error
-			if err != nil {
-				msg = err.Error()
-			}
-		}
+	// 创建服务
+	s := service.New(c)
+	// 校验原密码/密码策略/历史密码后更新
+	err := s.ChangePwd(user.Id, req.OldPassword, req.NewPassword)
+	if err == nil {
+		// 吊销该用户全部令牌, 需重新登录
+		err = s.RevokeUserTokens(user.Id, "用户修改密码")
 	}
-	if msg != "" {
-		response.FailWithMsg(msg)
+	if err != nil {
+		response.FailWithMsg(err.Error())
 		return
 	}
 	response.Success()
@@ -155,3 +167,141 @@ func BatchDeleteUserByIds(c *gin.Context) {
 	}
 	response.Success()
 }
+
+// 吊销用户全部令牌(强制重新登录)
+func RevokeUserTokensById(c *gin.Context) {
+	// 获取path中的userId
+	userId := utils.Str2Uint(c.Param("userId"))
+	if userId == 0 {
+		response.FailWithMsg("用户编号不正确")
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	err := s.RevokeUserTokens(userId, "管理员吊销令牌")
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 解锁用户(登录失败次数过多被锁定)
+func UnlockUserById(c *gin.Context) {
+	// 获取path中的userId
+	userId := utils.Str2Uint(c.Param("userId"))
+	if userId == 0 {
+		response.FailWithMsg("用户编号不正确")
+		return
+	}
+	// 创建服务
+	s := cache_service.New(c)
+	err := s.UnlockUserById(userId)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 绑定二次验证: 生成密钥及验证器扫码地址
+func EnrollTotp(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 创建服务
+	s := service.New(c)
+	secret, url, err := s.EnrollTotp(user.Id)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.SuccessWithData(map[string]interface{}{
+		"secret": secret,
+		"url":    url,
+	})
+}
+
+// 校验验证码, 通过后开启二次验证并返回恢复码
+func VerifyTotp(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 绑定参数
+	var req request.TotpCodeRequestStruct
+	_ = c.ShouldBindJSON(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	codes, err := s.EnableTotp(user.Id, req.Code)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.SuccessWithData(map[string]interface{}{
+		"recoveryCodes": codes,
+	})
+}
+
+// 关闭二次验证, 需提供验证码或恢复码
+func DisableTotp(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 绑定参数
+	var req request.TotpCodeRequestStruct
+	_ = c.ShouldBindJSON(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	err = s.DisableTotp(user.Id, req.Code)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 发送找回密码邮件
+func SendPwdResetMail(c *gin.Context) {
+	var req request.PwdResetMailRequestStruct
+	_ = c.ShouldBindJSON(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := cache_service.New(c)
+	err = s.SendPwdResetMail(req.Username)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 通过找回密码令牌重置密码
+func ResetPwd(c *gin.Context) {
+	var req request.PwdResetRequestStruct
+	_ = c.ShouldBindJSON(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	_, err = s.ResetPwd(req.Token, req.NewPassword)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
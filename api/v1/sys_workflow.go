@@ -39,6 +39,8 @@ func GetWorkflowApprovings(c *gin.Context) {
 			FlowTargetCategory:    log.Flow.TargetCategory,
 			FlowTargetCategoryStr: models.SysWorkflowTargetCategoryConst[log.Flow.TargetCategory],
 			TargetId:              log.TargetId,
+			CurrentLineId:         log.CurrentLineId,
+			CurrentLineName:       log.CurrentLine.Name,
 			Status:                log.Status,
 			StatusStr:             models.SysWorkflowLogStateConst[*log.Status],
 			SubmitUsername:        log.SubmitUser.Username,
@@ -47,6 +49,11 @@ func GetWorkflowApprovings(c *gin.Context) {
 			ApprovalUsername:      log.ApprovalUser.Username,
 			ApprovalUserNickname:  log.ApprovalUser.Nickname,
 			ApprovalOpinion:       log.ApprovalOpinion,
+			Actor:                 log.Actor,
+			OnBehalfOfUsername:    log.OnBehalfOfUser.Username,
+			OnBehalfOfNickname:    log.OnBehalfOfUser.Nickname,
+			AssigneeUsername:      log.AssigneeUser.Username,
+			AssigneeNickname:      log.AssigneeUser.Nickname,
 			ApprovingUserIds:      log.ApprovingUserIds,
 			CreatedAt:             log.Model.CreatedAt,
 			UpdatedAt:             log.Model.UpdatedAt,
@@ -119,6 +126,30 @@ func GetWorkflowLines(c *gin.Context) {
 	response.SuccessWithData(resp)
 }
 
+// 获取流程连线
+func GetWorkflowEdges(c *gin.Context) {
+	// 绑定参数
+	var req request.WorkflowEdgeListRequestStruct
+	_ = c.Bind(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	edges, err := s.GetWorkflowEdges(req.FlowId)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 转为ResponseStruct, 隐藏部分字段
+	var respStruct []response.WorkflowEdgeListResponseStruct
+	utils.Struct2StructByJson(edges, &respStruct)
+	response.SuccessWithData(respStruct)
+}
+
 // 创建工作流
 func CreateWorkflow(c *gin.Context) {
 	user := GetCurrentUser(c)
@@ -165,6 +196,28 @@ func UpdateWorkflowLineIncremental(c *gin.Context) {
 	response.Success()
 }
 
+// 更新流程连线
+func UpdateWorkflowEdges(c *gin.Context) {
+	// 绑定参数
+	var req request.UpdateWorkflowEdgeRequestStruct
+	_ = c.Bind(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	// 更新连线
+	err = s.UpdateWorkflowEdges(&req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
 // 更新工作流
 func UpdateWorkflowById(c *gin.Context) {
 	// 绑定参数
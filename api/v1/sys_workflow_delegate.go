@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"gin-web/pkg/response"
+	"gin-web/pkg/service"
+	"gin-web/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// 获取审批委托列表(当前用户作为委托人或被委托人)
+func GetWorkflowDelegates(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 绑定参数
+	var req request.WorkflowDelegateListRequestStruct
+	_ = c.Bind(&req)
+	req.CurrentUserId = user.Id
+	// 创建服务
+	s := service.New(c)
+	delegates, err := s.GetWorkflowDelegates(&req)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 转为ResponseStruct, 隐藏部分字段
+	now := time.Now().Unix()
+	respStruct := make([]response.WorkflowDelegateListResponseStruct, 0)
+	for _, delegate := range delegates {
+		var item response.WorkflowDelegateListResponseStruct
+		utils.Struct2StructByJson(delegate, &item)
+		item.Username = delegate.User.Username
+		item.UserNickname = delegate.User.Nickname
+		item.DelegateUsername = delegate.DelegateUser.Username
+		item.DelegateUserNickname = delegate.DelegateUser.Nickname
+		item.FlowName = delegate.Flow.Name
+		item.Active = delegate.Active(delegate.FlowId, now)
+		respStruct = append(respStruct, item)
+	}
+	// 返回分页数据
+	var resp response.PageData
+	// 设置分页参数
+	resp.PageInfo = req.PageInfo
+	// 设置数据列表
+	resp.List = respStruct
+	response.SuccessWithData(resp)
+}
+
+// 创建审批委托
+func CreateWorkflowDelegate(c *gin.Context) {
+	user := GetCurrentUser(c)
+	// 绑定参数
+	var req request.CreateWorkflowDelegateRequestStruct
+	_ = c.Bind(&req)
+	// 参数校验
+	err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 记录当前创建人信息
+	req.Creator = user.Nickname + user.Username
+	// 创建服务
+	s := service.New(c)
+	err = s.CreateWorkflowDelegate(&req, user)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
+
+// 批量删除审批委托
+func BatchDeleteWorkflowDelegateByIds(c *gin.Context) {
+	user := GetCurrentUser(c)
+	var req request.Req
+	_ = c.Bind(&req)
+	// 创建服务
+	s := service.New(c)
+	// 删除数据
+	err := s.DeleteWorkflowDelegateByIds(req.GetUintIds(), user)
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	response.Success()
+}
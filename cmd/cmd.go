@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 执行命令行子命令, 如: gin-web rbac export -format csv -o rbac.csv
+func Run(args []string) error {
+	switch args[0] {
+	case "rbac":
+		return Rbac(args[1:])
+	}
+	return errors.New(fmt.Sprintf("未知命令: %s", args[0]))
+}
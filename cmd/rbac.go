@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/service"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// 权限配置包命令:
+// rbac export [-format json|csv] [-o 文件]: 导出, 未指定文件时输出到标准输出
+// rbac diff -f 文件: 对比配置包与数据库
+// rbac apply -f 文件: 应用配置包(事务执行)
+// 文件扩展名为.csv时按csv解析, 否则按json解析, -tenant指定租户编号(默认为默认租户)
+func Rbac(args []string) error {
+	if len(args) == 0 {
+		return errors.New("用法: rbac export|diff|apply [参数]")
+	}
+	fs := flag.NewFlagSet("rbac "+args[0], flag.ContinueOnError)
+	format := fs.String("format", "json", "导出格式(json/csv)")
+	output := fs.String("o", "", "导出文件")
+	file := fs.String("f", "", "配置包文件")
+	tenantId := fs.Uint("tenant", 0, "租户编号")
+	err := fs.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "export":
+		return rbacExport(uint(*tenantId), *format, *output)
+	case "diff", "apply":
+		if *file == "" {
+			return errors.New("请使用-f指定配置包文件")
+		}
+		bundle, err := readRbacBundle(*file)
+		if err != nil {
+			return err
+		}
+		if args[0] == "diff" {
+			s := service.New(nil).WithTenant(uint(*tenantId))
+			diff, err := s.DiffRbac(bundle)
+			if err != nil {
+				return err
+			}
+			return printJson(diff)
+		}
+		return rbacApply(uint(*tenantId), bundle)
+	}
+	return errors.New(fmt.Sprintf("未知命令: rbac %s", args[0]))
+}
+
+// 导出配置包
+func rbacExport(tenantId uint, format string, output string) error {
+	s := service.New(nil).WithTenant(tenantId)
+	bundle, err := s.ExportRbac()
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if format == "csv" {
+		data, err = bundle.Csv()
+	} else {
+		data, err = json.MarshalIndent(bundle, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(output, data, 0644)
+}
+
+// 在事务中应用配置包
+func rbacApply(tenantId uint, bundle models.SysRbacBundle) error {
+	tx := global.Mysql.Begin()
+	s := service.NewWithTx(tx).WithTenant(tenantId)
+	diff, err := s.ApplyRbac(bundle)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+	return printJson(diff)
+}
+
+// 读取配置包文件
+func readRbacBundle(file string) (models.SysRbacBundle, error) {
+	var bundle models.SysRbacBundle
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return bundle, err
+	}
+	if strings.HasSuffix(strings.ToLower(file), ".csv") {
+		return models.ParseRbacBundleCsv(data)
+	}
+	err = json.Unmarshal(data, &bundle)
+	return bundle, err
+}
+
+func printJson(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
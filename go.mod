@@ -6,8 +6,9 @@ require (
 	github.com/appleboy/gin-jwt/v2 v2.6.3
 	github.com/casbin/casbin/v2 v2.2.2
 	github.com/casbin/gorm-adapter/v2 v2.1.0
-	github.com/casbin/redis-adapter/v2 v2.0.1
 	github.com/gin-gonic/gin v1.6.2
+	github.com/go-asn1-ber/asn1-ber v1.5.1
+	github.com/go-ldap/ldap/v3 v3.2.4
 	github.com/go-playground/locales v0.13.0
 	github.com/go-playground/universal-translator v0.17.0
 	github.com/go-redis/redis v6.15.7+incompatible
@@ -22,7 +23,7 @@ require (
 	github.com/thoas/go-funk v0.6.0
 	github.com/ulule/limiter/v3 v3.5.0
 	go.uber.org/zap v1.14.1
-	golang.org/x/crypto v0.0.0-20191205180655-e7c4368fe9dd
+	golang.org/x/crypto v0.0.0-20200604202706-70a84ac30bf9
 	gopkg.in/go-playground/validator.v9 v9.31.0
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0 // indirect
 )
@@ -0,0 +1,27 @@
+package initialize
+
+import (
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+// 根据路由表同步接口, 避免路由与接口表不一致
+func ApiSync(r *gin.Engine) {
+	if !global.Conf.ApiSync.Enable {
+		return
+	}
+	// 接口由默认租户维护, 其他租户共享
+	s := service.New(nil).WithTenant(models.SysTenantDefault)
+	apis := service.RouteApis(r.Routes(), global.Conf.System.UrlPathPrefix)
+	created, stale, err := s.SyncApis(apis, global.Conf.ApiSync.GrantRole)
+	if err != nil {
+		panic(fmt.Sprintf("同步接口失败: %v", err))
+	}
+	for _, api := range stale {
+		global.Log.Warn(fmt.Sprintf("接口路由已失效: %s %s", api.Method, api.Path))
+	}
+	global.Log.Debug(fmt.Sprintf("同步接口完成, 新增%d个, 失效%d个", len(created), len(stale)))
+}
@@ -0,0 +1,17 @@
+package initialize
+
+import (
+	"fmt"
+	"gin-web/pkg/global"
+	"gin-web/pkg/service"
+)
+
+// 初始化casbin策略管理器(加载策略, 开启redis时订阅其他实例的策略变更通知)
+func Casbin() {
+	s := service.New(nil)
+	_, err := s.Casbin()
+	if err != nil {
+		panic(fmt.Sprintf("初始化casbin策略管理器失败: %v", err))
+	}
+	global.Log.Debug("初始化casbin策略管理器完成")
+}
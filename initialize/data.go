@@ -21,11 +21,12 @@ func InitData() {
 			Model: models.Model{
 				Id: 1,
 			},
-			Name:    "访客",
-			Keyword: "guest",
-			Desc:    "外来访问人员",
-			Status:  &status,
-			Creator: creator,
+			Name:      "访客",
+			Keyword:   "guest",
+			Desc:      "外来访问人员",
+			Status:    &status,
+			Creator:   creator,
+			DataScope: models.SysRoleDataScopeOwn,
 		},
 		{
 			Model: models.Model{
@@ -41,11 +42,12 @@ func InitData() {
 			Model: models.Model{
 				Id: 3,
 			},
-			Name:    "管理员",
-			Keyword: "admin",
-			Desc:    "系统管理员",
-			Status:  &status,
-			Creator: creator,
+			Name:      "管理员",
+			Keyword:   "admin",
+			Desc:      "系统管理员",
+			Status:    &status,
+			Creator:   creator,
+			DataScope: models.SysRoleDataScopeAll,
 		},
 		{
 			Model: models.Model{
@@ -746,6 +748,396 @@ func InitData() {
 			Desc:     "审批工作流日志",
 			Creator:  creator,
 		},
+		{
+			Model: models.Model{
+				Id: 40,
+			},
+			Method:   "POST",
+			Path:     "/v1/user/token/revoke/:userId",
+			Category: "user",
+			Desc:     "吊销用户全部令牌",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 41,
+			},
+			Method:   "PATCH",
+			Path:     "/v1/user/unlock/:userId",
+			Category: "user",
+			Desc:     "解锁用户",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 42,
+			},
+			Method:   "POST",
+			Path:     "/v1/user/totp/enroll",
+			Category: "user",
+			Desc:     "绑定二次验证",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 43,
+			},
+			Method:   "POST",
+			Path:     "/v1/user/totp/verify",
+			Category: "user",
+			Desc:     "校验并开启二次验证",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 44,
+			},
+			Method:   "POST",
+			Path:     "/v1/user/totp/disable",
+			Category: "user",
+			Desc:     "关闭二次验证",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 45,
+			},
+			Method:   "GET",
+			Path:     "/v1/apiKey/list",
+			Category: "apiKey",
+			Desc:     "获取API密钥列表",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 46,
+			},
+			Method:   "POST",
+			Path:     "/v1/apiKey/create",
+			Category: "apiKey",
+			Desc:     "创建API密钥",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 47,
+			},
+			Method:   "DELETE",
+			Path:     "/v1/apiKey/delete/batch",
+			Category: "apiKey",
+			Desc:     "批量删除API密钥",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 48,
+			},
+			Method:   "GET",
+			Path:     "/v1/base/oidc/login",
+			Category: "base",
+			Desc:     "获取SSO登录地址",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 49,
+			},
+			Method:   "POST",
+			Path:     "/v1/base/oidc/callback",
+			Category: "base",
+			Desc:     "SSO登录回调",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 50,
+			},
+			Method:   "GET",
+			Path:     "/v1/session/list",
+			Category: "session",
+			Desc:     "获取当前用户的登录会话",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 51,
+			},
+			Method:   "DELETE",
+			Path:     "/v1/session/delete/batch",
+			Category: "session",
+			Desc:     "批量注销当前用户的登录会话",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 52,
+			},
+			Method:   "GET",
+			Path:     "/v1/session/online/list",
+			Category: "session",
+			Desc:     "获取在线用户",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 53,
+			},
+			Method:   "DELETE",
+			Path:     "/v1/session/online/delete/batch",
+			Category: "session",
+			Desc:     "批量强制下线",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 54,
+			},
+			Method:   "GET",
+			Path:     "/v1/role/parents/:roleId",
+			Category: "role",
+			Desc:     "获取角色继承的父角色",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 55,
+			},
+			Method:   "PATCH",
+			Path:     "/v1/role/parents/update/:roleId",
+			Category: "role",
+			Desc:     "更新角色继承的父角色",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 56,
+			},
+			Method:   "GET",
+			Path:     "/v1/dept/list",
+			Category: "dept",
+			Desc:     "获取部门树",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 57,
+			},
+			Method:   "POST",
+			Path:     "/v1/dept/create",
+			Category: "dept",
+			Desc:     "创建部门",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 58,
+			},
+			Method:   "PATCH",
+			Path:     "/v1/dept/update/:deptId",
+			Category: "dept",
+			Desc:     "更新部门",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 59,
+			},
+			Method:   "DELETE",
+			Path:     "/v1/dept/delete/batch",
+			Category: "dept",
+			Desc:     "批量删除部门",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 60,
+			},
+			Method:   "GET",
+			Path:     "/v1/api/explain",
+			Category: "api",
+			Desc:     "权限检查(解释是否允许访问)",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 61,
+			},
+			Method:   "GET",
+			Path:     "/v1/rbac/export",
+			Category: "rbac",
+			Desc:     "导出权限配置包",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 62,
+			},
+			Method:   "POST",
+			Path:     "/v1/rbac/diff",
+			Category: "rbac",
+			Desc:     "对比权限配置包",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 63,
+			},
+			Method:   "POST",
+			Path:     "/v1/rbac/apply",
+			Category: "rbac",
+			Desc:     "应用权限配置包",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 64,
+			},
+			Method:   "GET",
+			Path:     "/v1/menu/apis/:menuId",
+			Category: "menu",
+			Desc:     "获取菜单(按钮)依赖的接口",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 65,
+			},
+			Method:   "PATCH",
+			Path:     "/v1/menu/apis/update/:menuId",
+			Category: "menu",
+			Desc:     "更新菜单(按钮)依赖的接口",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 66,
+			},
+			Method:   "GET",
+			Path:     "/v1/tenant/list",
+			Category: "tenant",
+			Desc:     "获取租户列表",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 67,
+			},
+			Method:   "POST",
+			Path:     "/v1/tenant/create",
+			Category: "tenant",
+			Desc:     "创建租户",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 68,
+			},
+			Method:   "PATCH",
+			Path:     "/v1/tenant/update/:tenantId",
+			Category: "tenant",
+			Desc:     "更新租户",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 69,
+			},
+			Method:   "DELETE",
+			Path:     "/v1/tenant/delete/batch",
+			Category: "tenant",
+			Desc:     "批量删除租户",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 70,
+			},
+			Method:   "GET",
+			Path:     "/v1/role/grant/list",
+			Category: "role",
+			Desc:     "获取临时授权列表",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 71,
+			},
+			Method:   "GET",
+			Path:     "/v1/role/grant/log/list",
+			Category: "role",
+			Desc:     "获取临时授权审计日志",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 72,
+			},
+			Method:   "POST",
+			Path:     "/v1/role/grant/create",
+			Category: "role",
+			Desc:     "创建临时授权",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 73,
+			},
+			Method:   "PATCH",
+			Path:     "/v1/role/grant/revoke/batch",
+			Category: "role",
+			Desc:     "批量撤销临时授权",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 74,
+			},
+			Method:   "GET",
+			Path:     "/v1/workflow/edge/list",
+			Category: "workflow",
+			Desc:     "获取流程连线",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 75,
+			},
+			Method:   "PATCH",
+			Path:     "/v1/workflow/edge/update",
+			Category: "workflow",
+			Desc:     "更新流程连线",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 76,
+			},
+			Method:   "GET",
+			Path:     "/v1/workflow/delegate/list",
+			Category: "workflow",
+			Desc:     "获取审批委托列表",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 77,
+			},
+			Method:   "POST",
+			Path:     "/v1/workflow/delegate/create",
+			Category: "workflow",
+			Desc:     "创建审批委托",
+			Creator:  creator,
+		},
+		{
+			Model: models.Model{
+				Id: 78,
+			},
+			Method:   "DELETE",
+			Path:     "/v1/workflow/delegate/delete/batch",
+			Category: "workflow",
+			Desc:     "批量删除审批委托",
+			Creator:  creator,
+		},
 	}
 	for _, api := range apis {
 		oldApi := models.SysApi{}
@@ -773,8 +1165,8 @@ func InitData() {
 					Method:  api.Method,
 				})
 			}
-			// 其他人暂时只有登录/获取用户信息的权限
-			if api.Id < 5 || api.Id == 10 {
+			// 其他人暂时只有登录/SSO登录/获取用户信息/二次验证/API密钥/登录会话的权限
+			if api.Id < 5 || api.Id == 10 || (api.Id >= 42 && api.Id <= 51) {
 				s.CreateRoleCasbin(models.SysRoleCasbin{
 					Keyword: roles[0].Keyword,
 					Path:    api.Path,
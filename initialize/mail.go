@@ -0,0 +1,31 @@
+package initialize
+
+import (
+	"gin-web/pkg/global"
+	"gin-web/pkg/mail"
+	"strings"
+	"time"
+)
+
+// 初始化邮件发送
+func Mailer() {
+	conf := global.Conf.Mail
+	if conf.Host == "" {
+		// 未配置SMTP服务, 只将邮件写入日志(仅用于开发环境)
+		global.Mailer = mail.MailerFunc(func(msg mail.Message) error {
+			global.Log.Infof("[Mailer]未配置SMTP服务, 邮件未发送, 收件人: %s, 主题: %s, 内容: %s", strings.Join(msg.To, ","), msg.Subject, msg.Body)
+			return nil
+		})
+		global.Log.Debug("未配置SMTP服务, 邮件将写入日志")
+		return
+	}
+	global.Mailer = mail.NewSmtpMailer(mail.SmtpConfig{
+		Host:     conf.Host,
+		Port:     conf.Port,
+		Username: conf.Username,
+		Password: conf.Password,
+		From:     conf.From,
+		Timeout:  time.Duration(conf.Timeout) * time.Second,
+	})
+	global.Log.Debug("初始化邮件发送完成")
+}
@@ -6,6 +6,7 @@ import (
 	"gin-web/pkg/global"
 	_ "github.com/go-sql-driver/mysql" // mysql驱动
 	"github.com/jinzhu/gorm"
+	"time"
 )
 
 // 初始化mysql数据库
@@ -24,6 +25,8 @@ func Mysql() {
 	}
 	// 打印所有执行的sql
 	db.LogMode(global.Conf.Mysql.LogMode)
+	// 按租户隔离数据
+	models.RegisterTenantCallbacks(db)
 	global.Mysql = db
 	// 表结构
 	autoMigrate()
@@ -34,6 +37,9 @@ func Mysql() {
 
 // 自动迁移表结构
 func autoMigrate() {
+	// 旧版本没有数据范围字段
+	roleTable := new(models.SysRole).TableName()
+	hasDataScope := global.Mysql.Dialect().HasColumn(roleTable, "data_scope")
 	global.Mysql.AutoMigrate(
 		new(models.SysUser),
 		new(models.SysRole),
@@ -42,24 +48,92 @@ func autoMigrate() {
 		new(models.SysCasbin),
 		new(models.SysWorkflow),
 		new(models.SysWorkflowLine),
+		new(models.SysWorkflowEdge),
 		new(models.SysWorkflowLog),
+		new(models.SysWorkflowDelegate),
 		new(models.RelationUserWorkflowLine),
 		new(models.SysLeave),
+		new(models.SysTokenRevocation),
+		new(models.SysRefreshToken),
+		new(models.SysApiKey),
+		new(models.SysPwdHistory),
+		new(models.SysSession),
+		new(models.SysDept),
+		new(models.SysTenant),
+		new(models.SysRoleGrant),
+		new(models.SysRoleGrantLog),
 	)
+	// 角色关键字/用户名在租户内唯一
+	tenantUniqueIndex(new(models.SysRole), "keyword")
+	tenantUniqueIndex(new(models.SysUser), "username")
+	if !hasDataScope {
+		// 新增字段默认为仅本人数据, 已有角色升级前可以查看全部数据, 保持不变
+		global.Mysql.Unscoped().
+			Model(new(models.SysRole)).
+			UpdateColumn("data_scope", models.SysRoleDataScopeAll)
+	}
+	// 吊销时间/会话签发时间由秒改为毫秒
+	secondsToMillis(new(models.SysTokenRevocation), "revoked_at")
+	secondsToMillis(new(models.SysSession), "issued_at")
+	// 历史用户没有修改密码时间, 从当前时间开始计算密码有效期, 避免升级后全部被强制修改密码
+	global.Mysql.Unscoped().
+		Model(new(models.SysUser)).
+		Where("pwd_changed_at = ?", 0).
+		UpdateColumn("pwd_changed_at", time.Now().Unix())
+}
+
+// 将旧版本以unix秒存储的列转换为unix毫秒(小于1e11的值视为秒)
+func secondsToMillis(m interface{}, column string) {
+	global.Mysql.Unscoped().
+		Model(m).
+		Where(fmt.Sprintf("%s > 0 AND %s < ?", column, column), int64(1e11)).
+		UpdateColumn(column, gorm.Expr(fmt.Sprintf("%s * 1000", column)))
+}
+
+// 将单列唯一索引替换为租户编号+该列的唯一索引
+func tenantUniqueIndex(m interface{}, column string) {
+	scope := global.Mysql.NewScope(m)
+	tableName := scope.TableName()
+	// 旧版本通过unique标签创建, 索引名称与列名相同
+	if scope.Dialect().HasIndex(tableName, column) {
+		global.Mysql.Model(m).RemoveIndex(column)
+	}
+	global.Mysql.Model(m).AddUniqueIndex(fmt.Sprintf("idx_%s_tenant_%s", tableName, column), "tenant_id", column)
+}
+
+// 表名接口
+type tabler interface {
+	TableName() string
 }
 
 func binlog() {
-	MysqlBinlog([]string{
-		new(models.SysUser).TableName(),
-		new(models.SysRole).TableName(),
-		new(models.SysMenu).TableName(),
-		new(models.SysApi).TableName(),
-		new(models.SysCasbin).TableName(),
-		new(models.RelationRoleMenu).TableName(),
-		new(models.SysWorkflow).TableName(),
-		new(models.SysWorkflowLine).TableName(),
-		new(models.SysWorkflowLog).TableName(),
-		new(models.RelationUserWorkflowLine).TableName(),
-		new(models.SysLeave).TableName(),
-	})
+	tables := make([]string, 0)
+	for _, m := range []tabler{
+		new(models.SysUser),
+		new(models.SysRole),
+		new(models.SysMenu),
+		new(models.SysApi),
+		new(models.SysCasbin),
+		new(models.RelationRoleMenu),
+		new(models.RelationMenuApi),
+		new(models.RelationUserRole),
+		new(models.SysDept),
+		new(models.RelationRoleDept),
+		new(models.SysWorkflow),
+		new(models.SysWorkflowLine),
+		new(models.SysWorkflowLog),
+		new(models.SysWorkflowDelegate),
+		new(models.RelationUserWorkflowLine),
+		new(models.SysLeave),
+		new(models.SysTokenRevocation),
+		new(models.SysRoleGrant),
+	} {
+		tables = append(tables, m.TableName())
+		// 包含租户编号的表按租户缓存(参见global.CacheKey)
+		if _, ok := global.Mysql.NewScope(m).FieldByName("TenantId"); ok {
+			_, shared := m.(models.TenantShared)
+			global.TenantTables[m.TableName()] = shared
+		}
+	}
+	MysqlBinlog(tables)
 }
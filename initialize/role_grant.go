@@ -0,0 +1,38 @@
+package initialize
+
+import (
+	"fmt"
+	"gin-web/pkg/global"
+	"gin-web/pkg/service"
+	"time"
+)
+
+// 定时将到期的临时授权标记为过期(授权是否生效以有效期为准, 这里只是同步状态并记录审计日志)
+func RoleGrantScheduler() {
+	interval := global.Conf.RoleGrant.ExpireInterval
+	if interval <= 0 {
+		return
+	}
+	// 启动时先执行一次
+	expireRoleGrants()
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			expireRoleGrants()
+		}
+	}()
+}
+
+func expireRoleGrants() {
+	// 不区分租户
+	s := service.New(nil)
+	count, err := s.ExpireRoleGrants()
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("处理到期的临时授权失败: %v", err))
+		return
+	}
+	if count > 0 {
+		global.Log.Debug(fmt.Sprintf("处理到期的临时授权完成, 共%d个", count))
+	}
+}
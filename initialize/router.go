@@ -28,6 +28,8 @@ func Routers() *gin.Engine {
 	// 添加跨域中间件, 让请求支持跨域
 	r.Use(middleware.Cors())
 	global.Log.Debug("请求已支持跨域")
+	// 添加租户中间件
+	r.Use(middleware.Tenant)
 
 	// 初始化jwt auth中间件
 	authMiddleware, err := middleware.InitAuth()
@@ -43,14 +45,20 @@ func Routers() *gin.Engine {
 
 	// 方便统一添加路由前缀
 	v1Group := apiGroup.Group("v1")
-	router.InitPublicRouter(v1Group)                   // 注册公共路由
-	router.InitBaseRouter(v1Group, authMiddleware)     // 注册基础路由, 不会鉴权
-	router.InitUserRouter(v1Group, authMiddleware)     // 注册用户路由
-	router.InitMenuRouter(v1Group, authMiddleware)     // 注册菜单路由
-	router.InitRoleRouter(v1Group, authMiddleware)     // 注册角色路由
-	router.InitApiRouter(v1Group, authMiddleware)      // 注册接口路由
-	router.InitWorkflowRouter(v1Group, authMiddleware) // 注册工作流路由
-	router.InitLeaveRouter(v1Group, authMiddleware)    // 注册请假路由
+	router.InitPublicRouter(v1Group)                    // 注册公共路由
+	router.InitBaseRouter(v1Group, authMiddleware)      // 注册基础路由, 不会鉴权
+	router.InitUserRouter(v1Group, authMiddleware)      // 注册用户路由
+	router.InitMenuRouter(v1Group, authMiddleware)      // 注册菜单路由
+	router.InitRoleRouter(v1Group, authMiddleware)      // 注册角色路由
+	router.InitDeptRouter(v1Group, authMiddleware)      // 注册部门路由
+	router.InitApiRouter(v1Group, authMiddleware)       // 注册接口路由
+	router.InitWorkflowRouter(v1Group, authMiddleware)  // 注册工作流路由
+	router.InitLeaveRouter(v1Group, authMiddleware)     // 注册请假路由
+	router.InitApiKeyRouter(v1Group, authMiddleware)    // 注册API密钥路由
+	router.InitSessionRouter(v1Group, authMiddleware)   // 注册登录会话路由
+	router.InitRbacRouter(v1Group, authMiddleware)      // 注册权限配置包路由
+	router.InitTenantRouter(v1Group, authMiddleware)    // 注册租户路由
+	router.InitRoleGrantRouter(v1Group, authMiddleware) // 注册临时授权路由
 
 	global.Log.Debug("初始化路由完成")
 	return r
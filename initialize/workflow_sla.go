@@ -0,0 +1,56 @@
+package initialize
+
+import (
+	"fmt"
+	"gin-web/pkg/global"
+	"gin-web/pkg/service"
+	"time"
+)
+
+// 定时扫描待审批日志, 到达流水线审批时限时提醒审批人/升级给指定角色/自动通过/自动拒绝
+func WorkflowSlaScheduler() {
+	interval := global.Conf.WorkflowSla.ScanInterval
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			scanWorkflowSla()
+		}
+	}()
+}
+
+func scanWorkflowSla() {
+	now := time.Now()
+	// 不区分租户
+	s := service.New(nil)
+	logs, err := s.GetWorkflowSlaLogs(now)
+	if err != nil {
+		global.Log.Error(fmt.Sprintf("查询超时的待审批日志失败: %v", err))
+		return
+	}
+	count := 0
+	for _, log := range logs {
+		// 每条日志单独开启事务, 避免相互影响
+		tx := global.Mysql.Begin()
+		// 按日志所在租户处理
+		ts := service.NewWithTx(tx).WithTenant(log.TenantId)
+		err = ts.HandleWorkflowSla(log.Id, now)
+		if err != nil {
+			tx.Rollback()
+			global.Log.Error(fmt.Sprintf("处理超时的待审批日志失败, logId=%d: %v", log.Id, err))
+			continue
+		}
+		err = tx.Commit().Error
+		if err != nil {
+			global.Log.Error(fmt.Sprintf("处理超时的待审批日志失败, logId=%d: %v", log.Id, err))
+			continue
+		}
+		count++
+	}
+	if count > 0 {
+		global.Log.Debug(fmt.Sprintf("处理超时的待审批日志完成, 共%d条", count))
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"gin-web/cmd"
 	"gin-web/initialize"
 	"gin-web/pkg/global"
 	"net/http"
@@ -38,9 +39,23 @@ func main() {
 	// 初始校验器
 	initialize.Validate()
 
+	// 初始化邮件发送
+	initialize.Mailer()
+
 	// 结束后关闭数据库
 	defer global.Mysql.Close()
 
+	if len(os.Args) > 1 {
+		// 执行命令行子命令后退出, 不启动服务
+		err := cmd.Run(os.Args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			global.Mysql.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 初始化路由
 	r := initialize.Routers()
 
@@ -49,6 +64,18 @@ func main() {
 		initialize.InitData()
 	}
 
+	// 初始化casbin策略管理器
+	initialize.Casbin()
+
+	// 根据路由表同步接口
+	initialize.ApiSync(r)
+
+	// 定时处理到期的临时授权
+	initialize.RoleGrantScheduler()
+
+	// 定时处理超过审批时限的工单
+	initialize.WorkflowSlaScheduler()
+
 	host := "0.0.0.0"
 	port := global.Conf.System.Port
 	// 服务器启动以及优雅的关闭
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/response"
+	"gin-web/pkg/service"
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/gin-gonic/gin"
+)
+
+// API密钥请求头, 供CI脚本等机器客户端使用
+const ApiKeyHeader = "X-Api-Key"
+
+// 认证中间件, 携带API密钥时使用API密钥认证, 否则使用jwt认证
+func AuthMiddleware(authMiddleware *jwt.GinJWTMiddleware) gin.HandlerFunc {
+	jwtAuth := authMiddleware.MiddlewareFunc()
+	return func(c *gin.Context) {
+		key := c.GetHeader(ApiKeyHeader)
+		if key == "" {
+			jwtAuth(c)
+			return
+		}
+		// 创建服务, 此时还不知道密钥所属租户, 不区分租户查询
+		s := service.NewWithTx(global.GetTx(c))
+		user, apiKey, err := s.ApiKeyCheck(key, c.ClientIP())
+		if err != nil {
+			global.Log.Debug(fmt.Sprintf("API密钥认证失败: %v", err))
+			response.FailWithCode(response.Unauthorized)
+			return
+		}
+		// 与jwt认证保持一致, 以密钥所属用户的租户为准, 忽略请求头中的租户
+		if !tenantEnabled(c, user.TenantId) {
+			response.FailWithCode(response.Unauthorized)
+			return
+		}
+		global.SetTenantId(c, user.TenantId)
+		// 将用户保存到context, 后续按普通用户鉴权
+		c.Set("user", user)
+		c.Set("apiKey", apiKey)
+		c.Next()
+	}
+}
+
+// 判断API密钥的权限范围是否包含当前请求, 非API密钥认证的请求不受限制
+func apiKeyAllowed(c *gin.Context, obj string, act string) bool {
+	v, exists := c.Get("apiKey")
+	if !exists {
+		return true
+	}
+	apiKey, _ := v.(models.SysApiKey)
+	for _, api := range apiKey.Apis {
+		// 匹配规则与rbac_model.conf保持一致
+		if (util.KeyMatch2(obj, api.Path) || util.KeyMatch(obj, api.Path)) && (act == api.Method || api.Method == "*") {
+			return true
+		}
+	}
+	return false
+}
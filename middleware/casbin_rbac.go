@@ -2,23 +2,48 @@ package middleware
 
 import (
 	v1 "gin-web/api/v1"
+	"gin-web/models"
 	"gin-web/pkg/cache_service"
 	"gin-web/pkg/global"
 	"gin-web/pkg/response"
+	"gin-web/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"strings"
 )
 
+// 未绑定二次验证时允许访问的接口
+var totpEnrollApis = []string{
+	"/v1/user/info",
+	"/v1/user/totp/enroll",
+	"/v1/user/totp/verify",
+}
+
+// 密码过期或为初始密码时允许访问的接口
+var pwdChangeApis = []string{
+	"/v1/user/info",
+	"/v1/user/changePwd",
+}
+
 // Casbin中间件, 基于RBAC的权限访问控制模型
 func CasbinMiddleware(c *gin.Context) {
 	// 获取当前登录用户
 	user := v1.GetCurrentUser(c)
-	// 当前登录用户的角色关键字作为casbin访问实体sub
-	sub := user.Role.Keyword
+	// 当前登录用户全部角色的关键字作为casbin访问实体sub, 任一角色通过即可
+	subs := user.RoleKeywords()
 	// 请求URL路径作为casbin访问资源obj(需先清除path前缀)
 	obj := strings.Replace(c.Request.URL.Path, "/"+global.Conf.System.UrlPathPrefix, "", 1)
 	// 请求方式作为casbin访问动作act
 	act := c.Request.Method
+	// 角色要求开启二次验证但用户尚未绑定, 只允许访问绑定相关接口
+	if user.TotpRequired() && !user.TotpOn() && !utils.Contains(totpEnrollApis, obj) {
+		response.FailWithMsg(response.TotpEnrollRequiredMsg)
+		return
+	}
+	// 密码过期或为初始密码, 只允许修改密码(API密钥不受影响)
+	if _, exists := c.Get("apiKey"); !exists && user.NeedChangePwd(global.Conf.PwdPolicy.ExpireDays) && !utils.Contains(pwdChangeApis, obj) {
+		response.FailWithMsg(response.PwdChangeRequiredMsg)
+		return
+	}
 	// 创建服务
 	s := cache_service.New(c)
 	// 获取casbin策略管理器
@@ -28,8 +53,9 @@ func CasbinMiddleware(c *gin.Context) {
 		return
 	}
 	// 检查策略
-	pass, _ := e.Enforce(sub, obj, act)
-	if !pass {
+	pass, _ := e.EnforceAny(subs, models.TenantDomain(global.GetTenantId(c)), obj, act)
+	// 使用API密钥访问时, 还需在密钥的权限范围内
+	if !pass || !apiKeyAllowed(c, obj, act) {
 		response.FailWithCode(response.Forbidden)
 	}
 	// 处理请求
@@ -1,16 +1,22 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"gin-web/models"
 	"gin-web/pkg/cache_service"
 	"gin-web/pkg/global"
 	"gin-web/pkg/request"
 	"gin-web/pkg/response"
+	"gin-web/pkg/service"
 	"gin-web/pkg/utils"
 	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+	"strconv"
 	"time"
+	"unicode/utf8"
 )
 
 func InitAuth() (*jwt.GinJWTMiddleware, error) {
@@ -37,9 +43,23 @@ func payloadFunc(data interface{}) jwt.MapClaims {
 		var user models.SysUser
 		// 将用户json转为结构体
 		utils.JsonI2Struct(v["user"], &user)
+		// 令牌唯一标识, 用于吊销令牌, 由调用方生成以便关联刷新令牌
+		jti, _ := v["jti"].(string)
+		if jti == "" {
+			jti = uuid.NewV4().String()
+		}
+		// 签发时间(unix毫秒), 用于吊销用户全部令牌, 由调用方生成以便记录到会话
+		iatMs, _ := v["iatMs"].(int64)
+		if iatMs == 0 {
+			iatMs = utils.UnixMilli(time.Now())
+		}
 		return jwt.MapClaims{
 			jwt.IdentityKey: user.Id,
 			"user":          v["user"],
+			"jti":           jti,
+			"iat":           iatMs / 1000, // 标准字段只能是秒
+			"iatMs":         iatMs,
+			"tenantId":      user.TenantId, // 用户所属租户, 登录后按该租户隔离数据
 		}
 	}
 	return jwt.MapClaims{}
@@ -51,6 +71,10 @@ func identityHandler(c *gin.Context) interface{} {
 	return map[string]interface{}{
 		"IdentityKey": claims[jwt.IdentityKey],
 		"user":        claims["user"],
+		"jti":         claims["jti"],
+		"iat":         claims["iat"],
+		"iatMs":       claims["iatMs"],
+		"tenantId":    claims["tenantId"],
 	}
 }
 
@@ -59,29 +83,89 @@ func login(c *gin.Context) (interface{}, error) {
 	// 请求json绑定
 	_ = c.ShouldBindJSON(&req)
 
-	u := &models.SysUser{
-		Username: req.Username,
-		Password: req.Password,
-	}
-
 	// 创建服务
 	s := cache_service.New(c)
-	// 密码校验
-	user, err := s.LoginCheck(u)
-	if err != nil {
-		return nil, err
+	ip := c.ClientIP()
+	var user *models.SysUser
+	var err error
+	if req.Challenge != "" {
+		// 第二步: 完成二次验证挑战
+		user, err = s.TotpChallengeCheck(req.Challenge, req.TotpCode, ip)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// 第一步: 校验用户名密码
+		user, err = passwordCheck(c, s, req.Username, req.Password, ip)
+		if err != nil {
+			return nil, err
+		}
+		if user.TotpOn() {
+			// 已开启二次验证, 返回挑战令牌, 完成验证后才签发令牌
+			c.Set("totpChallenge", s.CreateTotpChallenge(user.Id))
+			return nil, errors.New(response.TotpRequiredMsg)
+		}
 	}
+	// 登录成功, 清除失败次数
+	s.LoginSucceeded(user.Username)
+	jti := uuid.NewV4().String()
+	iatMs := utils.UnixMilli(time.Now())
+	// 记录登录信息, loginResponse签发刷新令牌/创建会话时会使用到
+	c.Set("user", *user)
+	c.Set("jti", jti)
+	c.Set("iatMs", iatMs)
+	c.Set("device", getDevice(c, req.Device))
 	// 将用户以json格式写入, payloadFunc/authorizator会使用到
 	return map[string]interface{}{
-		"user": utils.Struct2Json(user),
+		"user":  utils.Struct2Json(user),
+		"jti":   jti,
+		"iatMs": iatMs,
 	}, nil
 }
 
+// 用户名密码校验
+func passwordCheck(c *gin.Context, s cache_service.RedisService, username string, password string, ip string) (*models.SysUser, error) {
+	// 登录失败次数校验
+	retryAfter, err := s.LoginLimitCheck(username, ip)
+	if err != nil {
+		// 记录剩余等待时间, unauthorized会使用到
+		c.Set("loginRetryAfter", retryAfter)
+		return nil, err
+	}
+	// 密码校验
+	user, err := s.LoginCheck(&models.SysUser{
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		if err.Error() == response.LoginCheckErrorMsg || gorm.IsRecordNotFoundError(err) {
+			// 用户名或密码错误, 累加失败次数
+			s.LoginFailed(username, ip)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
 func authorizator(data interface{}, c *gin.Context) bool {
 	if v, ok := data.(map[string]interface{}); ok {
 		var user models.SysUser
 		// 将用户json转为结构体
 		utils.JsonI2Struct(v["user"], &user)
+		// 以令牌中的租户为准, 数字在json解析后为float64
+		tenantId, _ := v["tenantId"].(float64)
+		if !tenantEnabled(c, uint(tenantId)) {
+			return false
+		}
+		global.SetTenantId(c, uint(tenantId))
+		// 令牌已被吊销
+		if tokenRevoked(c, v, user.Id) {
+			return false
+		}
+		// 记录会话最近访问时间
+		jti, _ := v["jti"].(string)
+		cs := cache_service.New(c)
+		cs.TouchSession(jti)
 		// 将用户保存到context, api调用时取数据方便
 		c.Set("user", user)
 		return true
@@ -91,20 +175,200 @@ func authorizator(data interface{}, c *gin.Context) bool {
 
 func unauthorized(c *gin.Context, code int, message string) {
 	global.Log.Debug(fmt.Sprintf("JWT认证失败, 错误码%d, 错误信息%s", code, message))
-	if message == response.LoginCheckErrorMsg {
-		response.FailWithMsg(response.LoginCheckErrorMsg)
+	switch message {
+	case response.LoginCheckErrorMsg, response.LoginLockedMsg, response.LoginIpLockedMsg, response.TotpCheckErrorMsg, response.TotpChallengeErrorMsg:
+		// 登录失败, 提示具体原因
+		response.FailWithMsg(message)
+		return
+	case response.TotpRequiredMsg:
+		// 需要二次验证, 返回挑战令牌
+		totpRequiredResponse(c.GetString("totpChallenge"))
+		return
+	case response.LoginTooFrequentMsg:
+		// 登录延迟未结束, 告知客户端等待时间
+		tooManyRequestsResponse(c, c.GetDuration("loginRetryAfter"))
 		return
 	}
 	response.FailWithCode(response.Unauthorized)
 }
 
 func loginResponse(c *gin.Context, code int, token string, expires time.Time) {
+	user, _ := c.Get("user")
+	u, _ := user.(models.SysUser)
+	// 创建服务
+	s := service.New(c)
+	// 签发刷新令牌(新的令牌族)
+	refreshToken, refreshExpires, err := s.CreateRefreshToken(u.Id, c.GetString("device"), c.GetString("jti"), "")
+	if err == nil {
+		// 记录登录会话
+		cs := cache_service.New(c)
+		err = cs.CreateSession(newSession(c, u, c.GetString("jti"), c.GetInt64("iatMs"), c.GetString("device"), refreshExpires))
+	}
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	tokenResponse(token, expires, refreshToken, refreshExpires)
+}
+
+// 需要二次验证时的响应, 客户端携带挑战令牌与验证码再次调用登录接口
+func totpRequiredResponse(challenge string) {
+	response.Result(response.TotpRequired, response.TotpRequiredMsg, map[string]interface{}{
+		"challenge": challenge,
+		"expires":   time.Now().Add(time.Duration(global.Conf.Totp.ChallengeTimeout) * time.Minute),
+	})
+}
+
+// 登录/刷新令牌成功后的响应
+func tokenResponse(token string, expires time.Time, refreshToken string, refreshExpires time.Time) {
 	response.SuccessWithData(map[string]interface{}{
-		"token":   token,
-		"expires": expires,
+		"token":          token,
+		"expires":        expires,
+		"refreshToken":   refreshToken,
+		"refreshExpires": refreshExpires,
 	})
 }
 
 func logoutResponse(c *gin.Context, code int) {
+	claims := jwt.ExtractClaims(c)
+	jti, _ := claims["jti"].(string)
+	user, _ := c.Get("user")
+	u, _ := user.(models.SysUser)
+	// 创建服务
+	s := service.New(c)
+	// 吊销当前令牌
+	err := s.RevokeToken(jti, u.Id, "用户登出")
+	if err == nil {
+		// 吊销当前令牌对应的刷新令牌族
+		err = s.RevokeRefreshTokensByJti(jti)
+	}
+	if err == nil {
+		// 删除当前会话
+		cs := cache_service.New(c)
+		err = cs.DeleteSession(jti)
+	}
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
 	response.Success()
 }
+
+// 使用刷新令牌换取新的访问令牌, 刷新令牌每次使用后轮换
+// 不再使用jwt.RefreshHandler, 否则被盗用的访问令牌可以无限续期
+func RefreshTokenHandler(authMiddleware *jwt.GinJWTMiddleware) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req request.RefreshTokenRequestStruct
+		// 请求json绑定
+		_ = c.ShouldBindJSON(&req)
+		// 创建服务
+		s := service.New(c)
+		oldToken, err := s.UseRefreshToken(req.RefreshToken)
+		if err != nil {
+			global.Log.Debug(fmt.Sprintf("刷新令牌校验失败: %v", err))
+			response.FailWithCode(response.Unauthorized)
+			return
+		}
+		// 查询最新的用户信息, 用户可能已被禁用
+		user, err := s.GetUserById(oldToken.UserId)
+		if err != nil || (user.Status != nil && !*user.Status) {
+			response.FailWithCode(response.Unauthorized)
+			return
+		}
+		// 签发新的访问令牌及同一族的新刷新令牌
+		issueTokens(c, authMiddleware, user, oldToken.Device, oldToken.FamilyId, oldToken.Jti)
+	}
+}
+
+// 签发访问令牌及刷新令牌(familyId为空时创建新的令牌族), 用于不经过jwt.LoginHandler的场景
+// 刷新令牌时oldJti为旧访问令牌的jti, 用于更新对应的会话
+func issueTokens(c *gin.Context, authMiddleware *jwt.GinJWTMiddleware, user models.SysUser, device string, familyId string, oldJti string) {
+	jti := uuid.NewV4().String()
+	iatMs := utils.UnixMilli(time.Now())
+	token, expires, err := authMiddleware.TokenGenerator(map[string]interface{}{
+		"user":  utils.Struct2Json(user),
+		"jti":   jti,
+		"iatMs": iatMs,
+	})
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	// 创建服务
+	s := service.New(c)
+	refreshToken, refreshExpires, err := s.CreateRefreshToken(user.Id, device, jti, familyId)
+	if err == nil {
+		cs := cache_service.New(c)
+		session := newSession(c, user, jti, iatMs, device, refreshExpires)
+		if familyId == "" {
+			// 新登录, 记录登录会话
+			err = cs.CreateSession(session)
+		} else {
+			// 刷新令牌, 更新原会话
+			err = cs.RefreshSession(oldJti, session)
+		}
+	}
+	if err != nil {
+		response.FailWithMsg(err.Error())
+		return
+	}
+	tokenResponse(token, expires, refreshToken, refreshExpires)
+}
+
+// 构造登录会话
+func newSession(c *gin.Context, user models.SysUser, jti string, iatMs int64, device string, expires time.Time) models.SysSession {
+	now := time.Now().Unix()
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	return models.SysSession{
+		Jti:        jti,
+		UserId:     user.Id,
+		Username:   user.Username,
+		Device:     device,
+		Ip:         c.ClientIP(),
+		UserAgent:  userAgent,
+		LoginAt:    now,
+		IssuedAt:   iatMs,
+		LastSeenAt: now,
+		ExpiresAt:  expires.Unix(),
+	}
+}
+
+// 获取登录设备标识, 未指定时使用User-Agent
+// 超过数据库字段长度(varchar(255))时截断, 否则严格模式下保存刷新令牌/会话会失败
+func getDevice(c *gin.Context, device string) string {
+	if device == "" {
+		device = c.Request.UserAgent()
+	}
+	if utf8.RuneCountInString(device) > 255 {
+		device = string([]rune(device)[:255])
+	}
+	return device
+}
+
+// 判断claims对应的令牌是否已被吊销
+func tokenRevoked(c *gin.Context, claims map[string]interface{}, userId uint) bool {
+	jti, _ := claims["jti"].(string)
+	// 数字在json解析后为float64
+	iatMs, _ := claims["iatMs"].(float64)
+	if iatMs == 0 {
+		// 旧版本令牌只有秒级签发时间
+		iat, _ := claims["iat"].(float64)
+		iatMs = iat * 1000
+	}
+	// 创建服务
+	s := cache_service.New(c)
+	return s.IsTokenRevoked(jti, userId, int64(iatMs))
+}
+
+// 登录过于频繁时的响应, 客户端等待retryAfter秒后再重试
+func tooManyRequestsResponse(c *gin.Context, retryAfter time.Duration) {
+	// 向上取整, 避免客户端过早重试
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	response.Result(response.TooManyRequests, response.LoginTooFrequentMsg, map[string]interface{}{
+		"retryAfter": seconds,
+	})
+}
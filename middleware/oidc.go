@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"fmt"
+	"gin-web/pkg/cache_service"
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"gin-web/pkg/response"
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// 获取SSO登录地址, 前端跳转到该地址完成IdP登录
+func OidcLoginHandler(c *gin.Context) {
+	if !global.Conf.Oidc.Enable {
+		response.FailWithMsg("未开启SSO登录")
+		return
+	}
+	// 创建服务
+	s := cache_service.New(c)
+	url, err := s.OidcAuthUrl()
+	if err != nil {
+		global.Log.Warn("[OidcLoginHandler]", err)
+		response.FailWithMsg("获取SSO登录地址失败")
+		return
+	}
+	response.SuccessWithData(map[string]interface{}{
+		"url": url,
+	})
+}
+
+// SSO回调, 前端回调页面将code/state提交到该接口换取令牌
+func OidcCallbackHandler(authMiddleware *jwt.GinJWTMiddleware) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !global.Conf.Oidc.Enable {
+			response.FailWithMsg("未开启SSO登录")
+			return
+		}
+		var req request.OidcCallbackRequestStruct
+		// 请求json绑定
+		_ = c.ShouldBindJSON(&req)
+		// 参数校验
+		err := global.NewValidatorError(global.Validate.Struct(req), req.FieldTrans())
+		if err != nil {
+			response.FailWithMsg(err.Error())
+			return
+		}
+		// 创建服务
+		s := cache_service.New(c)
+		user, err := s.OidcLogin(req.Code, req.State)
+		if err != nil {
+			global.Log.Warn(fmt.Sprintf("SSO登录失败: %v", err))
+			response.FailWithMsg(fmt.Sprintf("SSO登录失败: %v", err))
+			return
+		}
+		if user.Status != nil && !*user.Status {
+			response.FailWithMsg("用户已被禁用")
+			return
+		}
+		if user.IsLocked() {
+			response.FailWithMsg(response.LoginLockedMsg)
+			return
+		}
+		// 本地开启了二次验证, 同样需要完成挑战
+		if user.TotpOn() {
+			totpRequiredResponse(s.CreateTotpChallenge(user.Id))
+			return
+		}
+		issueTokens(c, authMiddleware, *user, getDevice(c, req.Device), "", "")
+	}
+}
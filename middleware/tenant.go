@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/response"
+	"gin-web/pkg/service"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+// 租户中间件, 从请求头读取租户编号, 未指定时为默认租户
+// 登录后以令牌中的租户为准(参见authorizator)
+func Tenant(c *gin.Context) {
+	header := c.GetHeader(global.TenantHeader)
+	if header != "" {
+		tenantId, err := strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			response.FailWithMsg("租户编号不正确")
+			return
+		}
+		if !tenantEnabled(c, uint(tenantId)) {
+			response.FailWithMsg("租户不存在或已禁用")
+			return
+		}
+		global.SetTenantId(c, uint(tenantId))
+	}
+	// 处理请求
+	c.Next()
+}
+
+// 租户是否可用, 默认租户始终可用
+func tenantEnabled(c *gin.Context, tenantId uint) bool {
+	if tenantId == models.SysTenantDefault {
+		return true
+	}
+	// 创建服务
+	s := service.New(c)
+	tenant, err := s.GetTenantById(tenantId)
+	return err == nil && tenant.Enabled()
+}
@@ -13,6 +13,7 @@ type Model struct {
 	CreatedAt LocalTime  `gorm:"comment:'创建时间'" json:"createdAt"`
 	UpdatedAt LocalTime  `gorm:"comment:'更新时间'" json:"updatedAt"`
 	DeletedAt *LocalTime `gorm:"comment:'删除时间(软删除)'" sql:"index" json:"deletedAt"`
+	TenantId  uint       `gorm:"index;default:0;comment:'租户编号'" json:"tenantId"`
 }
 
 // 表名设置
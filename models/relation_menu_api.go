@@ -0,0 +1,12 @@
+package models
+
+// 菜单(按钮)与接口关联关系
+type RelationMenuApi struct {
+	SysMenuId uint `json:"sysMenuId"`
+	SysApiId  uint `json:"sysApiId"`
+}
+
+func (m RelationMenuApi) TableName() string {
+	// 多对多关系表在tag中写死, 不能加自定义表前缀
+	return "relation_menu_api"
+}
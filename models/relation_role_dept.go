@@ -0,0 +1,12 @@
+package models
+
+// 角色与自定义数据范围部门关联关系
+type RelationRoleDept struct {
+	SysRoleId uint `json:"sysRoleId"`
+	SysDeptId uint `json:"sysDeptId"`
+}
+
+func (m RelationRoleDept) TableName() string {
+	// 多对多关系表在tag中写死, 不能加自定义表前缀
+	return "relation_role_dept"
+}
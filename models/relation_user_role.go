@@ -0,0 +1,12 @@
+package models
+
+// 用户与附加角色关联关系
+type RelationUserRole struct {
+	SysUserId uint `json:"sysUserId"`
+	SysRoleId uint `json:"sysRoleId"`
+}
+
+func (m RelationUserRole) TableName() string {
+	// 多对多关系表在tag中写死, 不能加自定义表前缀
+	return "relation_user_role"
+}
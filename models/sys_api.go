@@ -8,8 +8,14 @@ type SysApi struct {
 	Category string `gorm:"comment:'所属类别'" json:"category"`
 	Desc     string `gorm:"comment:'说明'" json:"desc"`
 	Creator  string `gorm:"comment:'创建人'" json:"creator"`
+	Stale    bool   `gorm:"comment:'路由已失效(启动同步时路由表中不存在)'" json:"stale"`
 }
 
 func (m SysApi) TableName() string {
 	return m.Model.TableName("sys_api")
 }
+
+// 接口权限标识, 如GET:/v1/user/list
+func (m SysApi) PermissionKey() string {
+	return m.Method + ":" + m.Path
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// 个人访问令牌(API密钥), 供CI脚本等机器客户端通过X-Api-Key请求头调用接口
+type SysApiKey struct {
+	Model
+	Name       string   `gorm:"comment:'密钥名称'" json:"name"`
+	KeyHash    string   `gorm:"unique;comment:'密钥sha256摘要(明文仅在创建时返回一次)'" json:"-"`
+	Prefix     string   `gorm:"comment:'密钥前缀(明文, 便于用户识别)'" json:"prefix"`
+	UserId     uint     `gorm:"index;comment:'所属用户Id'" json:"userId"`
+	ExpiresAt  int64    `gorm:"default:0;comment:'过期时间(unix秒, 0表示永不过期)'" json:"expiresAt"`
+	LastUsedAt int64    `gorm:"default:0;comment:'最近使用时间(unix秒)'" json:"lastUsedAt"`
+	LastUsedIp string   `gorm:"comment:'最近使用IP'" json:"lastUsedIp"`
+	Creator    string   `gorm:"comment:'创建人'" json:"creator"`
+	Apis       []SysApi `gorm:"many2many:relation_api_key_api;" json:"apis"` // 权限范围, 只能是所属用户角色已授权接口的子集
+}
+
+func (m SysApiKey) TableName() string {
+	return m.Model.TableName("sys_api_key")
+}
+
+// 是否已过期
+func (m SysApiKey) IsExpired() bool {
+	return m.ExpiresAt > 0 && m.ExpiresAt < time.Now().Unix()
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSysApiKey_IsExpired(t *testing.T) {
+	now := time.Now().Unix()
+	tests := []struct {
+		name      string
+		expiresAt int64
+		want      bool
+	}{
+		{"case1", 0, false},
+		{"case2", now + 3600, false},
+		{"case3", now - 3600, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := SysApiKey{
+				ExpiresAt: tt.expiresAt,
+			}
+			if got := m.IsExpired(); got != tt.want {
+				t.Errorf("IsExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,15 +5,22 @@ import (
 	"gin-web/pkg/global"
 )
 
+// 策略效果, 拒绝优先: 匹配到任一拒绝策略时禁止访问, 否则匹配到允许策略才能访问
+const (
+	SysCasbinEffectAllow = "allow" // 允许
+	SysCasbinEffectDeny  = "deny"  // 拒绝
+)
+
 // Casbin权限访问控制表, 参见github.com/casbin/gorm-adapter/v2/adapter.go CasbinRule
-// 可以根据项目实际需要动态设定, 这里用到了3个字段 角色关键字/资源名称/请求类型
+// 可以根据项目实际需要动态设定, 这里用到了5个字段 角色关键字/租户域/资源名称/请求类型/策略效果
+// 角色继承关系(p_type=g)用到了3个字段 子角色关键字/父角色关键字/租户域
 type SysCasbin struct {
 	PType string `gorm:"size:100;comment:'策略类型'"`
 	V0    string `gorm:"size:100;comment:'角色关键字'"`
-	V1    string `gorm:"size:100;comment:'资源名称'"`
-	V2    string `gorm:"size:100;comment:'请求类型'"`
-	V3    string `gorm:"size:100"`
-	V4    string `gorm:"size:100"`
+	V1    string `gorm:"size:100;comment:'租户域'"`
+	V2    string `gorm:"size:100;comment:'资源名称'"`
+	V3    string `gorm:"size:100;comment:'请求类型'"`
+	V4    string `gorm:"size:100;comment:'策略效果(allow/deny)'"`
 	V5    string `gorm:"size:100"`
 }
 
@@ -27,4 +34,13 @@ type SysRoleCasbin struct {
 	Keyword string `json:"keyword"` // 角色关键字
 	Method  string `json:"method"`  // 请求方式
 	Path    string `json:"path"`    // 访问路径
+	Effect  string `json:"effect"`  // 策略效果(allow/deny), 为空表示允许
+}
+
+// 获取策略效果, 为空表示允许
+func (m SysRoleCasbin) GetEffect() string {
+	if m.Effect == SysCasbinEffectDeny {
+		return SysCasbinEffectDeny
+	}
+	return SysCasbinEffectAllow
 }
@@ -0,0 +1,52 @@
+package models
+
+import "gin-web/pkg/utils"
+
+// 数据范围(行级权限), 由用户全部角色的数据范围合并得到
+type SysDataScope struct {
+	All     bool   // 全部数据
+	UserId  uint   // 本人数据
+	DeptIds []uint // 可见部门(部门内用户的数据)
+}
+
+// 根据用户角色生成数据范围, roles需加载自定义部门(Depts)
+func NewDataScope(user SysUser, roles []SysRole, allDept []SysDept) SysDataScope {
+	scope := SysDataScope{
+		UserId:  user.Id,
+		DeptIds: make([]uint, 0),
+	}
+	for _, role := range roles {
+		if role.Keyword == SysRoleSuperAdminKeyword {
+			scope.All = true
+			continue
+		}
+		deptIds := make([]uint, 0)
+		switch role.DataScope {
+		case SysRoleDataScopeAll:
+			scope.All = true
+		case SysRoleDataScopeDept:
+			if user.DeptId > 0 {
+				deptIds = append(deptIds, user.DeptId)
+			}
+		case SysRoleDataScopeDeptAndChildren:
+			if user.DeptId > 0 {
+				deptIds = GetDeptAndChildrenIds(user.DeptId, allDept)
+			}
+		case SysRoleDataScopeCustom:
+			for _, dept := range role.Depts {
+				deptIds = append(deptIds, dept.Id)
+			}
+		}
+		for _, id := range deptIds {
+			if !utils.ContainsUint(scope.DeptIds, id) {
+				scope.DeptIds = append(scope.DeptIds, id)
+			}
+		}
+	}
+	return scope
+}
+
+// 数据所属用户是否在范围内
+func (m SysDataScope) Contains(userId uint, deptId uint) bool {
+	return m.All || userId == m.UserId || (deptId > 0 && utils.ContainsUint(m.DeptIds, deptId))
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewDataScope(t *testing.T) {
+	// 部门结构: 1 -> 2 -> 3, 4
+	depts := []SysDept{
+		{Model: Model{Id: 1}},
+		{Model: Model{Id: 2}, ParentId: 1},
+		{Model: Model{Id: 3}, ParentId: 2},
+		{Model: Model{Id: 4}},
+	}
+	user := SysUser{Model: Model{Id: 10}, DeptId: 2}
+	tests := []struct {
+		name  string
+		user  SysUser
+		roles []SysRole
+		want  SysDataScope
+	}{
+		{"case1", user, []SysRole{{DataScope: SysRoleDataScopeAll}}, SysDataScope{All: true, UserId: 10, DeptIds: []uint{}}},
+		{"case2", user, []SysRole{{DataScope: SysRoleDataScopeOwn}}, SysDataScope{UserId: 10, DeptIds: []uint{}}},
+		{"case3", user, []SysRole{{DataScope: SysRoleDataScopeDept}}, SysDataScope{UserId: 10, DeptIds: []uint{2}}},
+		{"case4", user, []SysRole{{DataScope: SysRoleDataScopeDeptAndChildren}}, SysDataScope{UserId: 10, DeptIds: []uint{2, 3}}},
+		// 多个角色取并集
+		{"case5", user, []SysRole{
+			{DataScope: SysRoleDataScopeDept},
+			{DataScope: SysRoleDataScopeCustom, Depts: []SysDept{{Model: Model{Id: 4}}, {Model: Model{Id: 2}}}},
+		}, SysDataScope{UserId: 10, DeptIds: []uint{2, 4}}},
+		// 未分配部门
+		{"case6", SysUser{Model: Model{Id: 11}}, []SysRole{{DataScope: SysRoleDataScopeDeptAndChildren}}, SysDataScope{UserId: 11, DeptIds: []uint{}}},
+		{"case7", user, []SysRole{{Keyword: SysRoleSuperAdminKeyword, DataScope: SysRoleDataScopeOwn}}, SysDataScope{All: true, UserId: 10, DeptIds: []uint{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewDataScope(tt.user, tt.roles, depts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewDataScope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSysDataScope_Contains(t *testing.T) {
+	scope := SysDataScope{UserId: 10, DeptIds: []uint{2, 3}}
+	tests := []struct {
+		name   string
+		scope  SysDataScope
+		userId uint
+		deptId uint
+		want   bool
+	}{
+		{"case1", scope, 10, 0, true},
+		{"case2", scope, 11, 3, true},
+		{"case3", scope, 11, 4, false},
+		{"case4", scope, 11, 0, false},
+		{"case5", SysDataScope{All: true}, 11, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Contains(tt.userId, tt.deptId); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import "gin-web/pkg/utils"
+
+// 系统部门表
+type SysDept struct {
+	Model
+	Name     string    `gorm:"comment:'部门名称'" json:"name"`
+	Sort     int       `gorm:"type:int(3);comment:'部门顺序(同级部门, 从0开始, 越小显示越靠前)'" json:"sort"`
+	Status   *bool     `gorm:"type:tinyint(1);default:1;comment:'部门状态(正常/禁用, 默认正常)'" json:"status"` // 由于设置了默认值, 这里使用ptr, 可避免赋值失败
+	ParentId uint      `gorm:"default:0;comment:'父部门编号(编号为0时表示根部门)'" json:"parentId"`
+	Creator  string    `gorm:"comment:'创建人'" json:"creator"`
+	Children []SysDept `gorm:"-" json:"children"` // 子部门集合
+}
+
+func (m SysDept) TableName() string {
+	return m.Model.TableName("sys_dept")
+}
+
+// 获取部门及其全部下级部门编号
+func GetDeptAndChildrenIds(deptId uint, allDept []SysDept) []uint {
+	ids := []uint{deptId}
+	// 逐层查找下级部门, 已加入的部门不再重复查找(避免错误数据形成循环)
+	for i := 0; i < len(ids); i++ {
+		for _, dept := range allDept {
+			if dept.ParentId == ids[i] && !utils.ContainsUint(ids, dept.Id) {
+				ids = append(ids, dept.Id)
+			}
+		}
+	}
+	return ids
+}
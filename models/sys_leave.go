@@ -8,6 +8,7 @@ type SysLeave struct {
 	Status          *uint   `gorm:"default:0;comment:'状态(0:提交 1:批准 2:拒绝 3:取消 4:重启 5:结束)'" json:"status"`
 	ApprovalOpinion string  `gorm:"comment:'审批意见'" json:"approvalOpinion"`
 	Desc            string  `gorm:"comment:'说明'" json:"desc"`
+	Days            float64 `gorm:"comment:'请假天数'" json:"days"`
 }
 
 func (m SysLeave) TableName() string {
@@ -20,6 +20,7 @@ type SysMenu struct {
 	Creator    string    `gorm:"comment:'创建人'" json:"creator"`
 	Children   []SysMenu `gorm:"-" json:"children"`                          // 子菜单集合
 	Roles      []SysRole `gorm:"many2many:relation_role_menu;" json:"roles"` // 角色菜单多对多关系
+	Apis       []SysApi  `gorm:"many2many:relation_menu_api;" json:"apis"`   // 菜单(按钮)依赖的接口, 授权菜单时同时授权接口
 }
 
 func (m SysMenu) TableName() string {
@@ -0,0 +1,12 @@
+package models
+
+// 历史密码, 用于防止重复使用最近的密码
+type SysPwdHistory struct {
+	Model
+	UserId   uint   `gorm:"index;comment:'用户Id'" json:"userId"`
+	Password string `gorm:"comment:'密码(bcrypt)'" json:"-"`
+}
+
+func (m SysPwdHistory) TableName() string {
+	return m.Model.TableName("sys_pwd_history")
+}
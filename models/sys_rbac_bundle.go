@@ -0,0 +1,346 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// 权限配置包版本, 结构变化时递增
+const SysRbacBundleVersion = 1
+
+// 权限配置包(角色/角色菜单/角色继承/casbin策略), 用于在不同环境间迁移权限配置
+// 菜单与角色均使用名称标识而不是编号, 不同环境的编号可能不一致
+type SysRbacBundle struct {
+	Version  int                   `json:"version"`
+	Roles    []SysRbacBundleRole   `json:"roles"`
+	Menus    []SysRbacBundleMenu   `json:"menus"`
+	Parents  []SysRbacBundleParent `json:"parents"`
+	Policies []SysRoleCasbin       `json:"policies"`
+}
+
+// 配置包角色
+type SysRbacBundleRole struct {
+	Keyword      string `json:"keyword"`
+	Name         string `json:"name"`
+	Desc         string `json:"desc"`
+	Status       bool   `json:"status"`
+	TotpRequired bool   `json:"totpRequired"`
+	DataScope    uint   `json:"dataScope"`
+}
+
+// 配置包角色菜单, Menu为菜单标识(参见SysMenu.BundleKey)
+type SysRbacBundleMenu struct {
+	Keyword string `json:"keyword"`
+	Menu    string `json:"menu"`
+}
+
+// 配置包角色继承, Keyword继承自Parent
+type SysRbacBundleParent struct {
+	Keyword string `json:"keyword"`
+	Parent  string `json:"parent"`
+}
+
+// 配置包与数据库的差异, 只包含配置包中出现的角色, 其他角色不受影响
+type SysRbacBundleDiff struct {
+	CreateRoles    []SysRbacBundleRole   `json:"createRoles"`
+	UpdateRoles    []SysRbacBundleRole   `json:"updateRoles"`
+	CreateMenus    []SysRbacBundleMenu   `json:"createMenus"`
+	DeleteMenus    []SysRbacBundleMenu   `json:"deleteMenus"`
+	CreateParents  []SysRbacBundleParent `json:"createParents"`
+	DeleteParents  []SysRbacBundleParent `json:"deleteParents"`
+	CreatePolicies []SysRoleCasbin       `json:"createPolicies"`
+	DeletePolicies []SysRoleCasbin       `json:"deletePolicies"`
+}
+
+// 菜单在配置包中的标识, 优先使用菜单名称, 没有名称时使用访问路径
+func (m SysMenu) BundleKey() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return m.Path
+}
+
+// 转换为配置包角色
+func (m SysRole) BundleRole() SysRbacBundleRole {
+	return SysRbacBundleRole{
+		Keyword:      m.Keyword,
+		Name:         m.Name,
+		Desc:         m.Desc,
+		Status:       m.Status == nil || *m.Status,
+		TotpRequired: m.TotpOn(),
+		DataScope:    m.DataScope,
+	}
+}
+
+// 校验配置包
+func (b SysRbacBundle) Validate() error {
+	if b.Version < 1 || b.Version > SysRbacBundleVersion {
+		return errors.New(fmt.Sprintf("不支持的配置包版本: %d", b.Version))
+	}
+	keywords := make(map[string]bool, 0)
+	for _, role := range b.Roles {
+		if role.Keyword == "" {
+			return errors.New("角色关键字不能为空")
+		}
+		if keywords[role.Keyword] {
+			return errors.New(fmt.Sprintf("角色关键字重复: %s", role.Keyword))
+		}
+		keywords[role.Keyword] = true
+	}
+	for _, menu := range b.Menus {
+		if !keywords[menu.Keyword] {
+			return errors.New(fmt.Sprintf("角色菜单所属角色不存在: %s", menu.Keyword))
+		}
+	}
+	for _, parent := range b.Parents {
+		if !keywords[parent.Keyword] || !keywords[parent.Parent] {
+			return errors.New(fmt.Sprintf("继承关系中的角色不存在: %s -> %s", parent.Keyword, parent.Parent))
+		}
+	}
+	for _, policy := range b.Policies {
+		if !keywords[policy.Keyword] {
+			return errors.New(fmt.Sprintf("策略所属角色不存在: %s", policy.Keyword))
+		}
+	}
+	return nil
+}
+
+// 对比配置包(目标)与当前配置(live), 得到需要执行的变更
+func (b SysRbacBundle) Diff(live SysRbacBundle) SysRbacBundleDiff {
+	diff := SysRbacBundleDiff{
+		CreateRoles:    make([]SysRbacBundleRole, 0),
+		UpdateRoles:    make([]SysRbacBundleRole, 0),
+		CreateMenus:    make([]SysRbacBundleMenu, 0),
+		DeleteMenus:    make([]SysRbacBundleMenu, 0),
+		CreateParents:  make([]SysRbacBundleParent, 0),
+		DeleteParents:  make([]SysRbacBundleParent, 0),
+		CreatePolicies: make([]SysRoleCasbin, 0),
+		DeletePolicies: make([]SysRoleCasbin, 0),
+	}
+	liveRoles := make(map[string]SysRbacBundleRole, 0)
+	for _, role := range live.Roles {
+		liveRoles[role.Keyword] = role
+	}
+	keywords := make(map[string]bool, 0)
+	for _, role := range b.Roles {
+		keywords[role.Keyword] = true
+		old, ok := liveRoles[role.Keyword]
+		if !ok {
+			diff.CreateRoles = append(diff.CreateRoles, role)
+		} else if old != role {
+			diff.UpdateRoles = append(diff.UpdateRoles, role)
+		}
+	}
+
+	liveMenus := make(map[SysRbacBundleMenu]bool, 0)
+	for _, menu := range live.Menus {
+		liveMenus[menu] = true
+	}
+	menus := make(map[SysRbacBundleMenu]bool, 0)
+	for _, menu := range b.Menus {
+		if !menus[menu] && !liveMenus[menu] {
+			diff.CreateMenus = append(diff.CreateMenus, menu)
+		}
+		menus[menu] = true
+	}
+	for _, menu := range live.Menus {
+		if keywords[menu.Keyword] && !menus[menu] {
+			diff.DeleteMenus = append(diff.DeleteMenus, menu)
+		}
+	}
+
+	liveParents := make(map[SysRbacBundleParent]bool, 0)
+	for _, parent := range live.Parents {
+		liveParents[parent] = true
+	}
+	parents := make(map[SysRbacBundleParent]bool, 0)
+	for _, parent := range b.Parents {
+		if !parents[parent] && !liveParents[parent] {
+			diff.CreateParents = append(diff.CreateParents, parent)
+		}
+		parents[parent] = true
+	}
+	for _, parent := range live.Parents {
+		if keywords[parent.Keyword] && !parents[parent] {
+			diff.DeleteParents = append(diff.DeleteParents, parent)
+		}
+	}
+
+	// 策略效果为空表示允许, 对比前统一
+	livePolicies := make(map[SysRoleCasbin]bool, 0)
+	for _, policy := range live.Policies {
+		policy.Effect = policy.GetEffect()
+		livePolicies[policy] = true
+	}
+	policies := make(map[SysRoleCasbin]bool, 0)
+	for _, policy := range b.Policies {
+		policy.Effect = policy.GetEffect()
+		if !policies[policy] && !livePolicies[policy] {
+			diff.CreatePolicies = append(diff.CreatePolicies, policy)
+		}
+		policies[policy] = true
+	}
+	for _, policy := range live.Policies {
+		policy.Effect = policy.GetEffect()
+		if keywords[policy.Keyword] && !policies[policy] {
+			diff.DeletePolicies = append(diff.DeletePolicies, policy)
+		}
+	}
+	return diff
+}
+
+// 是否没有差异
+func (d SysRbacBundleDiff) Empty() bool {
+	return len(d.CreateRoles) == 0 && len(d.UpdateRoles) == 0 &&
+		len(d.CreateMenus) == 0 && len(d.DeleteMenus) == 0 &&
+		len(d.CreateParents) == 0 && len(d.DeleteParents) == 0 &&
+		len(d.CreatePolicies) == 0 && len(d.DeletePolicies) == 0
+}
+
+// 排序, 保证导出结果稳定, 方便版本管理
+func (b *SysRbacBundle) Sort() {
+	sort.Slice(b.Roles, func(i, j int) bool {
+		return b.Roles[i].Keyword < b.Roles[j].Keyword
+	})
+	sort.Slice(b.Menus, func(i, j int) bool {
+		if b.Menus[i].Keyword != b.Menus[j].Keyword {
+			return b.Menus[i].Keyword < b.Menus[j].Keyword
+		}
+		return b.Menus[i].Menu < b.Menus[j].Menu
+	})
+	sort.Slice(b.Parents, func(i, j int) bool {
+		if b.Parents[i].Keyword != b.Parents[j].Keyword {
+			return b.Parents[i].Keyword < b.Parents[j].Keyword
+		}
+		return b.Parents[i].Parent < b.Parents[j].Parent
+	})
+	sort.Slice(b.Policies, func(i, j int) bool {
+		x, y := b.Policies[i], b.Policies[j]
+		if x.Keyword != y.Keyword {
+			return x.Keyword < y.Keyword
+		}
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+		if x.Method != y.Method {
+			return x.Method < y.Method
+		}
+		return x.Effect < y.Effect
+	})
+}
+
+// 导出为csv, 每行第一列为类型:
+// version,版本
+// role,关键字,名称,说明,状态,强制二次验证,数据范围
+// menu,角色关键字,菜单标识
+// parent,角色关键字,父角色关键字
+// p,角色关键字,访问路径,请求方式,策略效果
+func (b SysRbacBundle) Csv() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	records := [][]string{
+		{"version", strconv.Itoa(b.Version)},
+	}
+	for _, role := range b.Roles {
+		records = append(records, []string{
+			"role",
+			role.Keyword,
+			role.Name,
+			role.Desc,
+			strconv.FormatBool(role.Status),
+			strconv.FormatBool(role.TotpRequired),
+			strconv.FormatUint(uint64(role.DataScope), 10),
+		})
+	}
+	for _, menu := range b.Menus {
+		records = append(records, []string{"menu", menu.Keyword, menu.Menu})
+	}
+	for _, parent := range b.Parents {
+		records = append(records, []string{"parent", parent.Keyword, parent.Parent})
+	}
+	for _, policy := range b.Policies {
+		records = append(records, []string{"p", policy.Keyword, policy.Path, policy.Method, policy.GetEffect()})
+	}
+	err := w.WriteAll(records)
+	return buf.Bytes(), err
+}
+
+// 解析csv格式的配置包, 格式参见SysRbacBundle.Csv
+func ParseRbacBundleCsv(data []byte) (SysRbacBundle, error) {
+	bundle := SysRbacBundle{
+		Roles:    make([]SysRbacBundleRole, 0),
+		Menus:    make([]SysRbacBundleMenu, 0),
+		Parents:  make([]SysRbacBundleParent, 0),
+		Policies: make([]SysRoleCasbin, 0),
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	// 不同类型的列数不一致
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return bundle, err
+	}
+	// 各类型的列数
+	fields := map[string]int{
+		"version": 2,
+		"role":    7,
+		"menu":    3,
+		"parent":  3,
+		"p":       5,
+	}
+	for i, record := range records {
+		line := i + 1
+		count, ok := fields[record[0]]
+		if !ok {
+			return bundle, errors.New(fmt.Sprintf("第%d行类型无效: %s", line, record[0]))
+		}
+		if len(record) != count {
+			return bundle, errors.New(fmt.Sprintf("第%d行应有%d列, 实际%d列", line, count, len(record)))
+		}
+		switch record[0] {
+		case "version":
+			bundle.Version, err = strconv.Atoi(record[1])
+		case "role":
+			role := SysRbacBundleRole{
+				Keyword: record[1],
+				Name:    record[2],
+				Desc:    record[3],
+			}
+			role.Status, err = strconv.ParseBool(record[4])
+			if err == nil {
+				role.TotpRequired, err = strconv.ParseBool(record[5])
+			}
+			if err == nil {
+				var dataScope uint64
+				dataScope, err = strconv.ParseUint(record[6], 10, 32)
+				role.DataScope = uint(dataScope)
+			}
+			bundle.Roles = append(bundle.Roles, role)
+		case "menu":
+			bundle.Menus = append(bundle.Menus, SysRbacBundleMenu{
+				Keyword: record[1],
+				Menu:    record[2],
+			})
+		case "parent":
+			bundle.Parents = append(bundle.Parents, SysRbacBundleParent{
+				Keyword: record[1],
+				Parent:  record[2],
+			})
+		case "p":
+			bundle.Policies = append(bundle.Policies, SysRoleCasbin{
+				Keyword: record[1],
+				Path:    record[2],
+				Method:  record[3],
+				Effect:  record[4],
+			})
+		}
+		if err != nil {
+			return bundle, errors.New(fmt.Sprintf("第%d行格式错误: %v", line, err))
+		}
+	}
+	return bundle, nil
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSysRbacBundle_Diff(t *testing.T) {
+	live := SysRbacBundle{
+		Version: SysRbacBundleVersion,
+		Roles: []SysRbacBundleRole{
+			{Keyword: "admin", Name: "管理员", Status: true},
+			{Keyword: "guest", Name: "访客", Status: true},
+		},
+		Menus: []SysRbacBundleMenu{
+			{Keyword: "admin", Menu: "dashboard"},
+			{Keyword: "admin", Menu: "user"},
+			{Keyword: "guest", Menu: "dashboard"},
+		},
+		Parents: []SysRbacBundleParent{},
+		Policies: []SysRoleCasbin{
+			{Keyword: "admin", Path: "/v1/user/list", Method: "GET", Effect: SysCasbinEffectAllow},
+			{Keyword: "admin", Path: "/v1/user/create", Method: "POST", Effect: SysCasbinEffectAllow},
+			{Keyword: "guest", Path: "/v1/user/info", Method: "GET", Effect: SysCasbinEffectAllow},
+		},
+	}
+	empty := SysRbacBundleDiff{
+		CreateRoles:    []SysRbacBundleRole{},
+		UpdateRoles:    []SysRbacBundleRole{},
+		CreateMenus:    []SysRbacBundleMenu{},
+		DeleteMenus:    []SysRbacBundleMenu{},
+		CreateParents:  []SysRbacBundleParent{},
+		DeleteParents:  []SysRbacBundleParent{},
+		CreatePolicies: []SysRoleCasbin{},
+		DeletePolicies: []SysRoleCasbin{},
+	}
+	// 只包含admin角色, 修改名称/菜单/策略并新增继承自tester的角色
+	target := SysRbacBundle{
+		Version: SysRbacBundleVersion,
+		Roles: []SysRbacBundleRole{
+			{Keyword: "admin", Name: "超级管理员", Status: true},
+			{Keyword: "tester", Name: "测试", Status: true, DataScope: SysRoleDataScopeOwn},
+		},
+		Menus: []SysRbacBundleMenu{
+			{Keyword: "admin", Menu: "dashboard"},
+			{Keyword: "admin", Menu: "role"},
+		},
+		Parents: []SysRbacBundleParent{
+			{Keyword: "admin", Parent: "tester"},
+		},
+		Policies: []SysRoleCasbin{
+			// 策略效果为空表示允许
+			{Keyword: "admin", Path: "/v1/user/list", Method: "GET"},
+			{Keyword: "admin", Path: "/v1/user/delete/batch", Method: "DELETE", Effect: SysCasbinEffectDeny},
+		},
+	}
+	want := empty
+	want.CreateRoles = []SysRbacBundleRole{{Keyword: "tester", Name: "测试", Status: true, DataScope: SysRoleDataScopeOwn}}
+	want.UpdateRoles = []SysRbacBundleRole{{Keyword: "admin", Name: "超级管理员", Status: true}}
+	want.CreateMenus = []SysRbacBundleMenu{{Keyword: "admin", Menu: "role"}}
+	want.DeleteMenus = []SysRbacBundleMenu{{Keyword: "admin", Menu: "user"}}
+	want.CreateParents = []SysRbacBundleParent{{Keyword: "admin", Parent: "tester"}}
+	want.CreatePolicies = []SysRoleCasbin{{Keyword: "admin", Path: "/v1/user/delete/batch", Method: "DELETE", Effect: SysCasbinEffectDeny}}
+	want.DeletePolicies = []SysRoleCasbin{{Keyword: "admin", Path: "/v1/user/create", Method: "POST", Effect: SysCasbinEffectAllow}}
+	tests := []struct {
+		name   string
+		target SysRbacBundle
+		want   SysRbacBundleDiff
+	}{
+		{"case1", live, empty},
+		{"case2", target, want},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.target.Diff(live)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+			if got.Empty() != reflect.DeepEqual(tt.want, empty) {
+				t.Errorf("Empty() = %v", got.Empty())
+			}
+		})
+	}
+}
+
+func TestParseRbacBundleCsv(t *testing.T) {
+	bundle := SysRbacBundle{
+		Version: SysRbacBundleVersion,
+		Roles: []SysRbacBundleRole{
+			{Keyword: "admin", Name: "管理员", Desc: "拥有全部权限, 谨慎分配", Status: true, TotpRequired: true},
+		},
+		Menus:    []SysRbacBundleMenu{{Keyword: "admin", Menu: "dashboard"}},
+		Parents:  []SysRbacBundleParent{{Keyword: "admin", Parent: "guest"}},
+		Policies: []SysRoleCasbin{{Keyword: "admin", Path: "/v1/*", Method: "*", Effect: SysCasbinEffectAllow}},
+	}
+	data, err := bundle.Csv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		data    string
+		want    SysRbacBundle
+		wantErr bool
+	}{
+		{"case1", string(data), bundle, false},
+		{"case2", "version,1\nrole,admin\n", SysRbacBundle{}, true},
+		{"case3", "unknown,1\n", SysRbacBundle{}, true},
+		{"case4", "version,1\nrole,admin,管理员,,yes,false,0\n", SysRbacBundle{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRbacBundleCsv([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRbacBundleCsv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRbacBundleCsv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+// 刷新令牌(不透明令牌, 数据库仅保存摘要)
+// 每次使用后轮换: 旧令牌标记为已使用, 签发同一族的新令牌
+// 已使用的令牌再次出现说明可能被盗用, 整个令牌族立即失效
+type SysRefreshToken struct {
+	Model
+	Token     string `gorm:"unique;comment:'刷新令牌sha256摘要'" json:"token"`
+	FamilyId  string `gorm:"index;comment:'令牌族编号(同一次登录轮换产生的令牌属于同一族)'" json:"familyId"`
+	UserId    uint   `gorm:"index;comment:'用户编号'" json:"userId"`
+	Device    string `gorm:"comment:'登录设备'" json:"device"`
+	Jti       string `gorm:"comment:'同时签发的访问令牌jti'" json:"jti"`
+	ExpiresAt int64  `gorm:"comment:'过期时间(unix秒)'" json:"expiresAt"`
+	UsedAt    int64  `gorm:"default:0;comment:'使用时间(unix秒, 0表示未使用)'" json:"usedAt"`
+	Revoked   *bool  `gorm:"type:tinyint(1);default:0;comment:'是否已吊销'" json:"revoked"` // 由于设置了默认值, 这里使用ptr, 可避免赋值失败
+}
+
+func (m SysRefreshToken) TableName() string {
+	return m.Model.TableName("sys_refresh_token")
+}
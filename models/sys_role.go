@@ -1,17 +1,38 @@
 package models
 
+// 角色数据范围, 用于列表查询的行级权限控制(多个角色取并集, 本人数据始终可见)
+// 默认值(0)为仅本人数据, 全部数据需要显式设置, 避免升级后已有角色可以查看全部数据
+const (
+	SysRoleDataScopeOwn             uint = 0 // 仅本人数据(旧版本的1同样视为仅本人数据)
+	SysRoleDataScopeDept            uint = 2 // 本部门数据
+	SysRoleDataScopeDeptAndChildren uint = 3 // 本部门及以下数据
+	SysRoleDataScopeCustom          uint = 4 // 自定义部门数据
+	SysRoleDataScopeAll             uint = 5 // 全部数据
+)
+
+// 超级管理员角色关键字, 不受数据范围限制
+const SysRoleSuperAdminKeyword = "admin"
+
 // 系统角色表
 type SysRole struct {
 	Model
-	Name    string    `gorm:"comment:'角色名称'" json:"name"`
-	Keyword string    `gorm:"unique;comment:'角色关键词'" json:"keyword"`
-	Desc    string    `gorm:"comment:'角色说明'" json:"desc"`
-	Status  *bool     `gorm:"type:tinyint(1);default:1;comment:'角色状态(正常/禁用, 默认正常)'" json:"status"` // 由于设置了默认值, 这里使用ptr, 可避免赋值失败
-	Creator string    `gorm:"comment:'创建人'" json:"creator"`
-	Menus   []SysMenu `gorm:"many2many:relation_role_menu;" json:"menus"` // 角色菜单多对多关系
-	Users   []SysUser `gorm:"foreignkey:RoleId"`                          // 一个角色有多个user
+	Name         string    `gorm:"comment:'角色名称'" json:"name"`
+	Keyword      string    `gorm:"comment:'角色关键词(租户内唯一)'" json:"keyword"`
+	Desc         string    `gorm:"comment:'角色说明'" json:"desc"`
+	Status       *bool     `gorm:"type:tinyint(1);default:1;comment:'角色状态(正常/禁用, 默认正常)'" json:"status"` // 由于设置了默认值, 这里使用ptr, 可避免赋值失败
+	Creator      string    `gorm:"comment:'创建人'" json:"creator"`
+	TotpRequired *bool     `gorm:"type:tinyint(1);default:0;comment:'是否强制该角色用户开启二次验证'" json:"totpRequired"`
+	DataScope    uint      `gorm:"default:0;comment:'数据范围(0:仅本人 2:本部门 3:本部门及以下 4:自定义部门 5:全部)'" json:"dataScope"`
+	Menus        []SysMenu `gorm:"many2many:relation_role_menu;" json:"menus"` // 角色菜单多对多关系
+	Depts        []SysDept `gorm:"many2many:relation_role_dept;" json:"depts"` // 自定义数据范围的部门, 角色部门多对多关系
+	Users        []SysUser `gorm:"foreignkey:RoleId"`                          // 一个角色有多个user(主角色)
 }
 
 func (m SysRole) TableName() string {
 	return m.Model.TableName("sys_role")
 }
+
+// 是否强制开启二次验证
+func (m SysRole) TotpOn() bool {
+	return m.TotpRequired != nil && *m.TotpRequired
+}
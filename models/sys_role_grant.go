@@ -0,0 +1,59 @@
+package models
+
+// 临时授权状态
+const (
+	SysRoleGrantStatusPending  uint = 0 // 待审批
+	SysRoleGrantStatusApproved uint = 1 // 已批准(有效期内生效)
+	SysRoleGrantStatusDenied   uint = 2 // 已拒绝
+	SysRoleGrantStatusCanceled uint = 3 // 已取消
+	SysRoleGrantStatusRevoked  uint = 4 // 已撤销
+	SysRoleGrantStatusExpired  uint = 5 // 已过期
+)
+
+// 临时授权审计操作
+const (
+	SysRoleGrantActionCreate  = "create"  // 申请授权
+	SysRoleGrantActionApprove = "approve" // 批准
+	SysRoleGrantActionDeny    = "deny"    // 拒绝
+	SysRoleGrantActionCancel  = "cancel"  // 取消
+	SysRoleGrantActionRevoke  = "revoke"  // 撤销
+	SysRoleGrantActionExpire  = "expire"  // 到期
+)
+
+// 临时授权表, 在有效期内为用户附加一个角色(如值班/审计)
+type SysRoleGrant struct {
+	Model
+	UserId  uint    `gorm:"index;comment:'被授权用户编号'" json:"userId"`
+	User    SysUser `gorm:"foreignkey:UserId" json:"user"`
+	RoleId  uint    `gorm:"comment:'授权角色编号'" json:"roleId"`
+	Role    SysRole `gorm:"foreignkey:RoleId" json:"role"`
+	StartAt int64   `gorm:"comment:'生效时间(unix秒)'" json:"startAt"`
+	EndAt   int64   `gorm:"index;comment:'失效时间(unix秒)'" json:"endAt"`
+	Reason  string  `gorm:"comment:'授权原因'" json:"reason"`
+	Status  uint    `gorm:"default:0;comment:'状态(0:待审批 1:已批准 2:已拒绝 3:已取消 4:已撤销 5:已过期)'" json:"status"`
+	Creator string  `gorm:"comment:'申请人'" json:"creator"`
+}
+
+func (m SysRoleGrant) TableName() string {
+	return m.Model.TableName("sys_role_grant")
+}
+
+// 指定时间是否生效
+func (m SysRoleGrant) Active(now int64) bool {
+	return m.Status == SysRoleGrantStatusApproved && m.StartAt <= now && now < m.EndAt
+}
+
+// 临时授权审计日志
+type SysRoleGrantLog struct {
+	Model
+	GrantId  uint   `gorm:"index;comment:'临时授权编号'" json:"grantId"`
+	UserId   uint   `gorm:"comment:'被授权用户编号'" json:"userId"`
+	RoleId   uint   `gorm:"comment:'授权角色编号'" json:"roleId"`
+	Action   string `gorm:"comment:'操作(create/approve/deny/cancel/revoke/expire)'" json:"action"`
+	Operator string `gorm:"comment:'操作人'" json:"operator"`
+	Detail   string `gorm:"comment:'操作说明'" json:"detail"`
+}
+
+func (m SysRoleGrantLog) TableName() string {
+	return m.Model.TableName("sys_role_grant_log")
+}
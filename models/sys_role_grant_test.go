@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestSysRoleGrant_Active(t *testing.T) {
+	now := int64(1600000000)
+	tests := []struct {
+		name    string
+		status  uint
+		startAt int64
+		endAt   int64
+		want    bool
+	}{
+		{"case1", SysRoleGrantStatusApproved, now - 3600, now + 3600, true},
+		{"case2", SysRoleGrantStatusApproved, now, now + 3600, true},
+		{"case3", SysRoleGrantStatusApproved, now + 60, now + 3600, false},
+		{"case4", SysRoleGrantStatusApproved, now - 3600, now, false},
+		{"case5", SysRoleGrantStatusPending, now - 3600, now + 3600, false},
+		{"case6", SysRoleGrantStatusRevoked, now - 3600, now + 3600, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := SysRoleGrant{
+				Status:  tt.status,
+				StartAt: tt.startAt,
+				EndAt:   tt.endAt,
+			}
+			if got := m.Active(now); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
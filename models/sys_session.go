@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// 登录会话, 每次登录创建一条记录, 刷新令牌时更新为新的访问令牌jti
+// 开启redis时保存在redis中, 否则保存在数据库中
+type SysSession struct {
+	Model
+	Jti        string `gorm:"unique;comment:'当前访问令牌jti'" json:"jti"`
+	UserId     uint   `gorm:"index;comment:'用户编号'" json:"userId"`
+	Username   string `gorm:"comment:'用户名'" json:"username"`
+	Device     string `gorm:"comment:'登录设备'" json:"device"`
+	Ip         string `gorm:"comment:'登录IP'" json:"ip"`
+	UserAgent  string `gorm:"type:varchar(512);comment:'User-Agent'" json:"userAgent"`
+	LoginAt    int64  `gorm:"comment:'登录时间(unix秒)'" json:"loginAt"`
+	IssuedAt   int64  `gorm:"comment:'当前访问令牌签发时间(unix毫秒)'" json:"issuedAt"`
+	LastSeenAt int64  `gorm:"comment:'最近访问时间(unix秒)'" json:"lastSeenAt"`
+	ExpiresAt  int64  `gorm:"index;comment:'会话过期时间(unix秒, 即刷新令牌过期时间)'" json:"expiresAt"`
+}
+
+func (m SysSession) TableName() string {
+	return m.Model.TableName("sys_session")
+}
+
+// 会话是否已过期
+func (m SysSession) IsExpired() bool {
+	return m.ExpiresAt < time.Now().Unix()
+}
+
+// 过滤已过期或已被吊销的会话
+func ActiveSessions(sessions []SysSession, revocations []SysTokenRevocation) []SysSession {
+	list := make([]SysSession, 0)
+	for _, session := range sessions {
+		if session.IsExpired() || TokenRevoked(revocations, session.Jti, session.UserId, session.IssuedAt) {
+			continue
+		}
+		list = append(list, session)
+	}
+	return list
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestActiveSessions(t *testing.T) {
+	now := time.Now().Unix()
+	s1 := SysSession{Jti: "a", UserId: 1, IssuedAt: now - 100, ExpiresAt: now + 3600}
+	s2 := SysSession{Jti: "b", UserId: 1, IssuedAt: now - 10, ExpiresAt: now + 3600}
+	s3 := SysSession{Jti: "c", UserId: 2, IssuedAt: now - 100, ExpiresAt: now - 1}
+	s4 := SysSession{Jti: "d", UserId: 2, IssuedAt: now - 100, ExpiresAt: now + 3600}
+	sessions := []SysSession{s1, s2, s3, s4}
+	tests := []struct {
+		name        string
+		revocations []SysTokenRevocation
+		want        []SysSession
+	}{
+		// 过期会话被过滤
+		{"case1", nil, []SysSession{s1, s2, s4}},
+		// 单个令牌被吊销
+		{"case2", []SysTokenRevocation{{Jti: "d", UserId: 2}}, []SysSession{s1, s2}},
+		// 用户全部令牌被吊销, 之后签发的令牌不受影响
+		{"case3", []SysTokenRevocation{{UserId: 1, RevokedAt: now - 50}}, []SysSession{s2, s4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ActiveSessions(sessions, tt.revocations); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ActiveSessions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
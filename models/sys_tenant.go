@@ -0,0 +1,103 @@
+package models
+
+import (
+	"gin-web/pkg/global"
+	"github.com/jinzhu/gorm"
+	"reflect"
+	"strconv"
+)
+
+// 默认租户编号, 未开启多租户前的数据均属于默认租户
+const SysTenantDefault uint = 0
+
+// 系统租户表, 租户编号即记录编号, 只能在默认租户下管理
+type SysTenant struct {
+	Model
+	Name    string `gorm:"comment:'租户名称'" json:"name"`
+	Desc    string `gorm:"comment:'租户说明'" json:"desc"`
+	Status  *bool  `gorm:"type:tinyint(1);default:1;comment:'租户状态(正常/禁用, 默认正常)'" json:"status"` // 由于设置了默认值, 这里使用ptr, 可避免赋值失败
+	Creator string `gorm:"comment:'创建人'" json:"creator"`
+}
+
+func (m SysTenant) TableName() string {
+	return m.Model.TableName("sys_tenant")
+}
+
+// 租户是否可用
+func (m SysTenant) Enabled() bool {
+	return m.Status == nil || *m.Status
+}
+
+// 租户对应的casbin域
+func TenantDomain(tenantId uint) string {
+	return strconv.FormatUint(uint64(tenantId), 10)
+}
+
+// 各租户共享的表, 查询时包含默认租户的数据, 写入时仍属于当前租户
+type TenantShared interface {
+	TenantShared() bool
+}
+
+// 菜单/接口由默认租户维护, 其他租户共享
+func (m SysMenu) TenantShared() bool {
+	return true
+}
+
+func (m SysApi) TenantShared() bool {
+	return true
+}
+
+// 注册租户回调: gorm设置了租户编号(global.TenantIdKey)时, 包含TenantId字段的表自动按租户过滤查询/更新/删除, 新增时写入租户编号
+// 未设置租户编号时(如启动任务/定时清理)不做处理
+func RegisterTenantCallbacks(db *gorm.DB) {
+	db.Callback().Create().Before("gorm:create").Register("tenant:create", tenantCreateCallback)
+	db.Callback().Query().Before("gorm:query").Register("tenant:query", tenantQueryCallback)
+	db.Callback().RowQuery().Before("gorm:row_query").Register("tenant:row_query", tenantQueryCallback)
+	db.Callback().Update().Before("gorm:update").Register("tenant:update", tenantUpdateCallback)
+	db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", tenantUpdateCallback)
+}
+
+// 获取租户编号, 表没有TenantId字段时返回false
+func scopeTenantId(scope *gorm.Scope) (uint, bool) {
+	v, ok := scope.Get(global.TenantIdKey)
+	if !ok {
+		return 0, false
+	}
+	if _, ok := scope.FieldByName("TenantId"); !ok {
+		return 0, false
+	}
+	tenantId, _ := v.(uint)
+	return tenantId, true
+}
+
+func tenantCreateCallback(scope *gorm.Scope) {
+	tenantId, ok := scopeTenantId(scope)
+	if !ok {
+		return
+	}
+	if field, _ := scope.FieldByName("TenantId"); field.IsBlank {
+		scope.Err(field.Set(tenantId))
+	}
+}
+
+func tenantQueryCallback(scope *gorm.Scope) {
+	tenantId, ok := scopeTenantId(scope)
+	if !ok {
+		return
+	}
+	column := scope.QuotedTableName() + "." + scope.Quote("tenant_id")
+	if _, shared := reflect.New(scope.GetModelStruct().ModelType).Interface().(TenantShared); shared && tenantId != SysTenantDefault {
+		scope.Search.Where(column+" IN (?)", []uint{SysTenantDefault, tenantId})
+		return
+	}
+	scope.Search.Where(column+" = ?", tenantId)
+}
+
+// 更新/删除只能操作当前租户的数据(共享表同样如此)
+func tenantUpdateCallback(scope *gorm.Scope) {
+	tenantId, ok := scopeTenantId(scope)
+	if !ok {
+		return
+	}
+	scope.Search.Where(scope.QuotedTableName()+"."+scope.Quote("tenant_id")+" = ?", tenantId)
+}
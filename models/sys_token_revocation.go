@@ -0,0 +1,34 @@
+package models
+
+// jwt令牌吊销记录
+// Jti不为空: 吊销单个令牌(如用户登出)
+// Jti为空: 吊销该用户在RevokedAt(含)之前签发的全部令牌(如禁用用户/修改密码)
+type SysTokenRevocation struct {
+	Model
+	Jti       string `gorm:"index;comment:'令牌唯一标识'" json:"jti"`
+	UserId    uint   `gorm:"index;comment:'用户编号'" json:"userId"`
+	RevokedAt int64  `gorm:"comment:'吊销时间(unix毫秒)'" json:"revokedAt"`
+	ExpiresAt int64  `gorm:"comment:'记录过期时间(unix秒, 0表示不过期), 过期后对应令牌已失效, 可清理'" json:"expiresAt"`
+	Reason    string `gorm:"comment:'吊销原因'" json:"reason"`
+}
+
+func (m SysTokenRevocation) TableName() string {
+	return m.Model.TableName("sys_token_revocation")
+}
+
+// 根据吊销记录判断令牌是否失效, iat为令牌签发时间(unix毫秒)
+// 使用毫秒精度, 避免吊销后同一秒内重新登录签发的令牌被误判为已吊销
+func TokenRevoked(revocations []SysTokenRevocation, jti string, userId uint, iat int64) bool {
+	for _, revocation := range revocations {
+		if revocation.Jti != "" {
+			// 单个令牌被吊销
+			if jti != "" && revocation.Jti == jti {
+				return true
+			}
+		} else if revocation.UserId == userId && iat <= revocation.RevokedAt {
+			// 用户全部令牌被吊销, 且当前令牌签发于吊销之前
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package models
+
+import "testing"
+
+func TestTokenRevoked(t *testing.T) {
+	revocations := []SysTokenRevocation{
+		{
+			Jti:       "a",
+			UserId:    1,
+			RevokedAt: 100000,
+		},
+		{
+			UserId:    2,
+			RevokedAt: 200500,
+		},
+	}
+	type args struct {
+		jti    string
+		userId uint
+		iat    int64
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "case1",
+			args: args{jti: "a", userId: 1, iat: 50000},
+			want: true,
+		},
+		{
+			name: "case2",
+			args: args{jti: "b", userId: 1, iat: 50000},
+			want: false,
+		},
+		{
+			name: "case3",
+			args: args{jti: "c", userId: 2, iat: 200500},
+			want: true,
+		},
+		{
+			name: "case4",
+			args: args{jti: "d", userId: 2, iat: 200501},
+			want: false,
+		},
+		{
+			name: "case5",
+			args: args{jti: "", userId: 3, iat: 0},
+			want: false,
+		},
+		{
+			name: "case6",
+			args: args{jti: "e", userId: 2, iat: 200499},
+			want: true,
+		},
+		{
+			name: "case7",
+			args: args{jti: "f", userId: 2, iat: 200900},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TokenRevoked(revocations, tt.args.jti, tt.args.userId, tt.args.iat); got != tt.want {
+				t.Errorf("TokenRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
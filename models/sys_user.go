@@ -1,20 +1,104 @@
 package models
 
+import "time"
+
 // User
 type SysUser struct {
 	Model
-	Username     string  `gorm:"unique;comment:'用户名'" json:"username"`
-	Password     string  `gorm:"comment:'密码'" json:"password"`
-	Mobile       string  `gorm:"comment:'手机'" json:"mobile"`
-	Avatar       string  `gorm:"comment:'头像'" json:"avatar"`
-	Nickname     string  `gorm:"comment:'昵称'" json:"nickname"`
-	Introduction string  `gorm:"comment:'自我介绍'" json:"introduction"`
-	Status       *bool   `gorm:"type:tinyint(1);default:1;comment:'用户状态(正常/禁用, 默认正常)'" json:"status"` // 由于设置了默认值, 这里使用ptr, 可避免赋值失败
-	Creator      string  `gorm:"comment:'创建人'" json:"creator"`
-	RoleId       uint    `gorm:"comment:'角色Id外键'" json:"roleId"`
-	Role         SysRole `gorm:"foreignkey:RoleId" json:"role"` // 将SysUser.RoleId指定为外键
+	Username          string    `gorm:"comment:'用户名(租户内唯一)'" json:"username"`
+	Password          string    `gorm:"comment:'密码'" json:"password"`
+	Mobile            string    `gorm:"comment:'手机'" json:"mobile"`
+	Email             string    `gorm:"comment:'邮箱(用于找回密码)'" json:"email"`
+	Avatar            string    `gorm:"comment:'头像'" json:"avatar"`
+	Nickname          string    `gorm:"comment:'昵称'" json:"nickname"`
+	Introduction      string    `gorm:"comment:'自我介绍'" json:"introduction"`
+	Status            *bool     `gorm:"type:tinyint(1);default:1;comment:'用户状态(正常/禁用, 默认正常)'" json:"status"` // 由于设置了默认值, 这里使用ptr, 可避免赋值失败
+	Creator           string    `gorm:"comment:'创建人'" json:"creator"`
+	LockedUntil       int64     `gorm:"default:0;comment:'锁定截止时间(unix秒, 登录失败次数过多时临时锁定)'" json:"lockedUntil"`
+	MustChangePwd     *bool     `gorm:"type:tinyint(1);default:0;comment:'是否必须修改密码(管理员设置的初始密码)'" json:"mustChangePwd"`
+	PwdChangedAt      int64     `gorm:"default:0;comment:'最近一次修改密码时间(unix秒)'" json:"pwdChangedAt"`
+	TotpEnabled       *bool     `gorm:"type:tinyint(1);default:0;comment:'是否开启二次验证(TOTP)'" json:"totpEnabled"`
+	TotpSecret        string    `gorm:"comment:'二次验证密钥(base32)'" json:"-"` // 敏感数据不参与json序列化(不会写入jwt/接口响应), 需要时直接查询数据库
+	TotpRecoveryCodes string    `gorm:"type:text;comment:'二次验证恢复码(sha256摘要, 逗号分隔)'" json:"-"`
+	TotpLastCounter   int64     `gorm:"default:0;comment:'最近一次使用的TOTP时间步, 防止验证码重放'" json:"-"`
+	OidcSubject       string    `gorm:"index;comment:'SSO用户标识(IdP中的sub)'" json:"oidcSubject"`
+	LdapDn            string    `gorm:"index;comment:'LDAP用户DN'" json:"ldapDn"`
+	DeptId            uint      `gorm:"default:0;comment:'所属部门编号'" json:"deptId"`
+	RoleId            uint      `gorm:"comment:'角色Id外键'" json:"roleId"`
+	Role              SysRole   `gorm:"foreignkey:RoleId" json:"role"`              // 将SysUser.RoleId指定为外键(主角色)
+	Roles             []SysRole `gorm:"many2many:relation_user_role;" json:"roles"` // 附加角色, 用户与角色多对多关系
+	GrantRoles        []SysRole `gorm:"-" json:"grantRoles"`                        // 临时授权的角色(有效期内), 无需保存到数据库
 }
 
 func (m SysUser) TableName() string {
 	return m.Model.TableName("sys_user")
 }
+
+// 是否处于登录锁定状态
+func (m SysUser) IsLocked() bool {
+	return m.LockedUntil > time.Now().Unix()
+}
+
+// 是否已开启二次验证
+func (m SysUser) TotpOn() bool {
+	return m.TotpEnabled != nil && *m.TotpEnabled
+}
+
+// 密码是否已过期, expireDays为0表示永不过期, 外部认证(LDAP/SSO)用户不使用本地密码, 不会过期
+func (m SysUser) PwdExpired(expireDays int, now time.Time) bool {
+	if expireDays <= 0 || m.LdapDn != "" || m.OidcSubject != "" {
+		return false
+	}
+	if m.PwdChangedAt == 0 {
+		// 修改时间未知(历史用户, 迁移时会补全), 不视为过期
+		return false
+	}
+	return now.Unix()-m.PwdChangedAt > int64(expireDays)*24*3600
+}
+
+// 是否必须修改密码(初始密码或密码已过期)
+func (m SysUser) NeedChangePwd(expireDays int) bool {
+	return (m.MustChangePwd != nil && *m.MustChangePwd) || m.PwdExpired(expireDays, time.Now())
+}
+
+// 用户拥有的全部有效角色(主角色+附加角色+临时授权的角色, 去重并忽略已禁用的角色)
+func (m SysUser) AllRoles() []SysRole {
+	roles := make([]SysRole, 0)
+	ids := make(map[uint]bool)
+	for _, role := range append(append([]SysRole{m.Role}, m.Roles...), m.GrantRoles...) {
+		if role.Id == 0 || ids[role.Id] || (role.Status != nil && !*role.Status) {
+			continue
+		}
+		ids[role.Id] = true
+		roles = append(roles, role)
+	}
+	return roles
+}
+
+// 用户全部有效角色的关键词
+func (m SysUser) RoleKeywords() []string {
+	keywords := make([]string, 0)
+	for _, role := range m.AllRoles() {
+		keywords = append(keywords, role.Keyword)
+	}
+	return keywords
+}
+
+// 用户全部有效角色的编号
+func (m SysUser) RoleIds() []uint {
+	ids := make([]uint, 0)
+	for _, role := range m.AllRoles() {
+		ids = append(ids, role.Id)
+	}
+	return ids
+}
+
+// 任一角色强制开启二次验证
+func (m SysUser) TotpRequired() bool {
+	for _, role := range m.AllRoles() {
+		if role.TotpOn() {
+			return true
+		}
+	}
+	return false
+}
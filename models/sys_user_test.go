@@ -0,0 +1,63 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSysUser_PwdExpired(t *testing.T) {
+	now := time.Now()
+	day := int64(24 * 3600)
+	tests := []struct {
+		name       string
+		user       SysUser
+		expireDays int
+		want       bool
+	}{
+		{"case1", SysUser{PwdChangedAt: now.Unix() - 100*day}, 0, false},
+		{"case2", SysUser{PwdChangedAt: now.Unix() - 10*day}, 90, false},
+		{"case3", SysUser{PwdChangedAt: now.Unix() - 100*day}, 90, true},
+		{"case4", SysUser{Model: Model{CreatedAt: LocalTime{Time: now.AddDate(0, 0, -100)}}}, 90, false},
+		{"case5", SysUser{PwdChangedAt: now.Unix() - 100*day, LdapDn: "uid=a,dc=example,dc=com"}, 90, false},
+		{"case6", SysUser{PwdChangedAt: now.Unix() - 100*day, OidcSubject: "1"}, 90, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.PwdExpired(tt.expireDays, now); got != tt.want {
+				t.Errorf("PwdExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSysUser_AllRoles(t *testing.T) {
+	enabled := true
+	disabled := false
+	admin := SysRole{Model: Model{Id: 1}, Keyword: "admin", Status: &enabled}
+	tester := SysRole{Model: Model{Id: 2}, Keyword: "tester", TotpRequired: &enabled}
+	guest := SysRole{Model: Model{Id: 3}, Keyword: "guest", Status: &disabled}
+	tests := []struct {
+		name         string
+		user         SysUser
+		want         []string
+		totpRequired bool
+	}{
+		{"case1", SysUser{}, []string{}, false},
+		{"case2", SysUser{Role: admin}, []string{"admin"}, false},
+		{"case3", SysUser{Role: admin, Roles: []SysRole{admin, tester}}, []string{"admin", "tester"}, true},
+		{"case4", SysUser{Roles: []SysRole{guest, admin}}, []string{"admin"}, false},
+		{"case5", SysUser{Role: admin, GrantRoles: []SysRole{tester, admin}}, []string{"admin", "tester"}, true},
+		{"case6", SysUser{Role: admin, GrantRoles: []SysRole{guest}}, []string{"admin"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.RoleKeywords(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RoleKeywords() = %v, want %v", got, tt.want)
+			}
+			if got := tt.user.TotpRequired(); got != tt.totpRequired {
+				t.Errorf("TotpRequired() = %v, want %v", got, tt.totpRequired)
+			}
+		})
+	}
+}
@@ -1,5 +1,9 @@
 package models
 
+import (
+	"time"
+)
+
 // 流程相关的常量
 const (
 	// 流程类别
@@ -9,22 +13,41 @@ const (
 	SysWorkflowCategoryAllApprovalStr     string = "必须全部通过"
 
 	// 流程目标类别
-	SysWorkflowTargetCategoryLeave    uint   = 1 // 请假
-	SysWorkflowTargetCategoryLeaveStr string = "请假流程"
+	SysWorkflowTargetCategoryLeave        uint   = 1 // 请假
+	SysWorkflowTargetCategoryLeaveStr     string = "请假流程"
+	SysWorkflowTargetCategoryRoleGrant    uint   = 2 // 临时授权
+	SysWorkflowTargetCategoryRoleGrantStr string = "临时授权流程"
 
 	// 流程日志状态
-	SysWorkflowLogStateSubmit      uint   = 0 // 已提交
-	SysWorkflowLogStateApproval    uint   = 1 // 通过
-	SysWorkflowLogStateDeny        uint   = 2 // 拒绝
-	SysWorkflowLogStateCancel      uint   = 3 // 取消
-	SysWorkflowLogStateRestart     uint   = 4 // 重启
-	SysWorkflowLogStateEnd         uint   = 5 // 结束
-	SysWorkflowLogStateSubmitStr   string = "已提交"
-	SysWorkflowLogStateApprovalStr string = "通过"
-	SysWorkflowLogStateDenyStr     string = "拒绝"
-	SysWorkflowLogStateCancelStr   string = "取消"
-	SysWorkflowLogStateRestartStr  string = "重启"
-	SysWorkflowLogStateEndStr      string = "结束"
+	SysWorkflowLogStateSubmit       uint   = 0 // 已提交
+	SysWorkflowLogStateApproval     uint   = 1 // 通过
+	SysWorkflowLogStateDeny         uint   = 2 // 拒绝
+	SysWorkflowLogStateCancel       uint   = 3 // 取消
+	SysWorkflowLogStateRestart      uint   = 4 // 重启
+	SysWorkflowLogStateEnd          uint   = 5 // 结束
+	SysWorkflowLogStateEscalate     uint   = 6 // 升级(超时未审批, 由系统升级给其他角色)
+	SysWorkflowLogStateTransfer     uint   = 7 // 转交(交给其他用户代替自己审批)
+	SysWorkflowLogStateAddBefore    uint   = 8 // 前加签(其他用户审批后再回到自己审批)
+	SysWorkflowLogStateAddAfter     uint   = 9 // 后加签(自己通过后还需其他用户审批)
+	SysWorkflowLogStateSubmitStr    string = "已提交"
+	SysWorkflowLogStateApprovalStr  string = "通过"
+	SysWorkflowLogStateDenyStr      string = "拒绝"
+	SysWorkflowLogStateCancelStr    string = "取消"
+	SysWorkflowLogStateRestartStr   string = "重启"
+	SysWorkflowLogStateEndStr       string = "结束"
+	SysWorkflowLogStateEscalateStr  string = "升级"
+	SysWorkflowLogStateTransferStr  string = "转交"
+	SysWorkflowLogStateAddBeforeStr string = "前加签"
+	SysWorkflowLogStateAddAfterStr  string = "后加签"
+
+	// 流水线超时操作
+	SysWorkflowLineTimeoutNone     uint = 0 // 不处理
+	SysWorkflowLineTimeoutEscalate uint = 1 // 升级给指定角色
+	SysWorkflowLineTimeoutApproval uint = 2 // 自动通过
+	SysWorkflowLineTimeoutDeny     uint = 3 // 自动拒绝
+
+	// 系统执行的操作(提醒/升级/超时自动审批)
+	SysWorkflowActorSystem = "system"
 )
 
 // 定义map方便取值
@@ -34,16 +57,21 @@ var SysWorkflowCategoryConst = map[uint]string{
 }
 
 var SysWorkflowTargetCategoryConst = map[uint]string{
-	SysWorkflowTargetCategoryLeave: SysWorkflowTargetCategoryLeaveStr,
+	SysWorkflowTargetCategoryLeave:     SysWorkflowTargetCategoryLeaveStr,
+	SysWorkflowTargetCategoryRoleGrant: SysWorkflowTargetCategoryRoleGrantStr,
 }
 
 var SysWorkflowLogStateConst = map[uint]string{
-	SysWorkflowLogStateSubmit:   SysWorkflowLogStateSubmitStr,
-	SysWorkflowLogStateApproval: SysWorkflowLogStateApprovalStr,
-	SysWorkflowLogStateDeny:     SysWorkflowLogStateDenyStr,
-	SysWorkflowLogStateCancel:   SysWorkflowLogStateCancelStr,
-	SysWorkflowLogStateRestart:  SysWorkflowLogStateRestartStr,
-	SysWorkflowLogStateEnd:      SysWorkflowLogStateEndStr,
+	SysWorkflowLogStateSubmit:    SysWorkflowLogStateSubmitStr,
+	SysWorkflowLogStateApproval:  SysWorkflowLogStateApprovalStr,
+	SysWorkflowLogStateDeny:      SysWorkflowLogStateDenyStr,
+	SysWorkflowLogStateCancel:    SysWorkflowLogStateCancelStr,
+	SysWorkflowLogStateRestart:   SysWorkflowLogStateRestartStr,
+	SysWorkflowLogStateEnd:       SysWorkflowLogStateEndStr,
+	SysWorkflowLogStateEscalate:  SysWorkflowLogStateEscalateStr,
+	SysWorkflowLogStateTransfer:  SysWorkflowLogStateTransferStr,
+	SysWorkflowLogStateAddBefore: SysWorkflowLogStateAddBeforeStr,
+	SysWorkflowLogStateAddAfter:  SysWorkflowLogStateAddAfterStr,
 }
 
 // 流程
@@ -52,7 +80,7 @@ type SysWorkflow struct {
 	Uuid              string `gorm:"unique;comment:'唯一标识'" json:"uuid"`
 	Category          uint   `gorm:"default:1;comment:'类别(1:每个流水线有一个人通过 2:每个流水线必须所有人审批通过(指定了Users) 其他自行扩展)'" json:"category"`
 	SubmitUserConfirm *bool  `gorm:"type:tinyint(1);default:0;comment:'是否需要提交人确认'" json:"submitUserConfirm"` // 由于设置了默认值, 这里使用ptr, 可避免赋值失败
-	TargetCategory    uint   `gorm:"default:1;comment:'目标类别(1:请假(需要关联SysUser表) 其他自行扩展)'" json:"targetCategory"`
+	TargetCategory    uint   `gorm:"default:1;comment:'目标类别(1:请假(需要关联SysUser表) 2:临时授权 其他自行扩展)'" json:"targetCategory"`
 	Self              *bool  `gorm:"type:tinyint(1);default:0;comment:'是否可以自我审批(当前流水线角色与可能提交人角色一致)'" json:"self"`
 	Name              string `gorm:"comment:'名称'" json:"name"`
 	Desc              string `gorm:"comment:'说明'" json:"desc"`
@@ -75,12 +103,37 @@ type SysWorkflowLine struct {
 	Users  []SysUser   `gorm:"many2many:relation_user_workflow_line;comment:'审批人列表(指定了具体审批人, 则不再使用角色判断)'" json:"users"`
 	Edit   *bool       `gorm:"type:tinyint(1);default:1;comment:'是否有编辑权限'" json:"edit"` // 由于设置了默认值, 这里使用ptr, 可避免赋值失败
 	Name   string      `gorm:"comment:'名称'" json:"name"`
+	// 审批时限, 从待审批日志创建(进入该流水线或同一流水线上一人审批完成)开始计算
+	RemindHours    uint `gorm:"default:0;comment:'超过多少小时未审批提醒审批人(0:不提醒)'" json:"remindHours"`
+	TimeoutHours   uint `gorm:"default:0;comment:'超过多少小时未审批执行超时操作(0:不处理)'" json:"timeoutHours"`
+	TimeoutAction  uint `gorm:"default:0;comment:'超时操作(0:不处理 1:升级给指定角色 2:自动通过 3:自动拒绝)'" json:"timeoutAction"`
+	EscalateRoleId uint `gorm:"default:0;comment:'超时升级的角色编号'" json:"escalateRoleId"`
 }
 
 func (m SysWorkflowLine) TableName() string {
 	return m.Model.TableName("sys_workflow_line")
 }
 
+// 待审批日志到达审批时限需要执行的操作, 不需要处理时返回false
+// 超时操作优先于提醒; 已升级的日志不再升级, 已提醒的日志不再提醒
+func (m SysWorkflowLine) SlaAction(log SysWorkflowLog, now time.Time) (uint, bool) {
+	elapsed := now.Sub(log.CreatedAt.Time)
+	if m.TimeoutHours > 0 && elapsed >= time.Duration(m.TimeoutHours)*time.Hour {
+		switch m.TimeoutAction {
+		case SysWorkflowLineTimeoutEscalate:
+			if m.EscalateRoleId > 0 && log.EscalateRoleId == 0 {
+				return SysWorkflowLineTimeoutEscalate, true
+			}
+		case SysWorkflowLineTimeoutApproval, SysWorkflowLineTimeoutDeny:
+			return m.TimeoutAction, true
+		}
+	}
+	if m.RemindHours > 0 && log.RemindedAt == 0 && elapsed >= time.Duration(m.RemindHours)*time.Hour {
+		return SysWorkflowLineTimeoutNone, true
+	}
+	return SysWorkflowLineTimeoutNone, false
+}
+
 // 用户与流水线关联关系
 type RelationUserWorkflowLine struct {
 	SysUserId         uint `json:"sysUserId"`
@@ -100,7 +153,7 @@ type SysWorkflowLog struct {
 	TargetId         uint            `gorm:"comment:'目标表编号'" json:"targetId"`
 	CurrentLineId    uint            `gorm:"comment:'当前审批线编号'" json:"currentLineId"`
 	CurrentLine      SysWorkflowLine `gorm:"foreignkey:CurrentLineId" json:"currentLine"`
-	Status           *uint           `gorm:"default:0;comment:'状态(0:提交 1:批准 2:拒绝 3:取消 4:重启 5:结束)'" json:"status"`
+	Status           *uint           `gorm:"default:0;comment:'状态(0:提交 1:批准 2:拒绝 3:取消 4:重启 5:结束 6:升级 7:转交 8:前加签 9:后加签)'" json:"status"`
 	End              *bool           `gorm:"default:0;comment:'是否到达末尾'" json:"end"`
 	SubmitUserId     uint            `gorm:"comment:'提交人编号'" json:"submitUserId"`
 	SubmitUser       SysUser         `gorm:"foreignkey:SubmitUserId" json:"submitUser"`
@@ -108,9 +161,46 @@ type SysWorkflowLog struct {
 	ApprovalUserId   uint            `gorm:"comment:'审批人编号'" json:"approvalUserId"`
 	ApprovalUser     SysUser         `gorm:"foreignkey:ApprovalUserId" json:"approvalUser"`
 	ApprovalOpinion  string          `gorm:"comment:'审批意见'" json:"approvalOpinion"`
+	PrevLogId        uint            `gorm:"comment:'上一条日志编号(同一流水线还需其他人继续审批时指向上一条日志)'" json:"prevLogId"`
+	Actor            string          `gorm:"comment:'操作方(为空表示审批人, system表示系统)'" json:"actor"`
+	OnBehalfOfUserId uint            `gorm:"default:0;comment:'被代理的审批人编号(被委托人代为审批时有效)'" json:"onBehalfOfUserId"`
+	OnBehalfOfUser   SysUser         `gorm:"foreignkey:OnBehalfOfUserId" json:"onBehalfOfUser"`
+	AssigneeUserId   uint            `gorm:"default:0;comment:'指定审批人编号(转交/加签时有效, 只有该用户可以审批)'" json:"assigneeUserId"`
+	AssigneeUser     SysUser         `gorm:"foreignkey:AssigneeUserId" json:"assigneeUser"`
+	AssignFromUserId uint            `gorm:"default:0;comment:'发起转交/加签的审批人编号'" json:"assignFromUserId"`
+	AssignStatus     uint            `gorm:"default:0;comment:'指定审批人的方式(7:转交 8:前加签 9:后加签)'" json:"assignStatus"`
+	RemindedAt       int64           `gorm:"default:0;comment:'超时提醒时间(unix秒)'" json:"remindedAt"`
+	EscalateRoleId   uint            `gorm:"default:0;comment:'超时升级的角色编号(该角色用户也可以审批)'" json:"escalateRoleId"`
+	EscalateRole     SysRole         `gorm:"foreignkey:EscalateRoleId" json:"escalateRole"`
 	ApprovingUserIds []uint          `gorm:"-" json:"approvingUserIds"` // status为0提交时有效, 表示审批人列表, 无需保存到数据库
 }
 
 func (m SysWorkflowLog) TableName() string {
 	return m.Model.TableName("sys_workflow_log")
 }
+
+// 是否为加签的审批人(不是流水线配置的审批人, 审批不计入流水线审批进度)
+func (m SysWorkflowLog) Countersign() bool {
+	return m.AssigneeUserId > 0 && m.AssignStatus != SysWorkflowLogStateTransfer
+}
+
+// 计算同一流水线审批进度时该日志对应的审批人, 第二个返回值为false时表示连续审批中断
+// 通过(含后加签)计入审批人(被委托人/转交的审批人代为审批时为原审批人), 升级/转交/前加签以及加签人的审批不影响审批进度, 审批人为0
+func (m SysWorkflowLog) HistoryApprovalUser() (uint, bool) {
+	if m.Status == nil {
+		return 0, false
+	}
+	if m.Countersign() {
+		return 0, true
+	}
+	switch *m.Status {
+	case SysWorkflowLogStateEscalate, SysWorkflowLogStateTransfer, SysWorkflowLogStateAddBefore:
+		return 0, true
+	case SysWorkflowLogStateApproval, SysWorkflowLogStateAddAfter:
+		if m.OnBehalfOfUserId > 0 {
+			return m.OnBehalfOfUserId, true
+		}
+		return m.ApprovalUserId, true
+	}
+	return 0, false
+}
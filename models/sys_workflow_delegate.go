@@ -0,0 +1,47 @@
+package models
+
+// 审批委托: 用户在指定时间段内(如休假)将审批委托给其他用户, 被委托人可以代为审批
+// FlowId为0表示全部流程; 委托不传递(被委托人再委托给其他人不生效)
+type SysWorkflowDelegate struct {
+	Model
+	UserId         uint        `gorm:"index;comment:'委托人编号'" json:"userId"`
+	User           SysUser     `gorm:"foreignkey:UserId" json:"user"`
+	DelegateUserId uint        `gorm:"index;comment:'被委托人编号'" json:"delegateUserId"`
+	DelegateUser   SysUser     `gorm:"foreignkey:DelegateUserId" json:"delegateUser"`
+	FlowId         uint        `gorm:"default:0;comment:'流程编号(0:全部流程)'" json:"flowId"`
+	Flow           SysWorkflow `gorm:"foreignkey:FlowId" json:"flow"`
+	StartAt        int64       `gorm:"comment:'生效时间(unix秒)'" json:"startAt"`
+	EndAt          int64       `gorm:"index;comment:'失效时间(unix秒)'" json:"endAt"`
+	Reason         string      `gorm:"comment:'委托原因'" json:"reason"`
+	Creator        string      `gorm:"comment:'创建人'" json:"creator"`
+}
+
+func (m SysWorkflowDelegate) TableName() string {
+	return m.Model.TableName("sys_workflow_delegate")
+}
+
+// 指定时间是否对流程生效
+func (m SysWorkflowDelegate) Active(flowId uint, now int64) bool {
+	return (m.FlowId == 0 || m.FlowId == flowId) && m.StartAt <= now && now < m.EndAt
+}
+
+// 获取审批人实际代表的待审批人: 审批人本身在待审批列表中时返回自己, 否则返回委托给审批人且正在生效的待审批人
+// 没有审批权限时返回false
+func WorkflowActingUser(approvingUserIds []uint, approvalUserId uint, flowId uint, delegates []SysWorkflowDelegate, now int64) (uint, bool) {
+	if approvalUserId == 0 {
+		return 0, false
+	}
+	for _, userId := range approvingUserIds {
+		if userId == approvalUserId {
+			return userId, true
+		}
+	}
+	for _, userId := range approvingUserIds {
+		for _, delegate := range delegates {
+			if delegate.UserId == userId && delegate.DelegateUserId == approvalUserId && delegate.Active(flowId, now) {
+				return userId, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestWorkflowActingUser(t *testing.T) {
+	now := int64(1600000000)
+	delegates := []SysWorkflowDelegate{
+		{UserId: 1, DelegateUserId: 3, FlowId: 0, StartAt: now - 3600, EndAt: now + 3600},
+		{UserId: 2, DelegateUserId: 4, FlowId: 10, StartAt: now - 3600, EndAt: now + 3600},
+		{UserId: 2, DelegateUserId: 5, FlowId: 0, StartAt: now + 60, EndAt: now + 3600},
+		{UserId: 6, DelegateUserId: 3, FlowId: 0, StartAt: now - 3600, EndAt: now + 3600},
+	}
+	tests := []struct {
+		name             string
+		approvingUserIds []uint
+		approvalUserId   uint
+		flowId           uint
+		wantUserId       uint
+		wantOk           bool
+	}{
+		{"case1", []uint{1, 2}, 1, 10, 1, true},
+		{"case2", []uint{1, 2}, 3, 10, 1, true},
+		{"case3", []uint{2}, 3, 10, 0, false},
+		{"case4", []uint{1, 2}, 4, 10, 2, true},
+		{"case5", []uint{1, 2}, 4, 11, 0, false},
+		{"case6", []uint{1, 2}, 5, 10, 0, false},
+		{"case7", []uint{1, 2}, 0, 10, 0, false},
+		{"case8", []uint{3}, 3, 10, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userId, ok := WorkflowActingUser(tt.approvingUserIds, tt.approvalUserId, tt.flowId, delegates, now)
+			if userId != tt.wantUserId || ok != tt.wantOk {
+				t.Errorf("WorkflowActingUser() = (%v, %v), want (%v, %v)", userId, ok, tt.wantUserId, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/pkg/expr"
+	"sort"
+	"strings"
+)
+
+// 流程连线: 描述流水线之间的流转关系, 一条流水线有多条后续连线时并行审批(分支), 有多条前置连线时需要全部完成才会继续(汇合)
+// FromLineId为0表示从开始节点出发, ToLineId为0表示到达结束节点
+// Condition为流转条件(参见expr包), 根据审批目标及提交明细计算, 为空表示无条件流转
+// IsDefault为默认连线(else), 同一起点的其他连线都不满足条件时才会流转, 不能设置条件
+type SysWorkflowEdge struct {
+	Model
+	FlowId     uint   `gorm:"index;comment:'流程编号'" json:"flowId"`
+	FromLineId uint   `gorm:"comment:'起始流水线编号(0:开始)'" json:"fromLineId"`
+	ToLineId   uint   `gorm:"comment:'目标流水线编号(0:结束)'" json:"toLineId"`
+	Condition  string `gorm:"type:varchar(1024);comment:'流转条件(为空表示无条件)'" json:"condition"`
+	IsDefault  bool   `gorm:"type:tinyint(1);default:0;comment:'是否为默认连线(其他连线都不满足条件时流转)'" json:"isDefault"`
+}
+
+func (m SysWorkflowEdge) TableName() string {
+	return m.Model.TableName("sys_workflow_edge")
+}
+
+// 流程图, 未配置连线的流程按流水线排序依次流转(兼容线性流程)
+type SysWorkflowGraph struct {
+	lineIds    []uint
+	next       map[uint][]uint
+	prev       map[uint][]uint
+	conditions map[[2]uint]string
+	defaults   map[[2]uint]bool
+}
+
+// 根据流水线与连线生成流程图
+func NewWorkflowGraph(lines []SysWorkflowLine, edges []SysWorkflowEdge) SysWorkflowGraph {
+	g := SysWorkflowGraph{
+		lineIds:    make([]uint, 0),
+		next:       make(map[uint][]uint),
+		prev:       make(map[uint][]uint),
+		conditions: make(map[[2]uint]string),
+		defaults:   make(map[[2]uint]bool),
+	}
+	sorted := make([]SysWorkflowLine, len(lines))
+	copy(sorted, lines)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Sort < sorted[j].Sort
+	})
+	for _, line := range sorted {
+		g.lineIds = append(g.lineIds, line.Id)
+	}
+	if len(edges) == 0 {
+		// 线性流程: 开始 -> 序号1 -> 序号2 -> ... -> 结束
+		from := uint(0)
+		for _, lineId := range g.lineIds {
+			g.addEdge(from, lineId)
+			from = lineId
+		}
+		if from > 0 {
+			g.addEdge(from, 0)
+		}
+		return g
+	}
+	for _, edge := range edges {
+		g.addEdge(edge.FromLineId, edge.ToLineId)
+		if condition := strings.TrimSpace(edge.Condition); condition != "" {
+			g.conditions[[2]uint{edge.FromLineId, edge.ToLineId}] = condition
+		}
+		if edge.IsDefault {
+			g.defaults[[2]uint{edge.FromLineId, edge.ToLineId}] = true
+		}
+	}
+	return g
+}
+
+func (g *SysWorkflowGraph) addEdge(from uint, to uint) {
+	for _, id := range g.next[from] {
+		if id == to {
+			return
+		}
+	}
+	g.next[from] = append(g.next[from], to)
+	g.prev[to] = append(g.prev[to], from)
+}
+
+// 开始后的流水线
+func (g SysWorkflowGraph) StartLines() []uint {
+	return g.NextLines(0)
+}
+
+// 后续流水线(不含结束节点), 为空表示该流水线完成后到达结束
+func (g SysWorkflowGraph) NextLines(lineId uint) []uint {
+	ids := make([]uint, 0)
+	for _, id := range g.next[lineId] {
+		if id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// 前置流水线(不含开始节点), 为空表示该流水线是开始后的第一个流水线
+func (g SysWorkflowGraph) PrevLines(lineId uint) []uint {
+	ids := make([]uint, 0)
+	for _, id := range g.prev[lineId] {
+		if id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// 是否配置了流转条件
+func (g SysWorkflowGraph) HasConditions() bool {
+	return len(g.conditions) > 0
+}
+
+// 连线的流转条件
+func (g SysWorkflowGraph) Condition(from uint, to uint) string {
+	return g.conditions[[2]uint{from, to}]
+}
+
+// 是否为默认连线
+func (g SysWorkflowGraph) IsDefault(from uint, to uint) bool {
+	return g.defaults[[2]uint{from, to}]
+}
+
+// 连线是否满足流转条件, 默认连线在同一起点的其他连线都不满足时满足
+func (g SysWorkflowGraph) Match(from uint, to uint, env map[string]interface{}) (bool, error) {
+	if g.IsDefault(from, to) {
+		for _, id := range g.next[from] {
+			if g.IsDefault(from, id) {
+				continue
+			}
+			ok, err := g.Match(from, id, env)
+			if err != nil || ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	condition := g.Condition(from, to)
+	if condition == "" {
+		return true, nil
+	}
+	ok, err := expr.EvalBool(condition, env)
+	if err != nil {
+		return false, fmt.Errorf("连线[%d->%d]的流转条件[%s]执行失败: %v", from, to, condition, err)
+	}
+	return ok, nil
+}
+
+// 根据流转条件获取后续流水线(不含结束节点), 为空表示到达结束
+// 其他连线都不满足条件时走默认连线, 没有满足条件的连线也没有默认连线时返回错误
+func (g SysWorkflowGraph) Route(lineId uint, env map[string]interface{}) ([]uint, error) {
+	ids := make([]uint, 0)
+	matched := false
+	for _, id := range g.next[lineId] {
+		if g.IsDefault(lineId, id) {
+			continue
+		}
+		ok, err := g.Match(lineId, id, env)
+		if err != nil {
+			return ids, err
+		}
+		if !ok {
+			continue
+		}
+		matched = true
+		if id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	if !matched {
+		for _, id := range g.next[lineId] {
+			if !g.IsDefault(lineId, id) {
+				continue
+			}
+			matched = true
+			if id > 0 {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if !matched && len(g.next[lineId]) > 0 {
+		return ids, fmt.Errorf("流水线[%d]没有满足条件的流转连线", lineId)
+	}
+	return ids, nil
+}
+
+// 全部上游流水线(不含开始节点)
+func (g SysWorkflowGraph) Ancestors(lineId uint) []uint {
+	ids := make([]uint, 0)
+	visited := make(map[uint]bool)
+	queue := g.PrevLines(lineId)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		ids = append(ids, id)
+		queue = append(queue, g.PrevLines(id)...)
+	}
+	return ids
+}
+
+// 全部汇合流水线
+func (g SysWorkflowGraph) Joins() []uint {
+	ids := make([]uint, 0)
+	for _, id := range g.lineIds {
+		if g.IsJoin(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// 是否为汇合流水线(需要等待全部前置流水线完成)
+func (g SysWorkflowGraph) IsJoin(lineId uint) bool {
+	return len(g.PrevLines(lineId)) > 1
+}
+
+// 校验流程图: 连线必须属于当前流程, 流转条件语法正确, 不能有环, 且从开始出发的每条路径都能到达结束
+// 连线都有流转条件时必须配置一条默认连线, 保证任何情况下都有连线可以流转
+// 未连线的流水线(如刚新增还未配置连线)不参与流转, 不做校验
+func (g SysWorkflowGraph) Validate() error {
+	if len(g.lineIds) == 0 {
+		return nil
+	}
+	exists := map[uint]bool{0: true}
+	for _, id := range g.lineIds {
+		exists[id] = true
+	}
+	for from, tos := range g.next {
+		for _, to := range tos {
+			if !exists[from] || !exists[to] {
+				return fmt.Errorf("连线[%d->%d]的流水线不属于当前流程", from, to)
+			}
+			if from == to {
+				return fmt.Errorf("连线[%d->%d]不能指向自身", from, to)
+			}
+			if from == 0 && to == 0 {
+				return errors.New("连线不能从开始直接到达结束")
+			}
+			if condition := g.Condition(from, to); condition != "" {
+				if g.IsDefault(from, to) {
+					return fmt.Errorf("连线[%d->%d]为默认连线, 不能设置流转条件", from, to)
+				}
+				if _, err := expr.Compile(condition); err != nil {
+					return fmt.Errorf("连线[%d->%d]的流转条件不正确: %v", from, to, err)
+				}
+			}
+		}
+		if err := g.validateDefault(from); err != nil {
+			return err
+		}
+	}
+	if len(g.StartLines()) == 0 {
+		return errors.New("流程缺少开始连线")
+	}
+	// 拓扑排序检查环
+	inDegree := make(map[uint]int)
+	queue := make([]uint, 0)
+	for _, id := range g.lineIds {
+		inDegree[id] = len(g.PrevLines(id))
+		if inDegree[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+	count := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		count++
+		for _, next := range g.NextLines(id) {
+			inDegree[next]--
+			if inDegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+	if count < len(g.lineIds) {
+		return errors.New("流程连线不能存在循环")
+	}
+	// 从开始出发的流水线不能再有前置流水线, 能到达的流水线必须有后续连线(到达结束的流水线需连线到结束)
+	for _, id := range g.StartLines() {
+		if len(g.PrevLines(id)) > 0 {
+			return fmt.Errorf("流水线[%d]从开始出发, 不能再有前置流水线", id)
+		}
+	}
+	visited := make(map[uint]bool)
+	queue = g.StartLines()
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		if len(g.next[id]) == 0 {
+			return fmt.Errorf("流水线[%d]没有后续连线, 无法到达结束", id)
+		}
+		queue = append(queue, g.NextLines(id)...)
+	}
+	return nil
+}
+
+// 校验默认连线: 最多一条, 连线都有流转条件时必须配置, 存在无条件连线时默认连线永远不会流转
+func (g SysWorkflowGraph) validateDefault(from uint) error {
+	defaults := 0
+	conditional := 0
+	unconditional := 0
+	for _, to := range g.next[from] {
+		if g.IsDefault(from, to) {
+			defaults++
+		} else if g.Condition(from, to) != "" {
+			conditional++
+		} else {
+			unconditional++
+		}
+	}
+	if defaults > 1 {
+		return fmt.Errorf("流水线[%d]只能有一条默认连线", from)
+	}
+	if defaults > 0 && unconditional > 0 {
+		return fmt.Errorf("流水线[%d]存在无条件连线, 默认连线不会生效", from)
+	}
+	if defaults == 0 && conditional > 0 && unconditional == 0 {
+		return fmt.Errorf("流水线[%d]的连线都有流转条件, 需要配置默认连线", from)
+	}
+	return nil
+}
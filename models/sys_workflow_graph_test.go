@@ -0,0 +1,138 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewWorkflowGraph(t *testing.T) {
+	lines := []SysWorkflowLine{
+		{Model: Model{Id: 3}, Sort: 3},
+		{Model: Model{Id: 1}, Sort: 1},
+		{Model: Model{Id: 2}, Sort: 2},
+		{Model: Model{Id: 4}, Sort: 4},
+	}
+	// 1 -> (2, 3) -> 4
+	edges := []SysWorkflowEdge{
+		{FromLineId: 0, ToLineId: 1},
+		{FromLineId: 1, ToLineId: 2},
+		{FromLineId: 1, ToLineId: 3},
+		{FromLineId: 2, ToLineId: 4},
+		{FromLineId: 3, ToLineId: 4},
+		{FromLineId: 4, ToLineId: 0},
+	}
+	linear := NewWorkflowGraph(lines, nil)
+	parallel := NewWorkflowGraph(lines, edges)
+	tests := []struct {
+		name  string
+		graph SysWorkflowGraph
+		line  uint
+		next  []uint
+		prev  []uint
+		join  bool
+	}{
+		{"case1", linear, 1, []uint{2}, []uint{}, false},
+		{"case2", linear, 2, []uint{3}, []uint{1}, false},
+		{"case3", linear, 4, []uint{}, []uint{3}, false},
+		{"case4", parallel, 1, []uint{2, 3}, []uint{}, false},
+		{"case5", parallel, 3, []uint{4}, []uint{1}, false},
+		{"case6", parallel, 4, []uint{}, []uint{2, 3}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.graph.NextLines(tt.line); !reflect.DeepEqual(got, tt.next) {
+				t.Errorf("NextLines() = %v, want %v", got, tt.next)
+			}
+			if got := tt.graph.PrevLines(tt.line); !reflect.DeepEqual(got, tt.prev) {
+				t.Errorf("PrevLines() = %v, want %v", got, tt.prev)
+			}
+			if got := tt.graph.IsJoin(tt.line); got != tt.join {
+				t.Errorf("IsJoin() = %v, want %v", got, tt.join)
+			}
+		})
+	}
+}
+
+func TestSysWorkflowGraph_Validate(t *testing.T) {
+	lines := []SysWorkflowLine{
+		{Model: Model{Id: 1}, Sort: 1},
+		{Model: Model{Id: 2}, Sort: 2},
+		{Model: Model{Id: 3}, Sort: 3},
+	}
+	tests := []struct {
+		name    string
+		edges   []SysWorkflowEdge
+		wantErr bool
+	}{
+		{"case1", nil, false},
+		{"case2", []SysWorkflowEdge{{ToLineId: 1}, {ToLineId: 2}, {FromLineId: 1, ToLineId: 3}, {FromLineId: 2, ToLineId: 3}, {FromLineId: 3}}, false},
+		{"case3", []SysWorkflowEdge{{FromLineId: 1, ToLineId: 2}, {FromLineId: 2, ToLineId: 3}}, true},
+		{"case4", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 2}, {FromLineId: 2, ToLineId: 3}, {FromLineId: 3, ToLineId: 2}}, true},
+		{"case5", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 2}, {FromLineId: 2, ToLineId: 9}}, true},
+		{"case6", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 2}}, true},
+		{"case7", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 1}, {FromLineId: 1, ToLineId: 2}, {FromLineId: 1, ToLineId: 3}}, true},
+		{"case8", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 2, Condition: "days > 3"}, {FromLineId: 1, ToLineId: 3, IsDefault: true}, {FromLineId: 2, ToLineId: 3}, {FromLineId: 3}}, false},
+		{"case9", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 2, Condition: "days >"}, {FromLineId: 2}}, true},
+		{"case10", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1}, {FromLineId: 2}}, false},
+		{"case11", []SysWorkflowEdge{{ToLineId: 1}, {ToLineId: 2}, {FromLineId: 1, ToLineId: 2}, {FromLineId: 2}}, true},
+		// 连线都有条件但没有默认连线
+		{"case12", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 2, Condition: "days > 3"}, {FromLineId: 1, ToLineId: 3, Condition: "days <= 3"}, {FromLineId: 2, ToLineId: 3}, {FromLineId: 3}}, true},
+		// 默认连线不能设置条件
+		{"case13", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 2, Condition: "days > 3"}, {FromLineId: 1, ToLineId: 3, Condition: "days <= 3", IsDefault: true}, {FromLineId: 2, ToLineId: 3}, {FromLineId: 3}}, true},
+		// 多条默认连线
+		{"case14", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 2, Condition: "days > 3"}, {FromLineId: 1, ToLineId: 2, IsDefault: true}, {FromLineId: 1, ToLineId: 3, IsDefault: true}, {FromLineId: 2, ToLineId: 3}, {FromLineId: 3}}, true},
+		// 存在无条件连线时默认连线不会生效
+		{"case15", []SysWorkflowEdge{{ToLineId: 1}, {FromLineId: 1, ToLineId: 2}, {FromLineId: 1, ToLineId: 3, IsDefault: true}, {FromLineId: 2, ToLineId: 3}, {FromLineId: 3}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewWorkflowGraph(lines, tt.edges).Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSysWorkflowGraph_Route(t *testing.T) {
+	lines := []SysWorkflowLine{
+		{Model: Model{Id: 1}, Sort: 1},
+		{Model: Model{Id: 2}, Sort: 2},
+		{Model: Model{Id: 3}, Sort: 3},
+	}
+	// 超过3天需要总监(2)审批, 否则(默认连线)直接到人事(3)
+	edges := []SysWorkflowEdge{
+		{ToLineId: 1},
+		{FromLineId: 1, ToLineId: 2, Condition: `days > 3 && user.role == "leave"`},
+		{FromLineId: 1, ToLineId: 3, IsDefault: true},
+		{FromLineId: 2, ToLineId: 3},
+		{FromLineId: 3},
+	}
+	graph := NewWorkflowGraph(lines, edges)
+	user := map[string]interface{}{"role": "leave"}
+	tests := []struct {
+		name    string
+		line    uint
+		env     map[string]interface{}
+		want    []uint
+		wantErr bool
+	}{
+		{"case1", 1, map[string]interface{}{"days": float64(5), "user": user}, []uint{2}, false},
+		{"case2", 1, map[string]interface{}{"days": float64(2), "user": user}, []uint{3}, false},
+		{"case3", 1, map[string]interface{}{"days": float64(5)}, []uint{3}, false},
+		{"case4", 1, map[string]interface{}{}, []uint{}, true},
+		{"case5", 3, map[string]interface{}{}, []uint{}, false},
+		{"case6", 0, map[string]interface{}{}, []uint{1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := graph.Route(tt.line, tt.env)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Route() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Route() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
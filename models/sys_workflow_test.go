@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSysWorkflowLine_SlaAction(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	tests := []struct {
+		name           string
+		line           SysWorkflowLine
+		hours          int
+		remindedAt     int64
+		escalateRoleId uint
+		wantAction     uint
+		wantOk         bool
+	}{
+		{"case1", SysWorkflowLine{}, 100, 0, 0, SysWorkflowLineTimeoutNone, false},
+		{"case2", SysWorkflowLine{RemindHours: 2}, 1, 0, 0, SysWorkflowLineTimeoutNone, false},
+		{"case3", SysWorkflowLine{RemindHours: 2}, 2, 0, 0, SysWorkflowLineTimeoutNone, true},
+		{"case4", SysWorkflowLine{RemindHours: 2}, 3, now.Unix(), 0, SysWorkflowLineTimeoutNone, false},
+		{"case5", SysWorkflowLine{RemindHours: 2, TimeoutHours: 4, TimeoutAction: SysWorkflowLineTimeoutApproval}, 3, 0, 0, SysWorkflowLineTimeoutNone, true},
+		{"case6", SysWorkflowLine{RemindHours: 2, TimeoutHours: 4, TimeoutAction: SysWorkflowLineTimeoutApproval}, 4, now.Unix(), 0, SysWorkflowLineTimeoutApproval, true},
+		{"case7", SysWorkflowLine{TimeoutHours: 4, TimeoutAction: SysWorkflowLineTimeoutDeny}, 5, 0, 0, SysWorkflowLineTimeoutDeny, true},
+		{"case8", SysWorkflowLine{TimeoutHours: 4, TimeoutAction: SysWorkflowLineTimeoutEscalate, EscalateRoleId: 2}, 5, 0, 0, SysWorkflowLineTimeoutEscalate, true},
+		{"case9", SysWorkflowLine{TimeoutHours: 4, TimeoutAction: SysWorkflowLineTimeoutEscalate, EscalateRoleId: 2}, 5, 0, 2, SysWorkflowLineTimeoutNone, false},
+		{"case10", SysWorkflowLine{RemindHours: 2, TimeoutHours: 4, TimeoutAction: SysWorkflowLineTimeoutEscalate, EscalateRoleId: 2}, 5, 0, 2, SysWorkflowLineTimeoutNone, true},
+		{"case11", SysWorkflowLine{TimeoutHours: 4, TimeoutAction: SysWorkflowLineTimeoutEscalate}, 5, 0, 0, SysWorkflowLineTimeoutNone, false},
+		{"case12", SysWorkflowLine{TimeoutHours: 4}, 5, 0, 0, SysWorkflowLineTimeoutNone, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log SysWorkflowLog
+			log.CreatedAt.Time = now.Add(-time.Duration(tt.hours) * time.Hour)
+			log.RemindedAt = tt.remindedAt
+			log.EscalateRoleId = tt.escalateRoleId
+			action, ok := tt.line.SlaAction(log, now)
+			if action != tt.wantAction || ok != tt.wantOk {
+				t.Errorf("SlaAction() = (%v, %v), want (%v, %v)", action, ok, tt.wantAction, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestSysWorkflowLog_HistoryApprovalUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         uint
+		onBehalfOf     uint
+		assigneeUserId uint
+		assignStatus   uint
+		wantUserId     uint
+		wantNext       bool
+	}{
+		{"case1", SysWorkflowLogStateApproval, 0, 0, 0, 2, true},
+		{"case2", SysWorkflowLogStateApproval, 3, 0, 0, 3, true},
+		{"case3", SysWorkflowLogStateDeny, 0, 0, 0, 0, false},
+		{"case4", SysWorkflowLogStateEscalate, 0, 0, 0, 0, true},
+		{"case5", SysWorkflowLogStateTransfer, 0, 0, 0, 0, true},
+		{"case6", SysWorkflowLogStateAddBefore, 0, 0, 0, 0, true},
+		{"case7", SysWorkflowLogStateAddAfter, 0, 0, 0, 2, true},
+		{"case8", SysWorkflowLogStateApproval, 3, 2, SysWorkflowLogStateTransfer, 3, true},
+		{"case9", SysWorkflowLogStateApproval, 0, 2, SysWorkflowLogStateAddBefore, 0, true},
+		{"case10", SysWorkflowLogStateDeny, 0, 2, SysWorkflowLogStateAddBefore, 0, true},
+		{"case11", SysWorkflowLogStateApproval, 0, 2, SysWorkflowLogStateAddAfter, 0, true},
+		{"case12", SysWorkflowLogStateCancel, 0, 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.status
+			m := SysWorkflowLog{
+				Status:           &status,
+				ApprovalUserId:   2,
+				OnBehalfOfUserId: tt.onBehalfOf,
+				AssigneeUserId:   tt.assigneeUserId,
+				AssignStatus:     tt.assignStatus,
+			}
+			userId, next := m.HistoryApprovalUser()
+			if userId != tt.wantUserId || next != tt.wantNext {
+				t.Errorf("HistoryApprovalUser() = (%v, %v), want (%v, %v)", userId, next, tt.wantUserId, tt.wantNext)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package cache_service
 
 import (
 	"fmt"
+	"gin-web/models"
 	"gin-web/pkg/global"
 	"gin-web/pkg/service"
 	"gin-web/pkg/utils"
@@ -46,23 +47,46 @@ func (s RedisService) JsonQuery() *gojsonq.JSONQ {
 }
 
 // 从缓存中获取model全部数据, 返回json字符串, 参数list为结构体数组, 必须传地址否则可能没数据
+// 按租户隔离的表只返回当前租户的数据, 共享表还包括默认租户的数据
 func (s RedisService) GetListFromCache(list interface{}, tableName string) string {
-	// 缓存键由数据库名与表名组成
-	cacheKey := fmt.Sprintf("%s_%s", global.Conf.Mysql.Database, tableName)
+	tenantId, ok := s.mysql.TenantId()
+	shared, isTenantTable := global.TenantTables[tableName]
+	var res string
+	if !ok || !isTenantTable || tenantId == models.SysTenantDefault {
+		res = s.getCache(global.CacheKey(tableName, models.SysTenantDefault))
+	} else {
+		rows := make([]interface{}, 0)
+		if shared {
+			utils.Json2Struct(s.getCache(global.CacheKey(tableName, models.SysTenantDefault)), &rows)
+		}
+		tenantRows := make([]interface{}, 0)
+		utils.Json2Struct(s.getCache(global.CacheKey(tableName, tenantId)), &tenantRows)
+		res = utils.Struct2Json(append(rows, tenantRows...))
+	}
+	if list != nil {
+		utils.Json2Struct(res, list)
+	}
+	return res
+}
+
+// 读取缓存, 如果是空字符串, 将其设置为空数组, 否则list会被转为nil
+func (s RedisService) getCache(cacheKey string) string {
 	res, err := s.redis.Get(cacheKey).Result()
 	if err != nil {
 		global.Log.Debug(fmt.Sprintf("[GetListFromCache]读取redis缓存异常: %v", err))
 	}
 	if res == "" {
-		// 如果是空字符串, 将其设置为空数组, 否则list会被转为nil
 		res = "[]"
 	}
-	if list != nil {
-		utils.Json2Struct(res, list)
-	}
 	return res
 }
 
+// 当前租户的casbin域
+func (s RedisService) domain() string {
+	tenantId, _ := s.mysql.TenantId()
+	return models.TenantDomain(tenantId)
+}
+
 // 从缓存中获取model全部数据, 返回json字符串, 参数m为结构体, 必须传地址否则可能没数据
 func (s RedisService) GetItemByIdFromCache(id uint, m interface{}, tableName string) error {
 	json := s.GetListFromCache(nil, tableName)
@@ -37,6 +37,9 @@ func (s *RedisService) GetApis(req *request.ApiListRequestStruct) ([]models.SysA
 	if creator != "" {
 		query = query.Where("creator", "contains", creator)
 	}
+	if req.Stale != nil {
+		query = query.Where("stale", "=", *req.Stale)
+	}
 	// 查询条数
 	req.PageInfo.Total = uint(query.Count())
 	var res interface{}
@@ -54,7 +57,7 @@ func (s *RedisService) GetApis(req *request.ApiListRequestStruct) ([]models.SysA
 }
 
 // 根据权限编号获取以api分类分组的权限接口
-func (s *RedisService) GetAllApiGroupByCategoryByRoleId(roleId uint) ([]response.ApiGroupByCategoryResponseStruct, []uint, error) {
+func (s *RedisService) GetAllApiGroupByCategoryByRoleId(roleId uint) ([]response.ApiGroupByCategoryResponseStruct, []uint, []uint, error) {
 	if !global.Conf.System.UseRedis {
 		// 不使用redis
 		return s.mysql.GetAllApiGroupByCategoryByRoleId(roleId)
@@ -63,13 +66,15 @@ func (s *RedisService) GetAllApiGroupByCategoryByRoleId(roleId uint) ([]response
 	tree := make([]response.ApiGroupByCategoryResponseStruct, 0)
 	// 有权限访问的id列表
 	accessIds := make([]uint, 0)
+	// 拒绝访问的id列表
+	denyIds := make([]uint, 0)
 	allApi := make([]models.SysApi, 0)
 	// 查询接口表所有缓存
 	_ = s.GetListFromCache(&allApi, new(models.SysApi).TableName())
 	// 查询当前角色拥有api访问权限的casbin规则
 	casbins, err := s.GetCasbinListByRoleId(roleId)
 	if err != nil {
-		return tree, accessIds, err
+		return tree, accessIds, denyIds, err
 	}
 
 	// 通过分类进行分组归纳
@@ -78,17 +83,25 @@ func (s *RedisService) GetAllApiGroupByCategoryByRoleId(roleId uint) ([]response
 		path := api.Path
 		method := api.Method
 		access := false
+		deny := false
 		for _, casbin := range casbins {
-			// 该api有权限
-			if path == casbin.V1 && method == casbin.V2 {
-				access = true
-				break
+			if path == casbin.V2 && method == casbin.V3 {
+				if casbin.V4 == models.SysCasbinEffectDeny {
+					// 该api被拒绝
+					deny = true
+				} else {
+					// 该api有权限
+					access = true
+				}
 			}
 		}
 		// 加入权限集合
 		if access {
 			accessIds = append(accessIds, api.Id)
 		}
+		if deny {
+			denyIds = append(denyIds, api.Id)
+		}
 		// 生成接口树
 		existIndex := -1
 		children := make([]response.ApiListResponseStruct, 0)
@@ -116,5 +129,5 @@ func (s *RedisService) GetAllApiGroupByCategoryByRoleId(roleId uint) ([]response
 			})
 		}
 	}
-	return tree, accessIds, err
+	return tree, accessIds, denyIds, err
 }
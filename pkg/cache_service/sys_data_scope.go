@@ -0,0 +1,48 @@
+package cache_service
+
+import (
+	"gin-web/models"
+	"gin-web/pkg/global"
+)
+
+// 获取用户的数据范围(合并全部角色及继承的角色)
+func (s *RedisService) GetDataScope(user models.SysUser) (models.SysDataScope, error) {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.GetDataScope(user)
+	}
+	roles, err := s.GetImplicitRoles(user.RoleIds())
+	if err != nil {
+		return models.SysDataScope{}, err
+	}
+	// 加载自定义数据范围的部门
+	relations := make([]models.RelationRoleDept, 0)
+	_ = s.GetListFromCache(&relations, new(models.RelationRoleDept).TableName())
+	depts := s.getAllDept()
+	for i, role := range roles {
+		for _, relation := range relations {
+			if relation.SysRoleId != role.Id {
+				continue
+			}
+			for _, dept := range depts {
+				if dept.Id == relation.SysDeptId {
+					roles[i].Depts = append(roles[i].Depts, dept)
+				}
+			}
+		}
+	}
+	return models.NewDataScope(user, roles, depts), nil
+}
+
+// 获取数据范围内的用户编号(JsonQuery只支持int数组, 不支持uint)
+func (s *RedisService) getDataScopeUserIds(scope models.SysDataScope) []int {
+	ids := make([]int, 0)
+	users := make([]models.SysUser, 0)
+	_ = s.GetListFromCache(&users, new(models.SysUser).TableName())
+	for _, user := range users {
+		if scope.Contains(user.Id, user.DeptId) {
+			ids = append(ids, int(user.Id))
+		}
+	}
+	return ids
+}
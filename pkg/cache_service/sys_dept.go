@@ -0,0 +1,29 @@
+package cache_service
+
+import (
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/service"
+	"gin-web/pkg/utils"
+)
+
+// 获取部门树
+func (s *RedisService) GetDepts() ([]models.SysDept, error) {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.GetDepts()
+	}
+	// 生成部门树
+	return service.GenDeptTree(nil, s.getAllDept()), nil
+}
+
+// 获取全部部门, 非部门树
+func (s *RedisService) getAllDept() []models.SysDept {
+	depts := make([]models.SysDept, 0)
+	jsonDepts := s.GetListFromCache(nil, new(models.SysDept).TableName())
+	// 查询所有部门, 根据sort字段排序
+	res := s.JsonQuery().FromString(jsonDepts).SortBy("sort").Get()
+	// 转换为结构体
+	utils.Struct2StructByJson(res, &depts)
+	return depts
+}
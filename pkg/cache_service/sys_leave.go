@@ -8,7 +8,7 @@ import (
 	"strings"
 )
 
-// 获取所有请假(当前用户)
+// 获取所有请假(当前用户数据范围内)
 func (s *RedisService) GetLeaves(req *request.LeaveListRequestStruct) ([]models.SysLeave, error) {
 	if !global.Conf.System.UseRedis {
 		// 不使用redis
@@ -19,7 +19,13 @@ func (s *RedisService) GetLeaves(req *request.LeaveListRequestStruct) ([]models.
 	// 查询请假表所有缓存
 	jsonLeaves := s.GetListFromCache(nil, new(models.SysLeave).TableName())
 	query := s.JsonQuery().FromString(jsonLeaves)
-	query = query.Where("userId", "=", int(req.UserId))
+	if req.DataScope != nil && !req.DataScope.All {
+		// 按数据范围过滤
+		query = query.WhereIn("userId", s.getDataScopeUserIds(*req.DataScope))
+	}
+	if req.UserId > 0 {
+		query = query.Where("userId", "=", int(req.UserId))
+	}
 	if req.Status != nil {
 		// redis存的json转换为int, 因此这里转一下类型
 		query = query.Where("status", "=", int(*req.Status))
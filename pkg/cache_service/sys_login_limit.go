@@ -0,0 +1,118 @@
+package cache_service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/pkg/global"
+	"gin-web/pkg/response"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 登录前校验: IP失败次数过多直接拒绝, 递增延迟未结束时拒绝并返回剩余等待时间
+func (s *RedisService) LoginLimitCheck(username string, ip string) (time.Duration, error) {
+	conf := global.Conf.LoginLimit
+	if conf.MaxIpFailures > 0 && s.getTempCount(loginFailuresIpKey(ip)) >= conf.MaxIpFailures {
+		return 0, errors.New(response.LoginIpLockedMsg)
+	}
+	// 递增延迟, 降低暴力破解速度(不阻塞请求, 由客户端等待后重试)
+	retryAfter := s.loginRetryAfter(loginRetryIpKey(ip))
+	if d := s.loginRetryAfter(s.loginRetryUserKey(username)); d > retryAfter {
+		retryAfter = d
+	}
+	if retryAfter > 0 {
+		return retryAfter, errors.New(response.LoginTooFrequentMsg)
+	}
+	return 0, nil
+}
+
+// 登录失败, 累加失败次数并记录下次允许登录的时间, 达到上限时锁定账号
+func (s *RedisService) LoginFailed(username string, ip string) {
+	conf := global.Conf.LoginLimit
+	window := time.Duration(conf.FailureWindow) * time.Minute
+	lock := time.Duration(conf.LockMinutes) * time.Minute
+	// IP维度在锁定时间内有效
+	ipFailures := s.incrTemp(loginFailuresIpKey(ip), lock)
+	userKey := s.loginFailuresUserKey(username)
+	failures := s.incrTemp(userKey, window)
+	s.setLoginRetry(loginRetryIpKey(ip), ipFailures)
+	if ipFailures > failures {
+		s.setLoginRetry(s.loginRetryUserKey(username), ipFailures)
+	} else {
+		s.setLoginRetry(s.loginRetryUserKey(username), failures)
+	}
+	if conf.MaxFailures > 0 && failures >= conf.MaxFailures {
+		// 锁定账号(用户不存在时不会更新任何数据), 重新开始计数
+		err := s.mysql.LockUserByUsername(username, time.Now().Add(lock).Unix())
+		if err != nil {
+			global.Log.Warn("[LoginFailed]", err)
+		}
+		s.delTemp(userKey)
+	}
+}
+
+// 登录成功, 清除用户名维度的失败次数
+func (s *RedisService) LoginSucceeded(username string) {
+	s.delTemp(s.loginFailuresUserKey(username))
+	s.delTemp(s.loginRetryUserKey(username))
+}
+
+// 解锁用户(管理员操作)
+func (s *RedisService) UnlockUserById(id uint) error {
+	user, err := s.mysql.UnlockUserById(id)
+	if err != nil {
+		return err
+	}
+	s.delTemp(s.loginFailuresUserKey(user.Username))
+	s.delTemp(s.loginRetryUserKey(user.Username))
+	return nil
+}
+
+// 按失败次数计算延迟, 记录下次允许登录的时间(纳秒时间戳)
+func (s *RedisService) setLoginRetry(key string, failures int64) {
+	conf := global.Conf.LoginLimit
+	delay := time.Duration(failures*int64(conf.DelayStep)) * time.Millisecond
+	maxDelay := time.Duration(conf.MaxDelay) * time.Millisecond
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if delay <= 0 {
+		return
+	}
+	s.setTemp(key, strconv.FormatInt(time.Now().Add(delay).UnixNano(), 10), delay)
+}
+
+// 距离下次允许登录的剩余时间
+func (s *RedisService) loginRetryAfter(key string) time.Duration {
+	next, err := strconv.ParseInt(s.getTemp(key), 10, 64)
+	if err != nil {
+		return 0
+	}
+	if d := time.Until(time.Unix(0, next)); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// 用户名维度的缓存键, 用户名在租户内唯一
+func (s RedisService) loginFailuresUserKey(username string) string {
+	tenantId, _ := s.mysql.TenantId()
+	return fmt.Sprintf("%s_login_failures_tenant_%d_user_%s", global.Conf.Mysql.Database, tenantId, strings.ToLower(strings.TrimSpace(username)))
+}
+
+// IP维度的缓存键
+func loginFailuresIpKey(ip string) string {
+	return fmt.Sprintf("%s_login_failures_ip_%s", global.Conf.Mysql.Database, ip)
+}
+
+// 用户名维度下次允许登录时间的缓存键
+func (s RedisService) loginRetryUserKey(username string) string {
+	tenantId, _ := s.mysql.TenantId()
+	return fmt.Sprintf("%s_login_retry_tenant_%d_user_%s", global.Conf.Mysql.Database, tenantId, strings.ToLower(strings.TrimSpace(username)))
+}
+
+// IP维度下次允许登录时间的缓存键
+func loginRetryIpKey(ip string) string {
+	return fmt.Sprintf("%s_login_retry_ip_%s", global.Conf.Mysql.Database, ip)
+}
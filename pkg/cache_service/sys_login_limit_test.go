@@ -0,0 +1,159 @@
+package cache_service
+
+import (
+	"gin-web/pkg/global"
+	"gin-web/pkg/response"
+	"gin-web/tests"
+	"testing"
+	"time"
+)
+
+// 使用内存存储, 不依赖redis/mysql
+func initLoginLimitConf(maxFailures int64, maxIpFailures int64, delayStep int, maxDelay int) {
+	global.Conf.System.UseRedis = false
+	global.Conf.LoginLimit = global.LoginLimitConfiguration{
+		MaxFailures:   maxFailures,
+		MaxIpFailures: maxIpFailures,
+		FailureWindow: 15,
+		LockMinutes:   30,
+		DelayStep:     delayStep,
+		MaxDelay:      maxDelay,
+	}
+}
+
+// 失败次数按用户名/IP分别累加, 登录成功只清除用户名维度
+func TestRedisService_LoginFailedCounter(t *testing.T) {
+	initLoginLimitConf(0, 0, 0, 0)
+	s := New(nil)
+	s.LoginFailed("counter", "10.0.0.1")
+	s.LoginFailed("Counter ", "10.0.0.1")
+	s.LoginFailed("other", "10.0.0.1")
+	tests := []struct {
+		name string
+		key  string
+		want int64
+	}{
+		{"case1", s.loginFailuresUserKey("counter"), 2},
+		{"case2", s.loginFailuresUserKey("other"), 1},
+		{"case3", loginFailuresIpKey("10.0.0.1"), 3},
+		{"case4", loginFailuresIpKey("10.0.0.2"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.getTempCount(tt.key); got != tt.want {
+				t.Errorf("getTempCount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	s.LoginSucceeded("counter")
+	if got := s.getTempCount(s.loginFailuresUserKey("counter")); got != 0 {
+		t.Errorf("getTempCount() after LoginSucceeded = %v, want 0", got)
+	}
+	if got := s.getTempCount(loginFailuresIpKey("10.0.0.1")); got != 3 {
+		t.Errorf("getTempCount() ip after LoginSucceeded = %v, want 3", got)
+	}
+}
+
+// 递增延迟未结束时拒绝登录并返回剩余等待时间, 不阻塞请求
+func TestRedisService_LoginLimitCheckDelay(t *testing.T) {
+	initLoginLimitConf(0, 0, 1000, 2500)
+	s := New(nil)
+	if _, err := s.LoginLimitCheck("delay", "10.0.1.1"); err != nil {
+		t.Fatalf("LoginLimitCheck() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		failures int
+		min      time.Duration
+		max      time.Duration
+	}{
+		{"case1", 1, 900 * time.Millisecond, time.Second},
+		{"case2", 1, 1900 * time.Millisecond, 2 * time.Second},
+		// 不超过最大延迟
+		{"case3", 3, 2400 * time.Millisecond, 2500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tt.failures; i++ {
+				s.LoginFailed("delay", "10.0.1.1")
+			}
+			start := time.Now()
+			retryAfter, err := s.LoginLimitCheck("delay", "10.0.1.1")
+			if time.Since(start) > 100*time.Millisecond {
+				t.Errorf("LoginLimitCheck() blocked %v", time.Since(start))
+			}
+			if err == nil || err.Error() != response.LoginTooFrequentMsg {
+				t.Errorf("LoginLimitCheck() error = %v, want %s", err, response.LoginTooFrequentMsg)
+			}
+			if retryAfter < tt.min || retryAfter > tt.max {
+				t.Errorf("LoginLimitCheck() retryAfter = %v, want [%v, %v]", retryAfter, tt.min, tt.max)
+			}
+		})
+	}
+	// 同一IP的其他用户名同样需要等待
+	if _, err := s.LoginLimitCheck("delay2", "10.0.1.1"); err == nil {
+		t.Errorf("LoginLimitCheck() same ip error = nil, want error")
+	}
+	// 其他IP的其他用户名不受影响
+	if _, err := s.LoginLimitCheck("delay2", "10.0.1.2"); err != nil {
+		t.Errorf("LoginLimitCheck() other ip error = %v", err)
+	}
+}
+
+// 延迟结束后允许再次登录
+func TestRedisService_LoginLimitCheckDelayExpired(t *testing.T) {
+	initLoginLimitConf(0, 0, 50, 50)
+	s := New(nil)
+	s.LoginFailed("expired", "10.0.2.1")
+	if _, err := s.LoginLimitCheck("expired", "10.0.2.1"); err == nil {
+		t.Fatalf("LoginLimitCheck() error = nil, want error")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := s.LoginLimitCheck("expired", "10.0.2.1"); err != nil {
+		t.Errorf("LoginLimitCheck() after delay error = %v", err)
+	}
+}
+
+// IP失败次数达到上限后直接拒绝
+func TestRedisService_LoginLimitCheckIpLocked(t *testing.T) {
+	initLoginLimitConf(0, 3, 0, 0)
+	s := New(nil)
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"case1", false},
+		{"case2", false},
+		{"case3", false},
+		{"case4", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.LoginLimitCheck("ip", "10.0.3.1")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoginLimitCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && err.Error() != response.LoginIpLockedMsg {
+				t.Errorf("LoginLimitCheck() error = %v, want %s", err, response.LoginIpLockedMsg)
+			}
+			s.LoginFailed("ip", "10.0.3.1")
+		})
+	}
+}
+
+// 用户名失败次数达到上限后锁定账号并重新计数
+func TestRedisService_LoginFailedLock(t *testing.T) {
+	tests.InitTestEnv()
+	initLoginLimitConf(3, 0, 0, 0)
+	s := New(nil)
+	for i := 0; i < 2; i++ {
+		s.LoginFailed("lock", "10.0.4.1")
+	}
+	if got := s.getTempCount(s.loginFailuresUserKey("lock")); got != 2 {
+		t.Fatalf("getTempCount() = %v, want 2", got)
+	}
+	s.LoginFailed("lock", "10.0.4.1")
+	if got := s.getTempCount(s.loginFailuresUserKey("lock")); got != 0 {
+		t.Errorf("getTempCount() after lock = %v, want 0", got)
+	}
+}
@@ -1,26 +1,33 @@
 package cache_service
 
 import (
+	"errors"
 	"gin-web/models"
 	"gin-web/pkg/global"
 	"gin-web/pkg/service"
 	"gin-web/pkg/utils"
 )
 
-// 获取权限菜单树
-func (s *RedisService) GetMenuTree(roleId uint) ([]models.SysMenu, error) {
+// 获取权限菜单树, 合并用户全部角色(含继承的角色)的菜单
+func (s *RedisService) GetMenuTree(roleIds []uint) ([]models.SysMenu, error) {
 	if !global.Conf.System.UseRedis {
 		// 不使用redis
-		return s.mysql.GetMenuTree(roleId)
+		return s.mysql.GetMenuTree(roleIds)
 	}
 	tree := make([]models.SysMenu, 0)
-	var role models.SysRole
-	err := s.GetItemByIdFromCache(roleId, &role, role.TableName())
+	roles, err := s.GetImplicitRoles(roleIds)
 	if err != nil {
 		return tree, err
 	}
-	// 当前角色拥有的全部菜单, 生成菜单树
-	tree = service.GenMenuTree(nil, s.getMenusByRoleId(roleId))
+	if len(roles) == 0 {
+		return tree, errors.New("菜单为空")
+	}
+	ids := make([]uint, 0)
+	for _, role := range roles {
+		ids = append(ids, role.Id)
+	}
+	// 角色拥有的全部菜单, 生成菜单树
+	tree = service.GenMenuTree(nil, s.getMenusByRoleIds(ids))
 	return tree, nil
 }
 
@@ -51,7 +58,7 @@ func (s *RedisService) GetAllMenuByRoleId(roleId uint) ([]models.SysMenu, []uint
 	// 获取全部菜单
 	allMenu := s.getAllMenu()
 	// 查询角色拥有的全部菜单
-	roleMenus := s.getMenusByRoleId(roleId)
+	roleMenus := s.getMenusByRoleIds([]uint{roleId})
 	// 生成菜单树
 	tree = service.GenMenuTree(nil, allMenu)
 	// 获取id列表
@@ -63,8 +70,8 @@ func (s *RedisService) GetAllMenuByRoleId(roleId uint) ([]models.SysMenu, []uint
 	return tree, accessIds, nil
 }
 
-// 获取当前角色拥有的全部菜单
-func (s *RedisService) getMenusByRoleId(roleId uint) []models.SysMenu {
+// 获取角色拥有的全部菜单(多个角色时合并去重)
+func (s *RedisService) getMenusByRoleIds(roleIds []uint) []models.SysMenu {
 	menus := make([]models.SysMenu, 0)
 	relations := make([]models.RelationRoleMenu, 0)
 	_ = s.GetListFromCache(&relations, new(models.RelationRoleMenu).TableName())
@@ -72,11 +79,11 @@ func (s *RedisService) getMenusByRoleId(roleId uint) []models.SysMenu {
 	// JsonQuery只支持int数组, 不支持uint
 	menuIds := make([]int, 0)
 	for _, relation := range relations {
-		if relation.SysRoleId == roleId {
+		if utils.Contains(roleIds, relation.SysRoleId) {
 			menuIds = append(menuIds, int(relation.SysMenuId))
 		}
 	}
-	res := s.JsonQuery().FromString(jsonMenus).WhereIn("id", menuIds).Get()
+	res := s.JsonQuery().FromString(jsonMenus).WhereIn("id", menuIds).SortBy("sort").Get()
 
 	// 转换为结构体
 	utils.Struct2StructByJson(res, &menus)
@@ -93,3 +100,20 @@ func (s *RedisService) getAllMenu() []models.SysMenu {
 	utils.Struct2StructByJson(res, &menus)
 	return menus
 }
+
+// 获取菜单(按钮)依赖的接口编号
+func (s *RedisService) GetMenuApiIds(menuId uint) ([]uint, error) {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.GetMenuApiIds(menuId)
+	}
+	apiIds := make([]uint, 0)
+	relations := make([]models.RelationMenuApi, 0)
+	_ = s.GetListFromCache(&relations, new(models.RelationMenuApi).TableName())
+	for _, relation := range relations {
+		if relation.SysMenuId == menuId {
+			apiIds = append(apiIds, relation.SysApiId)
+		}
+	}
+	return apiIds, nil
+}
@@ -47,5 +47,93 @@ func (s *RedisService) GetRoles(req *request.RoleListRequestStruct) ([]models.Sy
 	}
 	// 转换为结构体
 	utils.Struct2StructByJson(res, &list)
+	// 加载自定义数据范围的部门
+	relations := make([]models.RelationRoleDept, 0)
+	_ = s.GetListFromCache(&relations, new(models.RelationRoleDept).TableName())
+	for i, role := range list {
+		for _, relation := range relations {
+			if relation.SysRoleId == role.Id {
+				list[i].Depts = append(list[i].Depts, models.SysDept{
+					Model: models.Model{
+						Id: relation.SysDeptId,
+					},
+				})
+			}
+		}
+	}
 	return list, err
 }
+
+// 获取角色直接继承的父角色编号
+func (s *RedisService) GetRoleParentIds(roleId uint) ([]uint, error) {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.GetRoleParentIds(roleId)
+	}
+	parentIds := make([]uint, 0)
+	var role models.SysRole
+	err := s.GetItemByIdFromCache(roleId, &role, role.TableName())
+	if err != nil {
+		return parentIds, err
+	}
+	e, err := s.Casbin()
+	if err != nil {
+		return parentIds, err
+	}
+	// 查询符合字段v0=role.Keyword的所有继承关系(g, 子角色, 父角色, 租户域)
+	keywords := make([]string, 0)
+	for _, v := range e.GetFilteredGroupingPolicy(0, role.Keyword, "", s.domain()) {
+		keywords = append(keywords, v[1])
+	}
+	for _, parent := range s.getRolesByKeywords(keywords) {
+		parentIds = append(parentIds, parent.Id)
+	}
+	return parentIds, nil
+}
+
+// 获取角色自身及继承的全部角色(去重)
+func (s *RedisService) GetImplicitRoles(roleIds []uint) ([]models.SysRole, error) {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.GetImplicitRoles(roleIds)
+	}
+	roles := make([]models.SysRole, 0)
+	keywords := make([]string, 0)
+	allRoles := make([]models.SysRole, 0)
+	_ = s.GetListFromCache(&allRoles, new(models.SysRole).TableName())
+	for _, role := range allRoles {
+		if utils.Contains(roleIds, role.Id) {
+			keywords = append(keywords, role.Keyword)
+		}
+	}
+	if len(keywords) == 0 {
+		return roles, nil
+	}
+	e, err := s.Casbin()
+	if err != nil {
+		return roles, err
+	}
+	// 继承的角色同样需要是有效角色
+	for _, role := range s.getRolesByKeywords(e.GetImplicitRoles(s.domain(), keywords...)) {
+		if role.Status == nil || *role.Status {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// 根据关键词获取角色
+func (s *RedisService) getRolesByKeywords(keywords []string) []models.SysRole {
+	roles := make([]models.SysRole, 0)
+	if len(keywords) == 0 {
+		return roles
+	}
+	allRoles := make([]models.SysRole, 0)
+	_ = s.GetListFromCache(&allRoles, new(models.SysRole).TableName())
+	for _, role := range allRoles {
+		if utils.Contains(keywords, role.Keyword) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,220 @@
+package cache_service
+
+import (
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"gin-web/pkg/service"
+	"gin-web/pkg/utils"
+	"github.com/go-redis/redis"
+	"strings"
+	"time"
+)
+
+// 开启redis时会话保存在redis中:
+// {db}_session_{jti}: 会话json, 过期时间与会话一致
+// {db}_sessions: 全部会话的jti有序集合, 分值为过期时间
+// {db}_sessions_user_{userId}: 用户会话的jti有序集合, 分值为过期时间
+// 集合中已过期的成员在读取时清理
+
+// 创建登录会话
+func (s *RedisService) CreateSession(session models.SysSession) error {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.CreateSession(session)
+	}
+	return s.saveSession(session)
+}
+
+// 刷新令牌后更新会话(保留登录时间), 会话不存在时重新创建
+func (s *RedisService) RefreshSession(oldJti string, session models.SysSession) error {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.RefreshSession(oldJti, session)
+	}
+	oldSession, ok := s.getSession(oldJti)
+	if ok {
+		session.LoginAt = oldSession.LoginAt
+		s.deleteSessions([]models.SysSession{oldSession})
+	}
+	return s.saveSession(session)
+}
+
+// 更新会话最近访问时间
+func (s *RedisService) TouchSession(jti string) {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		s.mysql.TouchSession(jti)
+		return
+	}
+	session, ok := s.getSession(jti)
+	now := time.Now().Unix()
+	if !ok || session.LastSeenAt >= now-service.SessionTouchInterval {
+		return
+	}
+	session.LastSeenAt = now
+	err := s.saveSession(session)
+	if err != nil {
+		global.Log.Warn("[TouchSession]", err)
+	}
+}
+
+// 删除会话(如用户登出)
+func (s *RedisService) DeleteSession(jti string) error {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.DeleteSession(jti)
+	}
+	session, ok := s.getSession(jti)
+	if ok {
+		s.deleteSessions([]models.SysSession{session})
+	}
+	return nil
+}
+
+// 获取有效会话, req.UserId不为0时只查询该用户
+func (s *RedisService) GetSessions(req *request.SessionListRequestStruct) ([]models.SysSession, error) {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.GetSessions(req)
+	}
+	setKey := sessionsKey()
+	if req.UserId > 0 {
+		setKey = userSessionsKey(req.UserId)
+	}
+	sessions, err := s.getSessionsBySet(setKey)
+	if err != nil {
+		return sessions, err
+	}
+	username := strings.TrimSpace(req.Username)
+	list := make([]models.SysSession, 0)
+	for _, session := range sessions {
+		if username != "" && !strings.Contains(session.Username, username) {
+			continue
+		}
+		list = append(list, session)
+	}
+	// 查询吊销记录表所有缓存, 过滤已被吊销的会话
+	revocations := make([]models.SysTokenRevocation, 0)
+	s.GetListFromCache(&revocations, new(models.SysTokenRevocation).TableName())
+	return service.PageSessions(models.ActiveSessions(list, revocations), req), nil
+}
+
+// 强制下线, userId不为0时只能操作该用户自己的会话
+func (s *RedisService) KillSessions(userId uint, jtis []string, reason string) error {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.KillSessions(userId, jtis, reason)
+	}
+	killed := make([]models.SysSession, 0)
+	for _, jti := range jtis {
+		session, ok := s.getSession(jti)
+		if !ok || (userId > 0 && session.UserId != userId) {
+			continue
+		}
+		// 吊销记录写入数据库, 通过binlog同步到缓存
+		err := s.mysql.RevokeSession(session, reason)
+		if err != nil {
+			return err
+		}
+		killed = append(killed, session)
+	}
+	s.deleteSessions(killed)
+	return nil
+}
+
+// 保存会话
+func (s *RedisService) saveSession(session models.SysSession) error {
+	expiration := time.Until(time.Unix(session.ExpiresAt, 0))
+	if expiration <= 0 {
+		return nil
+	}
+	member := redis.Z{
+		Score:  float64(session.ExpiresAt),
+		Member: session.Jti,
+	}
+	pipe := s.redis.TxPipeline()
+	pipe.Set(sessionKey(session.Jti), utils.Struct2Json(session), expiration)
+	pipe.ZAdd(sessionsKey(), member)
+	pipe.ZAdd(userSessionsKey(session.UserId), member)
+	_, err := pipe.Exec()
+	return err
+}
+
+// 读取会话
+func (s *RedisService) getSession(jti string) (models.SysSession, bool) {
+	var session models.SysSession
+	if jti == "" {
+		return session, false
+	}
+	res, err := s.redis.Get(sessionKey(jti)).Result()
+	if err != nil || res == "" {
+		return session, false
+	}
+	utils.Json2Struct(res, &session)
+	return session, session.Jti != ""
+}
+
+// 读取有序集合中的全部会话, 同时清理已过期的成员
+func (s *RedisService) getSessionsBySet(setKey string) ([]models.SysSession, error) {
+	sessions := make([]models.SysSession, 0)
+	err := s.redis.ZRemRangeByScore(setKey, "-inf", fmt.Sprintf("(%d", time.Now().Unix())).Err()
+	if err != nil {
+		return sessions, err
+	}
+	jtis, err := s.redis.ZRange(setKey, 0, -1).Result()
+	if err != nil || len(jtis) == 0 {
+		return sessions, err
+	}
+	keys := make([]string, 0)
+	for _, jti := range jtis {
+		keys = append(keys, sessionKey(jti))
+	}
+	values, err := s.redis.MGet(keys...).Result()
+	if err != nil {
+		return sessions, err
+	}
+	for _, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var session models.SysSession
+		utils.Json2Struct(str, &session)
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// 删除会话
+func (s *RedisService) deleteSessions(sessions []models.SysSession) {
+	if len(sessions) == 0 {
+		return
+	}
+	pipe := s.redis.TxPipeline()
+	for _, session := range sessions {
+		pipe.Del(sessionKey(session.Jti))
+		pipe.ZRem(sessionsKey(), session.Jti)
+		pipe.ZRem(userSessionsKey(session.UserId), session.Jti)
+	}
+	_, err := pipe.Exec()
+	if err != nil {
+		global.Log.Warn("[deleteSessions]", err)
+	}
+}
+
+// 会话缓存键
+func sessionKey(jti string) string {
+	return fmt.Sprintf("%s_session_%s", global.Conf.Mysql.Database, jti)
+}
+
+// 全部会话集合缓存键
+func sessionsKey() string {
+	return fmt.Sprintf("%s_sessions", global.Conf.Mysql.Database)
+}
+
+// 用户会话集合缓存键
+func userSessionsKey(userId uint) string {
+	return fmt.Sprintf("%s_sessions_user_%d", global.Conf.Mysql.Database, userId)
+}
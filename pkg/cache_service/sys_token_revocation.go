@@ -0,0 +1,27 @@
+package cache_service
+
+import (
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/utils"
+)
+
+// 判断令牌是否已被吊销
+func (s *RedisService) IsTokenRevoked(jti string, userId uint, iat int64) bool {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.IsTokenRevoked(jti, userId, iat)
+	}
+	revocations := make([]models.SysTokenRevocation, 0)
+	// 查询吊销记录表所有缓存
+	jsonRevocations := s.GetListFromCache(nil, new(models.SysTokenRevocation).TableName())
+	res := s.JsonQuery().
+		FromString(jsonRevocations).
+		Where("jti", "=", jti).
+		// userId在JSONQ中以int存在
+		OrWhere("userId", "=", int(userId)).
+		Get()
+	// 转换为结构体
+	utils.Struct2StructByJson(res, &revocations)
+	return models.TokenRevoked(revocations, jti, userId, iat)
+}
@@ -6,8 +6,10 @@ import (
 	"gin-web/pkg/global"
 	"gin-web/pkg/request"
 	"gin-web/pkg/response"
+	"gin-web/pkg/service"
 	"gin-web/pkg/utils"
 	"strings"
+	"time"
 )
 
 // 登录校验
@@ -22,7 +24,8 @@ func (s *RedisService) LoginCheck(user *models.SysUser) (*models.SysUser, error)
 	jsonUsers := s.GetListFromCache(nil, u.TableName())
 	res1, err := JsonQueryFindOne(s.JsonQuery().FromString(jsonUsers).Where("username", "=", user.Username))
 	if err != nil {
-		return nil, err
+		// 本地用户不存在, 可能是首次登录的LDAP用户
+		return s.mysql.AuthenticateByOrder(user.Username, user.Password, nil, err)
 	}
 	utils.Struct2StructByJson(res1, &u)
 	jsonRoles := s.GetListFromCache(nil, role.TableName())
@@ -32,11 +35,12 @@ func (s *RedisService) LoginCheck(user *models.SysUser) (*models.SysUser, error)
 	}
 	utils.Struct2StructByJson(res2, &role)
 	u.Role = role
-	// 校验密码
-	if ok := utils.ComparePwd(user.Password, u.Password); !ok {
-		return nil, errors.New(response.LoginCheckErrorMsg)
+	// 登录失败次数过多, 账号已锁定
+	if u.IsLocked() {
+		return nil, errors.New(response.LoginLockedMsg)
 	}
-	return &u, nil
+	// 按认证顺序校验密码(本地密码/LDAP)
+	return s.mysql.AuthenticateByOrder(user.Username, user.Password, &u, nil)
 }
 
 func (s *RedisService) GetUsers(req *request.UserListRequestStruct) ([]models.SysUser, error) {
@@ -49,6 +53,10 @@ func (s *RedisService) GetUsers(req *request.UserListRequestStruct) ([]models.Sy
 	// 查询接口表所有缓存
 	jsonUsers := s.GetListFromCache(nil, new(models.SysUser).TableName())
 	query := s.JsonQuery().FromString(jsonUsers)
+	if req.DataScope != nil && !req.DataScope.All {
+		// 按数据范围过滤
+		query = query.WhereIn("id", s.getDataScopeUserIds(*req.DataScope))
+	}
 	username := strings.TrimSpace(req.Username)
 	if username != "" {
 		query = query.Where("username", "contains", username)
@@ -68,6 +76,9 @@ func (s *RedisService) GetUsers(req *request.UserListRequestStruct) ([]models.Sy
 	if req.Status != nil {
 		query = query.Where("status", "=", *req.Status)
 	}
+	if req.DeptId > 0 {
+		query = query.Where("deptId", "=", int(req.DeptId))
+	}
 	// 查询条数
 	req.PageInfo.Total = uint(query.Count())
 	var res interface{}
@@ -81,6 +92,11 @@ func (s *RedisService) GetUsers(req *request.UserListRequestStruct) ([]models.Sy
 	}
 	// 转换为结构体
 	utils.Struct2StructByJson(res, &list)
+	// 附加角色
+	userRoles := s.getUserRoles()
+	for i, user := range list {
+		list[i].Roles = userRoles[user.Id]
+	}
 	return list, err
 }
 
@@ -103,5 +119,93 @@ func (s *RedisService) GetUserById(id uint) (models.SysUser, error) {
 	utils.Struct2StructByJson(res, &role)
 	// 将角色放到role中
 	user.Role = role
+	// 附加角色
+	user.Roles = s.getUserRoles()[user.Id]
+	// 当前生效的临时授权角色
+	user.GrantRoles = s.getUserGrantRoles(user.Id)
 	return user, nil
 }
+
+// 获取全部用户的附加角色, key为用户编号
+func (s *RedisService) getUserRoles() map[uint][]models.SysRole {
+	userRoles := make(map[uint][]models.SysRole)
+	relations := make([]models.RelationUserRole, 0)
+	_ = s.GetListFromCache(&relations, new(models.RelationUserRole).TableName())
+	if len(relations) == 0 {
+		return userRoles
+	}
+	roles := make([]models.SysRole, 0)
+	_ = s.GetListFromCache(&roles, new(models.SysRole).TableName())
+	roleMap := make(map[uint]models.SysRole)
+	for _, role := range roles {
+		roleMap[role.Id] = role
+	}
+	for _, relation := range relations {
+		if role, ok := roleMap[relation.SysRoleId]; ok {
+			userRoles[relation.SysUserId] = append(userRoles[relation.SysUserId], role)
+		}
+	}
+	return userRoles
+}
+
+// 获取用户当前生效的临时授权角色
+func (s *RedisService) getUserGrantRoles(userId uint) []models.SysRole {
+	roles := make([]models.SysRole, 0)
+	grants := make([]models.SysRoleGrant, 0)
+	_ = s.GetListFromCache(&grants, new(models.SysRoleGrant).TableName())
+	roleIds := make(map[uint]bool)
+	now := time.Now().Unix()
+	for _, grant := range grants {
+		if grant.UserId == userId && grant.Active(now) {
+			roleIds[grant.RoleId] = true
+		}
+	}
+	if len(roleIds) == 0 {
+		return roles
+	}
+	allRoles := make([]models.SysRole, 0)
+	_ = s.GetListFromCache(&allRoles, new(models.SysRole).TableName())
+	for _, role := range allRoles {
+		if roleIds[role.Id] {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// 获取用户权限标识, 包括角色(含继承的角色)菜单的权限标识, 以及有权访问的接口标识
+func (s *RedisService) GetUserPermissions(user models.SysUser) ([]string, error) {
+	if !global.Conf.System.UseRedis {
+		// 不使用redis
+		return s.mysql.GetUserPermissions(user)
+	}
+	roles, err := s.GetImplicitRoles(user.RoleIds())
+	if err != nil || len(roles) == 0 {
+		return make([]string, 0), err
+	}
+	ids := make([]uint, 0)
+	for _, role := range roles {
+		ids = append(ids, role.Id)
+	}
+	menus := make([]models.SysMenu, 0)
+	for _, menu := range s.getMenusByRoleIds(ids) {
+		// 只保留正常菜单
+		if menu.Status == nil || *menu.Status {
+			menus = append(menus, menu)
+		}
+	}
+	// 查询接口表所有缓存, 过滤路由已失效的接口
+	allApi := make([]models.SysApi, 0)
+	s.GetListFromCache(&allApi, new(models.SysApi).TableName())
+	apis := make([]models.SysApi, 0)
+	for _, api := range allApi {
+		if !api.Stale {
+			apis = append(apis, api)
+		}
+	}
+	e, err := s.mysql.Casbin()
+	if err != nil {
+		return make([]string, 0), err
+	}
+	return service.GenPermissions(e, user.RoleKeywords(), s.domain(), menus, apis), nil
+}
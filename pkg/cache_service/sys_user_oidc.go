@@ -0,0 +1,84 @@
+package cache_service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/oidc"
+	"gin-web/pkg/utils"
+	"time"
+)
+
+// SSO授权请求有效期
+const oidcStateTimeout = 10 * time.Minute
+
+// SSO授权请求, 回调时校验
+type oidcState struct {
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+}
+
+// 生成SSO登录地址
+func (s *RedisService) OidcAuthUrl() (string, error) {
+	conf := oidcConfig()
+	p, err := oidc.Discover(conf.Issuer)
+	if err != nil {
+		return "", err
+	}
+	state := oidc.GenState()
+	st := oidcState{
+		Nonce:        oidc.GenState(),
+		CodeVerifier: oidc.GenCodeVerifier(),
+	}
+	s.setTemp(oidcStateKey(state), utils.Struct2Json(st), oidcStateTimeout)
+	return p.AuthCodeUrl(conf, state, st.Nonce, oidc.CodeChallengeS256(st.CodeVerifier)), nil
+}
+
+// 完成SSO登录, 校验通过返回用户及其角色
+func (s *RedisService) OidcLogin(code string, state string) (*models.SysUser, error) {
+	key := oidcStateKey(state)
+	res := s.getTemp(key)
+	if res == "" {
+		return nil, errors.New("SSO登录已失效, 请重新登录")
+	}
+	// state只能使用一次
+	s.delTemp(key)
+	var st oidcState
+	utils.Json2Struct(res, &st)
+	conf := oidcConfig()
+	p, err := oidc.Discover(conf.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := p.Exchange(conf, code, st.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := p.VerifyIdToken(conf, idToken, st.Nonce, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.mysql.GetOidcUser(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SSO客户端配置
+func oidcConfig() oidc.Config {
+	conf := global.Conf.Oidc
+	return oidc.Config{
+		Issuer:       conf.Issuer,
+		ClientId:     conf.ClientId,
+		ClientSecret: conf.ClientSecret,
+		RedirectUrl:  conf.RedirectUrl,
+		Scopes:       conf.Scopes,
+	}
+}
+
+// SSO授权请求缓存键
+func oidcStateKey(state string) string {
+	return fmt.Sprintf("%s_oidc_state_%s", global.Conf.Mysql.Database, utils.Sha256(state))
+}
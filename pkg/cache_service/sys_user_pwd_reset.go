@@ -0,0 +1,28 @@
+package cache_service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/pkg/global"
+	"strings"
+	"time"
+)
+
+// 发送找回密码邮件, 限制同一用户名的发送频率(无论用户是否存在)
+func (s *RedisService) SendPwdResetMail(username string) error {
+	interval := global.Conf.PwdReset.Interval
+	if interval > 0 {
+		key := s.pwdResetMailKey(username)
+		if s.getTemp(key) != "" {
+			return errors.New("发送过于频繁, 请稍后再试")
+		}
+		s.setTemp(key, "1", time.Duration(interval)*time.Second)
+	}
+	return s.mysql.SendPwdResetMail(username)
+}
+
+// 找回密码邮件发送间隔缓存键
+func (s RedisService) pwdResetMailKey(username string) string {
+	tenantId, _ := s.mysql.TenantId()
+	return fmt.Sprintf("%s_pwd_reset_mail_tenant_%d_%s", global.Conf.Mysql.Database, tenantId, strings.ToLower(strings.TrimSpace(username)))
+}
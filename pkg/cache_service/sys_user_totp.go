@@ -0,0 +1,63 @@
+package cache_service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/response"
+	"gin-web/pkg/utils"
+	"time"
+)
+
+// 创建登录挑战(密码校验通过且已开启二次验证), 返回挑战令牌
+func (s *RedisService) CreateTotpChallenge(userId uint) string {
+	challenge := utils.GenRandomToken(32)
+	timeout := time.Duration(global.Conf.Totp.ChallengeTimeout) * time.Minute
+	s.setTemp(totpChallengeKey(challenge), fmt.Sprintf("%d", userId), timeout)
+	return challenge
+}
+
+// 完成登录挑战, 校验通过返回用户及其角色
+func (s *RedisService) TotpChallengeCheck(challenge string, code string, ip string) (*models.SysUser, error) {
+	if challenge == "" {
+		return nil, errors.New(response.TotpChallengeErrorMsg)
+	}
+	key := totpChallengeKey(challenge)
+	userId := utils.Str2Uint(s.getTemp(key))
+	if userId == 0 {
+		return nil, errors.New(response.TotpChallengeErrorMsg)
+	}
+	// 限制同一挑战的尝试次数, 超过后需重新输入密码
+	timeout := time.Duration(global.Conf.Totp.ChallengeTimeout) * time.Minute
+	attemptsKey := totpChallengeAttemptsKey(challenge)
+	attempts := s.incrTemp(attemptsKey, timeout)
+	if global.Conf.Totp.ChallengeMax > 0 && attempts > global.Conf.Totp.ChallengeMax {
+		s.delTemp(key)
+		s.delTemp(attemptsKey)
+		return nil, errors.New(response.TotpChallengeErrorMsg)
+	}
+	user, err := s.mysql.TotpCheck(userId, code)
+	if err != nil {
+		if err.Error() == response.LoginLockedMsg {
+			return nil, err
+		}
+		// 验证码错误同样计入登录失败次数
+		s.LoginFailed(user.Username, ip)
+		return nil, errors.New(response.TotpCheckErrorMsg)
+	}
+	// 挑战只能使用一次
+	s.delTemp(key)
+	s.delTemp(attemptsKey)
+	return &user, nil
+}
+
+// 登录挑战缓存键
+func totpChallengeKey(challenge string) string {
+	return fmt.Sprintf("%s_totp_challenge_%s", global.Conf.Mysql.Database, utils.Sha256(challenge))
+}
+
+// 登录挑战尝试次数缓存键
+func totpChallengeAttemptsKey(challenge string) string {
+	return fmt.Sprintf("%s_totp_challenge_attempts_%s", global.Conf.Mysql.Database, utils.Sha256(challenge))
+}
@@ -7,6 +7,7 @@ import (
 	"gin-web/pkg/request"
 	"gin-web/pkg/utils"
 	"strings"
+	"time"
 )
 
 // 获取所有工作流
@@ -144,11 +145,17 @@ func (s *RedisService) GetWorkflowLogs(flowId uint, targetId uint) ([]models.Sys
 	newLogs := make([]models.SysWorkflowLog, 0)
 	for _, workflowLog := range workflowLogList {
 		newLog := workflowLog
-		// 查找审批人/提交人
+		// 查找审批人/被代理的审批人/提交人
 		for _, user := range userList {
 			if workflowLog.ApprovalUserId == user.Id {
 				newLog.ApprovalUser = user
 			}
+			if workflowLog.OnBehalfOfUserId > 0 && workflowLog.OnBehalfOfUserId == user.Id {
+				newLog.OnBehalfOfUser = user
+			}
+			if workflowLog.AssigneeUserId > 0 && workflowLog.AssigneeUserId == user.Id {
+				newLog.AssigneeUser = user
+			}
 			if workflowLog.SubmitUserId == user.Id {
 				newLog.SubmitUser = user
 			}
@@ -242,6 +249,15 @@ func (s *RedisService) GetWorkflowApprovings(req *request.WorkflowApprovingListR
 				}
 			}
 		}
+		// 查找超时升级的角色用户
+		if log.EscalateRoleId > 0 {
+			log.EscalateRole.Id = log.EscalateRoleId
+			for _, user := range userList {
+				if user.RoleId == log.EscalateRoleId {
+					log.EscalateRole.Users = append(log.EscalateRole.Users, user)
+				}
+			}
+		}
 		// 查找工作流
 		if log.FlowId > 0 {
 			for _, flow := range workflowList {
@@ -254,12 +270,21 @@ func (s *RedisService) GetWorkflowApprovings(req *request.WorkflowApprovingListR
 		workflowLogList[i] = log
 	}
 
+	// 查询委托给当前审批人的审批
+	delegates := make([]models.SysWorkflowDelegate, 0)
+	jsonDelegates := s.GetListFromCache(nil, new(models.SysWorkflowDelegate).TableName())
+	delegateRes := s.JsonQuery().FromString(jsonDelegates).
+		Where("delegateUserId", "=", int(approval.Id)).
+		Get()
+	utils.Struct2StructByJson(delegateRes, &delegates)
+
+	now := time.Now().Unix()
 	for _, log := range workflowLogList {
 		// 获取当前待审批人
-		userIds := s.getApprovingUsers(log.FlowId, log.TargetId, log.CurrentLineId, log.CurrentLine)
+		userIds := s.getApprovingUsers(log)
 		log.ApprovingUserIds = userIds
-		// 包含当前审批人
-		if utils.ContainsUint(userIds, approval.Id) {
+		// 包含当前审批人(或委托人)
+		if _, ok := models.WorkflowActingUser(userIds, approval.Id, log.FlowId, delegates, now); ok {
 			list = append(list, log)
 		}
 	}
@@ -302,10 +327,20 @@ func (s *RedisService) getWorkflowUsersByLineId(lineId uint) []models.SysUser {
 }
 
 // 获取待审批人(当前流水线)
-func (s *RedisService) getApprovingUsers(flowId uint, targetId uint, currentLineId uint, currentLine models.SysWorkflowLine) []uint {
+func (s *RedisService) getApprovingUsers(log models.SysWorkflowLog) []uint {
 	userIds := make([]uint, 0)
-	allUserIds := s.getAllApprovalUsers(currentLine)
-	historyUserIds := s.getHistoryApprovalUsers(flowId, targetId, currentLineId)
+	if log.AssigneeUserId > 0 {
+		// 转交/加签时只有指定审批人可以审批
+		return append(userIds, log.AssigneeUserId)
+	}
+	allUserIds := s.getAllApprovalUsers(log.CurrentLine)
+	// 超时升级的角色
+	for _, user := range log.EscalateRole.Users {
+		if user.Id > 0 && !utils.ContainsUint(allUserIds, user.Id) {
+			allUserIds = append(allUserIds, user.Id)
+		}
+	}
+	historyUserIds := s.getHistoryApprovalUsers(log)
 	for _, allUserId := range allUserIds {
 		// 不在历史列表中
 		if !utils.ContainsUint(historyUserIds, allUserId) {
@@ -316,30 +351,43 @@ func (s *RedisService) getApprovingUsers(flowId uint, targetId uint, currentLine
 }
 
 // 获取历史审批人(最后一个流水线, 主要用于判断是否审批完成)
-func (s *RedisService) getHistoryApprovalUsers(flowId uint, targetId uint, currentLineId uint) []uint {
+func (s *RedisService) getHistoryApprovalUsers(log models.SysWorkflowLog) []uint {
 	historyUserIds := make([]uint, 0)
+	if log.PrevLogId == 0 {
+		return historyUserIds
+	}
 	// 查询已审核的日志
 	logs := make([]models.SysWorkflowLog, 0)
 	jsonWorkflowLogs := s.GetListFromCache(nil, new(models.SysWorkflowLog).TableName())
 	workflowLogRes := s.JsonQuery().FromString(jsonWorkflowLogs).
-		Where("flowId", "=", int(flowId)).
-		Where("targetId", "=", int(targetId)).
+		Where("flowId", "=", int(log.FlowId)).
+		Where("targetId", "=", int(log.TargetId)).
 		Where("status", ">", models.SysWorkflowLogStateSubmit). // 状态非提交
 		Get()
 	utils.Struct2StructByJson(workflowLogRes, &logs)
+	logMap := make(map[uint]models.SysWorkflowLog)
+	for _, item := range logs {
+		logMap[item.Id] = item
+	}
 
-	// 保留连续审核通过记录
-	l := len(logs)
-	for i := 0; i < l; i++ {
-		log := logs[i]
-		// 如果不是通过立即结束, 必须保证连续的通过 或 当前流水线不一致
-		if *log.Status != models.SysWorkflowLogStateApproval || log.CurrentLineId != currentLineId {
+	// 沿上一条日志查找同一流水线连续审核通过记录(并行分支的日志会交错, 不能按编号顺序查找)
+	prevLogId := log.PrevLogId
+	for prevLogId > 0 {
+		item, ok := logMap[prevLogId]
+		// 当前流水线不一致立即结束
+		if !ok || item.CurrentLineId != log.CurrentLineId {
+			break
+		}
+		// 必须保证连续的通过(升级/转交/加签不影响审批进度)
+		userId, next := item.HistoryApprovalUser()
+		if !next {
 			break
 		}
 		// 审批人为配置中的一人
-		if !utils.ContainsUint(historyUserIds, log.ApprovalUserId) {
-			historyUserIds = append(historyUserIds, log.ApprovalUserId)
+		if userId > 0 && !utils.ContainsUint(historyUserIds, userId) {
+			historyUserIds = append(historyUserIds, userId)
 		}
+		prevLogId = item.PrevLogId
 	}
 	return historyUserIds
 }
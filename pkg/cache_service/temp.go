@@ -0,0 +1,122 @@
+package cache_service
+
+import (
+	"gin-web/pkg/global"
+	"sync"
+	"time"
+)
+
+// 临时数据(登录失败次数/登录挑战等), 开启redis时保存在redis中
+// 未开启redis时保存在内存中(仅适用于单实例部署)
+var memoryTemp = struct {
+	sync.Mutex
+	m map[string]memoryTempItem
+}{
+	m: make(map[string]memoryTempItem),
+}
+
+type memoryTempItem struct {
+	value   string
+	count   int64
+	expires time.Time
+}
+
+// 写入临时数据
+func (s *RedisService) setTemp(key string, value string, expiration time.Duration) {
+	if !global.Conf.System.UseRedis {
+		memoryTemp.Lock()
+		defer memoryTemp.Unlock()
+		now := time.Now()
+		memoryTemp.m[key] = memoryTempItem{
+			value:   value,
+			expires: now.Add(expiration),
+		}
+		clearExpiredMemoryTemp(now)
+		return
+	}
+	err := s.redis.Set(key, value, expiration).Err()
+	if err != nil {
+		global.Log.Warn("[setTemp]", err)
+	}
+}
+
+// 读取临时数据, 不存在时返回空字符串
+func (s *RedisService) getTemp(key string) string {
+	if !global.Conf.System.UseRedis {
+		memoryTemp.Lock()
+		defer memoryTemp.Unlock()
+		item, ok := memoryTemp.m[key]
+		if !ok || item.expires.Before(time.Now()) {
+			return ""
+		}
+		return item.value
+	}
+	res, _ := s.redis.Get(key).Result()
+	return res
+}
+
+// 读取计数, 不存在时返回0
+func (s *RedisService) getTempCount(key string) int64 {
+	if !global.Conf.System.UseRedis {
+		memoryTemp.Lock()
+		defer memoryTemp.Unlock()
+		item, ok := memoryTemp.m[key]
+		if !ok || item.expires.Before(time.Now()) {
+			return 0
+		}
+		return item.count
+	}
+	count, err := s.redis.Get(key).Int64()
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// 计数加1, 每次累加重新计算过期时间
+func (s *RedisService) incrTemp(key string, expiration time.Duration) int64 {
+	if !global.Conf.System.UseRedis {
+		memoryTemp.Lock()
+		defer memoryTemp.Unlock()
+		now := time.Now()
+		item, ok := memoryTemp.m[key]
+		if !ok || item.expires.Before(now) {
+			item = memoryTempItem{}
+		}
+		item.count++
+		item.expires = now.Add(expiration)
+		memoryTemp.m[key] = item
+		clearExpiredMemoryTemp(now)
+		return item.count
+	}
+	// 累加与设置过期时间在同一事务中执行, 避免留下永不过期的计数
+	pipe := s.redis.TxPipeline()
+	incr := pipe.Incr(key)
+	pipe.Expire(key, expiration)
+	_, err := pipe.Exec()
+	if err != nil {
+		global.Log.Warn("[incrTemp]", err)
+		return 0
+	}
+	return incr.Val()
+}
+
+// 删除临时数据
+func (s *RedisService) delTemp(key string) {
+	if !global.Conf.System.UseRedis {
+		memoryTemp.Lock()
+		defer memoryTemp.Unlock()
+		delete(memoryTemp.m, key)
+		return
+	}
+	s.redis.Del(key)
+}
+
+// 清理内存中的过期数据, 避免内存持续增长(调用方需持有锁)
+func clearExpiredMemoryTemp(now time.Time) {
+	for k, v := range memoryTemp.m {
+		if v.expires.Before(now) {
+			delete(memoryTemp.m, k)
+		}
+	}
+}
@@ -0,0 +1,527 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 简单的条件表达式, 仅支持字面量/变量/运算符, 不能调用函数, 可安全执行用户配置的表达式
+// 例如: days > 3 && user.role == "leave"
+//
+// 字面量: 数字, 字符串("..."或'...'), true, false, null
+// 变量: 字母/数字/下划线组成, 使用.访问下级字段, 不存在的变量为null
+// 运算符(优先级从低到高): ||, &&, == != > >= < <= in, + -, * / %, ! -(负号)
+// 其中in表示包含: 数组包含元素或字符串包含子串, 例如 "admin" in user.roles
+
+const (
+	// 表达式最大长度
+	MaxLength = 1024
+	// 最大嵌套层数, 避免恶意表达式导致栈溢出
+	MaxDepth = 64
+)
+
+var ErrTooLong = fmt.Errorf("表达式长度不能超过%d", MaxLength)
+
+// 编译后的表达式
+type Expr struct {
+	source string
+	root   node
+}
+
+// 编译表达式
+func Compile(source string) (*Expr, error) {
+	if len(source) > MaxLength {
+		return nil, ErrTooLong
+	}
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("表达式第%d个字符附近存在多余内容", p.peek().pos+1)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// 原始表达式
+func (e *Expr) String() string {
+	return e.source
+}
+
+// 执行表达式, env为变量
+func (e *Expr) Eval(env map[string]interface{}) (interface{}, error) {
+	return e.root.eval(env)
+}
+
+// 执行表达式, 结果必须为布尔值
+func (e *Expr) EvalBool(env map[string]interface{}) (bool, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("表达式[%s]的结果不是布尔值", e.source)
+	}
+	return b, nil
+}
+
+// 编译并执行表达式, 结果必须为布尔值
+func EvalBool(source string, env map[string]interface{}) (bool, error) {
+	e, err := Compile(source)
+	if err != nil {
+		return false, err
+	}
+	return e.EvalBool(env)
+}
+
+// 词法分析
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// 运算符, 长的在前优先匹配
+var operators = []string{"||", "&&", "==", "!=", ">=", "<=", ">", "<", "+", "-", "*", "/", "%", "!"}
+
+func tokenize(source string) ([]token, error) {
+	tokens := make([]token, 0)
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			closed := false
+			for i < len(source) {
+				if source[i] == '\\' && i+1 < len(source) {
+					b.WriteByte(source[i+1])
+					i += 2
+					continue
+				}
+				if source[i] == c {
+					closed = true
+					i++
+					break
+				}
+				b.WriteByte(source[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("表达式第%d个字符开始的字符串未结束", start+1)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: source[start:i], value: b.String(), pos: start})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			f, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("表达式第%d个字符开始的数字[%s]不正确", start+1, source[start:i])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], value: f, pos: start})
+		case isIdentStart(c):
+			start := i
+			for i < len(source) && (isIdentStart(source[i]) || source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			text := source[start:i]
+			if strings.HasPrefix(text, ".") || strings.HasSuffix(text, ".") || strings.Contains(text, "..") {
+				return nil, fmt.Errorf("表达式第%d个字符开始的变量[%s]不正确", start+1, text)
+			}
+			if text == "in" {
+				tokens = append(tokens, token{kind: tokenOp, text: text, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdent, text: text, pos: start})
+			}
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("表达式第%d个字符[%c]不支持", i+1, c)
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(source)})
+	return tokens, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// 语法分析(递归下降)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// 当前是否为指定运算符之一
+func (p *parser) matchOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr(depth int) (node, error) {
+	return p.parseBinary(depth, []string{"||"}, p.parseAnd)
+}
+
+func (p *parser) parseAnd(depth int) (node, error) {
+	return p.parseBinary(depth, []string{"&&"}, p.parseCompare)
+}
+
+func (p *parser) parseCompare(depth int) (node, error) {
+	left, err := p.parseAdd(depth)
+	if err != nil {
+		return nil, err
+	}
+	// 比较运算不能连续使用(如a < b < c)
+	if op, ok := p.matchOp("==", "!=", ">=", "<=", ">", "<", "in"); ok {
+		right, err := p.parseAdd(depth)
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdd(depth int) (node, error) {
+	return p.parseBinary(depth, []string{"+", "-"}, p.parseMul)
+}
+
+func (p *parser) parseMul(depth int) (node, error) {
+	return p.parseBinary(depth, []string{"*", "/", "%"}, p.parseUnary)
+}
+
+func (p *parser) parseBinary(depth int, ops []string, operand func(int) (node, error)) (node, error) {
+	left, err := operand(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.matchOp(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary(depth int) (node, error) {
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("表达式嵌套不能超过%d层", MaxDepth)
+	}
+	if op, ok := p.matchOp("!", "-"); ok {
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		return &identNode{path: strings.Split(t.text, ".")}, nil
+	case tokenLParen:
+		n, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRParen {
+			return nil, fmt.Errorf("表达式第%d个字符附近缺少右括号", t.pos+1)
+		}
+		return n, nil
+	case tokenEOF:
+		return nil, errors.New("表达式不完整")
+	}
+	return nil, fmt.Errorf("表达式第%d个字符附近的[%s]不正确", t.pos+1, t.text)
+}
+
+// 语法树
+
+type node interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	path []string
+}
+
+func (n *identNode) eval(env map[string]interface{}) (interface{}, error) {
+	var v interface{} = env
+	for _, key := range n.path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		v = m[key]
+	}
+	return normalize(v), nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("运算符[!]只能用于布尔值, 实际为%v", v)
+		}
+		return !b, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("运算符[-]只能用于数字, 实际为%v", v)
+	}
+	return -f, nil
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	// 逻辑运算短路
+	if n.op == "&&" || n.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("运算符[%s]只能用于布尔值, 实际为%v", n.op, left)
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("运算符[%s]只能用于布尔值, 实际为%v", n.op, right)
+		}
+		return r, nil
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	case "+":
+		// 字符串拼接
+		if ls, ok := left.(string); ok {
+			if rs, ok := right.(string); ok {
+				return ls + rs, nil
+			}
+		}
+	case ">", ">=", "<", "<=":
+		if ls, ok := left.(string); ok {
+			if rs, ok := right.(string); ok {
+				return compare(n.op, strings.Compare(ls, rs)), nil
+			}
+		}
+	}
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("运算符[%s]不能用于%v和%v", n.op, left, right)
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, errors.New("除数不能为0")
+		}
+		if n.op == "/" {
+			return l / r, nil
+		}
+		// 使用浮点取模, 除数为小数(如0.5)时int64(r)为0会panic
+		return math.Mod(l, r), nil
+	}
+	c := 0
+	if l < r {
+		c = -1
+	} else if l > r {
+		c = 1
+	}
+	return compare(n.op, c), nil
+}
+
+func compare(op string, c int) bool {
+	switch op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	}
+	return c <= 0
+}
+
+func equal(left interface{}, right interface{}) bool {
+	return reflect.DeepEqual(left, right)
+}
+
+// 数组包含元素或字符串包含子串
+func contains(container interface{}, item interface{}) (bool, error) {
+	switch c := container.(type) {
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("运算符[in]左侧必须为字符串, 实际为%v", item)
+		}
+		return strings.Contains(c, s), nil
+	case []interface{}:
+		for _, v := range c {
+			if equal(normalize(v), item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("运算符[in]右侧必须为数组或字符串, 实际为%v", container)
+}
+
+// 统一变量类型: 数字转为float64, 数组转为[]interface{}, 便于比较
+func normalize(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			list[i] = normalize(rv.Index(i).Interface())
+		}
+		return list
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	}
+	return v
+}
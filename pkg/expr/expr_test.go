@@ -0,0 +1,75 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvalBool(t *testing.T) {
+	env := map[string]interface{}{
+		"days":   float64(5),
+		"reason": "家中有事",
+		"status": uint(1),
+		"user": map[string]interface{}{
+			"role":  "leave",
+			"roles": []string{"leave", "guest"},
+		},
+	}
+	tests := []struct {
+		name    string
+		source  string
+		want    bool
+		wantErr bool
+	}{
+		{"case1", `days > 3 && user.role == "leave"`, true, false},
+		{"case2", `days <= 3 || user.role != 'leave'`, false, false},
+		{"case3", `!(days > 3) || status == 1`, true, false},
+		{"case4", `(days - 1) * 2 >= 8 && days % 2 == 1`, true, false},
+		{"case5", `"guest" in user.roles && "有事" in reason`, true, false},
+		{"case6", `"admin" in user.roles`, false, false},
+		{"case7", `missing == null && user.dept.name == null`, true, false},
+		{"case8", `days > 3 && missing > 1`, false, true},
+		{"case9", `days + 1`, false, true},
+		{"case10", `days > `, false, true},
+		{"case11", `days > 3)`, false, true},
+		{"case12", `exec("rm")`, false, true},
+		{"case13", `days / 0 > 1`, false, true},
+		{"case14", `days > 3 || missing > 1`, true, false},
+		{"case15", strings.Repeat("(", MaxDepth+2) + "true" + strings.Repeat(")", MaxDepth+2), false, true},
+		{"case16", `days % 0.5 == 0 && days % 2 == 1`, true, false},
+		{"case17", `days % 0 == 0`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EvalBool(tt.source, env)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("EvalBool() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("EvalBool() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr bool
+	}{
+		{"case1", `a.b.c == 'x' && (d < 1 || !e)`, false},
+		{"case2", `a..b == 1`, true},
+		{"case3", `"abc`, true},
+		{"case4", `a = 1`, true},
+		{"case5", strings.Repeat("a", MaxLength+1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile(tt.source); (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
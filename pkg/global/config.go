@@ -5,13 +5,23 @@ import "go.uber.org/zap/zapcore"
 // 系统配置, 配置字段可参见yml注释
 // viper内置了mapstructure, yml文件用"-"区分单词, 转为驼峰方便
 type Configuration struct {
-	System    SystemConfiguration    `mapstructure:"system" json:"system"`
-	Logs      LogsConfiguration      `mapstructure:"logs" json:"logs"`
-	Mysql     MysqlConfiguration     `mapstructure:"mysql" json:"mysql"`
-	Redis     RedisConfiguration     `mapstructure:"redis" json:"redis"`
-	Casbin    CasbinConfiguration    `mapstructure:"casbin" json:"casbin"`
-	Jwt       JwtConfiguration       `mapstructure:"jwt" json:"jwt"`
-	RateLimit RateLimitConfiguration `mapstructure:"rate-limit" json:"rateLimit"`
+	System      SystemConfiguration      `mapstructure:"system" json:"system"`
+	Logs        LogsConfiguration        `mapstructure:"logs" json:"logs"`
+	Mysql       MysqlConfiguration       `mapstructure:"mysql" json:"mysql"`
+	Redis       RedisConfiguration       `mapstructure:"redis" json:"redis"`
+	Casbin      CasbinConfiguration      `mapstructure:"casbin" json:"casbin"`
+	Jwt         JwtConfiguration         `mapstructure:"jwt" json:"jwt"`
+	RateLimit   RateLimitConfiguration   `mapstructure:"rate-limit" json:"rateLimit"`
+	LoginLimit  LoginLimitConfiguration  `mapstructure:"login-limit" json:"loginLimit"`
+	Totp        TotpConfiguration        `mapstructure:"totp" json:"totp"`
+	Oidc        OidcConfiguration        `mapstructure:"oidc" json:"oidc"`
+	Ldap        LdapConfiguration        `mapstructure:"ldap" json:"ldap"`
+	PwdPolicy   PwdPolicyConfiguration   `mapstructure:"pwd-policy" json:"pwdPolicy"`
+	PwdReset    PwdResetConfiguration    `mapstructure:"pwd-reset" json:"pwdReset"`
+	Mail        MailConfiguration        `mapstructure:"mail" json:"mail"`
+	ApiSync     ApiSyncConfiguration     `mapstructure:"api-sync" json:"apiSync"`
+	RoleGrant   RoleGrantConfiguration   `mapstructure:"role-grant" json:"roleGrant"`
+	WorkflowSla WorkflowSlaConfiguration `mapstructure:"workflow-sla" json:"workflowSla"`
 }
 
 type SystemConfiguration struct {
@@ -55,12 +65,113 @@ type CasbinConfiguration struct {
 }
 
 type JwtConfiguration struct {
-	Realm      string `mapstructure:"realm" json:"realm"`
-	Key        string `mapstructure:"key" json:"key"`
-	Timeout    int    `mapstructure:"timeout" json:"timeout"`
-	MaxRefresh int    `mapstructure:"max-refresh" json:"maxRefresh"`
+	Realm          string `mapstructure:"realm" json:"realm"`
+	Key            string `mapstructure:"key" json:"key"`
+	Timeout        int    `mapstructure:"timeout" json:"timeout"`
+	MaxRefresh     int    `mapstructure:"max-refresh" json:"maxRefresh"`
+	RefreshTimeout int    `mapstructure:"refresh-timeout" json:"refreshTimeout"`
 }
 
 type RateLimitConfiguration struct {
 	Max int64 `mapstructure:"max" json:"max"`
 }
+
+type LoginLimitConfiguration struct {
+	MaxFailures   int64 `mapstructure:"max-failures" json:"maxFailures"`
+	MaxIpFailures int64 `mapstructure:"max-ip-failures" json:"maxIpFailures"`
+	FailureWindow int   `mapstructure:"failure-window" json:"failureWindow"`
+	LockMinutes   int   `mapstructure:"lock-minutes" json:"lockMinutes"`
+	DelayStep     int   `mapstructure:"delay-step" json:"delayStep"`
+	MaxDelay      int   `mapstructure:"max-delay" json:"maxDelay"`
+}
+
+type TotpConfiguration struct {
+	Issuer           string `mapstructure:"issuer" json:"issuer"`
+	ChallengeTimeout int    `mapstructure:"challenge-timeout" json:"challengeTimeout"`
+	ChallengeMax     int64  `mapstructure:"challenge-max" json:"challengeMax"`
+	RecoveryCodes    int    `mapstructure:"recovery-codes" json:"recoveryCodes"`
+}
+
+type OidcConfiguration struct {
+	Enable        bool              `mapstructure:"enable" json:"enable"`
+	Issuer        string            `mapstructure:"issuer" json:"issuer"`
+	ClientId      string            `mapstructure:"client-id" json:"clientId"`
+	ClientSecret  string            `mapstructure:"client-secret" json:"clientSecret"`
+	RedirectUrl   string            `mapstructure:"redirect-url" json:"redirectUrl"`
+	Scopes        []string          `mapstructure:"scopes" json:"scopes"`
+	UsernameClaim string            `mapstructure:"username-claim" json:"usernameClaim"`
+	RoleClaim     string            `mapstructure:"role-claim" json:"roleClaim"`
+	RoleMappings  []OidcRoleMapping `mapstructure:"role-mappings" json:"roleMappings"`
+	DefaultRoleId uint              `mapstructure:"default-role-id" json:"defaultRoleId"`
+}
+
+type OidcRoleMapping struct {
+	Claim string `mapstructure:"claim" json:"claim"`
+	Role  string `mapstructure:"role" json:"role"`
+}
+
+type LdapConfiguration struct {
+	Enable        bool               `mapstructure:"enable" json:"enable"`
+	AuthOrder     []string           `mapstructure:"auth-order" json:"authOrder"`
+	Url           string             `mapstructure:"url" json:"url"`
+	BindDn        string             `mapstructure:"bind-dn" json:"bindDn"`
+	BindPassword  string             `mapstructure:"bind-password" json:"bindPassword"`
+	BaseDn        string             `mapstructure:"base-dn" json:"baseDn"`
+	UserFilter    string             `mapstructure:"user-filter" json:"userFilter"`
+	GroupBaseDn   string             `mapstructure:"group-base-dn" json:"groupBaseDn"`
+	GroupFilter   string             `mapstructure:"group-filter" json:"groupFilter"`
+	GroupAttr     string             `mapstructure:"group-attr" json:"groupAttr"`
+	NicknameAttr  string             `mapstructure:"nickname-attr" json:"nicknameAttr"`
+	MobileAttr    string             `mapstructure:"mobile-attr" json:"mobileAttr"`
+	Timeout       int                `mapstructure:"timeout" json:"timeout"`
+	GroupMappings []LdapGroupMapping `mapstructure:"group-mappings" json:"groupMappings"`
+	DefaultRoleId uint               `mapstructure:"default-role-id" json:"defaultRoleId"`
+}
+
+type LdapGroupMapping struct {
+	Group string `mapstructure:"group" json:"group"`
+	Role  string `mapstructure:"role" json:"role"`
+}
+
+type PwdPolicyConfiguration struct {
+	MinLength      int      `mapstructure:"min-length" json:"minLength"`
+	MaxLength      int      `mapstructure:"max-length" json:"maxLength"`
+	RequireUpper   bool     `mapstructure:"require-upper" json:"requireUpper"`
+	RequireLower   bool     `mapstructure:"require-lower" json:"requireLower"`
+	RequireDigit   bool     `mapstructure:"require-digit" json:"requireDigit"`
+	RequireSpecial bool     `mapstructure:"require-special" json:"requireSpecial"`
+	NotUsername    bool     `mapstructure:"not-username" json:"notUsername"`
+	Banned         []string `mapstructure:"banned" json:"banned"`
+	History        int      `mapstructure:"history" json:"history"`
+	ExpireDays     int      `mapstructure:"expire-days" json:"expireDays"`
+}
+
+type PwdResetConfiguration struct {
+	Enable   bool   `mapstructure:"enable" json:"enable"`
+	Url      string `mapstructure:"url" json:"url"`
+	Timeout  int    `mapstructure:"timeout" json:"timeout"`
+	Interval int    `mapstructure:"interval" json:"interval"`
+}
+
+type MailConfiguration struct {
+	Host     string `mapstructure:"host" json:"host"`
+	Port     int    `mapstructure:"port" json:"port"`
+	Username string `mapstructure:"username" json:"username"`
+	Password string `mapstructure:"password" json:"password"`
+	From     string `mapstructure:"from" json:"from"`
+	Timeout  int    `mapstructure:"timeout" json:"timeout"`
+}
+
+type ApiSyncConfiguration struct {
+	Enable    bool   `mapstructure:"enable" json:"enable"`
+	GrantRole string `mapstructure:"grant-role" json:"grantRole"`
+}
+
+type RoleGrantConfiguration struct {
+	MaxHours       int `mapstructure:"max-hours" json:"maxHours"`
+	ExpireInterval int `mapstructure:"expire-interval" json:"expireInterval"`
+}
+
+type WorkflowSlaConfiguration struct {
+	ScanInterval int `mapstructure:"scan-interval" json:"scanInterval"`
+}
@@ -2,6 +2,8 @@ package global
 
 import (
 	"errors"
+	"fmt"
+	"gin-web/pkg/mail"
 	"github.com/gin-gonic/gin"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-redis/redis"
@@ -23,6 +25,8 @@ var (
 	Mysql *gorm.DB
 	// redis实例
 	Redis *redis.Client
+	// 邮件发送
+	Mailer mail.Mailer
 	// validation.v9校验器
 	Validate *validator.Validate
 	// validation.v9相关翻译器
@@ -61,3 +65,32 @@ func GetTx(c *gin.Context) *gorm.DB {
 	}
 	return tx
 }
+
+// 租户请求头, 未登录的接口(如登录/刷新令牌)通过该请求头指定租户, 默认为0(默认租户)
+const TenantHeader = "X-Tenant-Id"
+
+// 租户编号在gorm中的设置键, 设置后自动按租户过滤/写入数据(参见models.RegisterTenantCallbacks)
+const TenantIdKey = "gin-web:tenant_id"
+
+// 按租户隔离的缓存表(值表示是否为各租户共享的表), 参见CacheKey
+var TenantTables = make(map[string]bool)
+
+// 设置当前请求的租户编号
+func SetTenantId(c *gin.Context, tenantId uint) {
+	c.Set("tenantId", tenantId)
+}
+
+// 获取当前请求的租户编号, 未设置时为默认租户
+func GetTenantId(c *gin.Context) uint {
+	tenantId, _ := c.Get("tenantId")
+	id, _ := tenantId.(uint)
+	return id
+}
+
+// 表数据的缓存键, 由数据库名与表名组成, 非默认租户的数据添加租户后缀
+func CacheKey(tableName string, tenantId uint) string {
+	if tenantId == 0 {
+		return fmt.Sprintf("%s_%s", Conf.Mysql.Database, tableName)
+	}
+	return fmt.Sprintf("%s_%s_tenant_%d", Conf.Mysql.Database, tableName, tenantId)
+}
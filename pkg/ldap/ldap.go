@@ -0,0 +1,127 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	goldap "github.com/go-ldap/ldap/v3"
+	"net"
+	"strings"
+	"time"
+)
+
+// LDAP认证: 使用服务账号查找用户DN, 再以用户DN+密码绑定校验密码, 最后查询用户所属组
+
+// 用户名或密码错误
+var ErrInvalidCredentials = errors.New("LDAP用户名或密码错误")
+
+// 连接配置
+type Config struct {
+	Url          string        // 服务地址, 如ldap://127.0.0.1:389, ldaps://127.0.0.1:636
+	BindDn       string        // 服务账号DN, 为空时匿名查询
+	BindPassword string        // 服务账号密码
+	BaseDn       string        // 用户查询根DN
+	UserFilter   string        // 用户查询条件, %s替换为(转义后的)用户名, 如(uid=%s)
+	GroupBaseDn  string        // 组查询根DN, 为空时不查询组
+	GroupFilter  string        // 组查询条件, %s替换为(转义后的)用户DN, 如(member=%s)
+	GroupAttr    string        // 组名称属性, 如cn
+	NicknameAttr string        // 昵称属性, 如displayName
+	MobileAttr   string        // 手机属性, 如mobile
+	Timeout      time.Duration // 连接/请求超时时间
+}
+
+// LDAP用户
+type Entry struct {
+	Dn       string
+	Nickname string
+	Mobile   string
+	Groups   []string
+}
+
+// 校验用户名密码, 通过返回用户信息
+func Authenticate(conf Config, username string, password string) (*Entry, error) {
+	// 空密码在多数LDAP服务中会被当作匿名绑定而成功, 必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	conn, err := goldap.DialURL(conf.Url, goldap.DialWithDialer(&net.Dialer{Timeout: conf.Timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("连接LDAP服务失败: %v", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(conf.Timeout)
+
+	// 使用服务账号查找用户
+	err = serviceBind(conn, conf)
+	if err != nil {
+		return nil, err
+	}
+	res, err := conn.Search(goldap.NewSearchRequest(
+		conf.BaseDn,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(conf.UserFilter, goldap.EscapeFilter(username)),
+		[]string{"dn", conf.NicknameAttr, conf.MobileAttr},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("查询LDAP用户失败: %v", err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	user := res.Entries[0]
+
+	// 以用户身份绑定, 校验密码
+	err = conn.Bind(user.DN, password)
+	if err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("LDAP绑定失败: %v", err)
+	}
+	entry := Entry{
+		Dn:       user.DN,
+		Nickname: user.GetAttributeValue(conf.NicknameAttr),
+		Mobile:   user.GetAttributeValue(conf.MobileAttr),
+		Groups:   make([]string, 0),
+	}
+	if conf.GroupBaseDn == "" {
+		return &entry, nil
+	}
+
+	// 切回服务账号查询用户所属组(普通用户可能没有查询权限)
+	err = serviceBind(conn, conf)
+	if err != nil {
+		return nil, err
+	}
+	res, err = conn.Search(goldap.NewSearchRequest(
+		conf.GroupBaseDn,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(conf.GroupFilter, goldap.EscapeFilter(user.DN)),
+		[]string{conf.GroupAttr},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("查询LDAP用户组失败: %v", err)
+	}
+	for _, group := range res.Entries {
+		name := group.GetAttributeValue(conf.GroupAttr)
+		if name != "" {
+			entry.Groups = append(entry.Groups, name)
+		}
+	}
+	return &entry, nil
+}
+
+// 服务账号绑定, 未配置时使用匿名查询
+func serviceBind(conn *goldap.Conn, conf Config) error {
+	var err error
+	if strings.TrimSpace(conf.BindDn) == "" {
+		err = conn.UnauthenticatedBind("")
+	} else {
+		err = conn.Bind(conf.BindDn, conf.BindPassword)
+	}
+	if err != nil {
+		return fmt.Errorf("LDAP服务账号绑定失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package ldap
+
+import (
+	"gin-web/pkg/ldap/ldaptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAuthenticate(t *testing.T) {
+	server := ldaptest.NewServer([]ldaptest.Entry{
+		{
+			Dn:       "cn=admin,dc=example,dc=com",
+			Password: "admin-secret",
+		},
+		{
+			Dn:       "uid=zhangsan,ou=people,dc=example,dc=com",
+			Password: "123456",
+			Attributes: map[string][]string{
+				"uid":         {"zhangsan"},
+				"displayName": {"张三"},
+				"mobile":      {"13800000000"},
+			},
+		},
+		{
+			Dn:       "uid=lisi,ou=people,dc=example,dc=com",
+			Password: "654321",
+			Attributes: map[string][]string{
+				"uid": {"lisi"},
+			},
+		},
+		{
+			Dn: "cn=developers,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"cn":     {"developers"},
+				"member": {"uid=zhangsan,ou=people,dc=example,dc=com", "uid=lisi,ou=people,dc=example,dc=com"},
+			},
+		},
+		{
+			Dn: "cn=admins,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"cn":     {"admins"},
+				"member": {"uid=zhangsan,ou=people,dc=example,dc=com"},
+			},
+		},
+	})
+	defer server.Close()
+	conf := Config{
+		Url:          server.URL,
+		BindDn:       "cn=admin,dc=example,dc=com",
+		BindPassword: "admin-secret",
+		BaseDn:       "ou=people,dc=example,dc=com",
+		UserFilter:   "(&(objectClass=*)(uid=%s))",
+		GroupBaseDn:  "ou=groups,dc=example,dc=com",
+		GroupFilter:  "(member=%s)",
+		GroupAttr:    "cn",
+		NicknameAttr: "displayName",
+		MobileAttr:   "mobile",
+		Timeout:      5 * time.Second,
+	}
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     *Entry
+		wantErr  bool
+	}{
+		{"case1", "zhangsan", "123456", &Entry{
+			Dn:       "uid=zhangsan,ou=people,dc=example,dc=com",
+			Nickname: "张三",
+			Mobile:   "13800000000",
+			Groups:   []string{"developers", "admins"},
+		}, false},
+		{"case2", "lisi", "654321", &Entry{
+			Dn:     "uid=lisi,ou=people,dc=example,dc=com",
+			Groups: []string{"developers"},
+		}, false},
+		{"case3", "zhangsan", "wrong", nil, true},
+		{"case4", "zhangsan", "", nil, true},
+		{"case5", "wangwu", "123456", nil, true},
+		// 用户名中的特殊字符需要转义, 不能改变查询条件
+		{"case6", "*", "123456", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Authenticate(conf, tt.username, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authenticate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package ldaptest
+
+import (
+	"fmt"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+	"net"
+	"strings"
+	"sync"
+)
+
+// 进程内LDAP服务, 用于测试及本地联调LDAP登录
+// 只实现了simple bind/search/unbind, 查询条件支持and/or/not/equality/present
+type Server struct {
+	URL      string
+	Entries  []Entry
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// 目录条目, Password不为空时可使用Dn+Password绑定
+type Entry struct {
+	Dn         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// 启动LDAP服务, 监听本地随机端口
+func NewServer(entries []Entry) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("[ldaptest]监听端口失败: %v", err))
+	}
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		Entries:  entries,
+		listener: listener,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// 关闭服务
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageId, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			code := s.bind(op)
+			write(conn, messageId, result(goldap.ApplicationBindResponse, code))
+		case goldap.ApplicationSearchRequest:
+			for _, entry := range s.search(op) {
+				write(conn, messageId, entry)
+			}
+			write(conn, messageId, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess))
+		case goldap.ApplicationUnbindRequest:
+			return
+		default:
+			write(conn, messageId, result(goldap.ApplicationExtendedResponse, goldap.LDAPResultUnwillingToPerform))
+		}
+	}
+}
+
+// simple bind: [version, name, [0]password]
+func (s *Server) bind(op *ber.Packet) uint16 {
+	if len(op.Children) < 3 {
+		return goldap.LDAPResultProtocolError
+	}
+	dn := op.Children[1].Data.String()
+	password := op.Children[2].Data.String()
+	// 匿名绑定
+	if dn == "" && password == "" {
+		return goldap.LDAPResultSuccess
+	}
+	for _, entry := range s.Entries {
+		if strings.EqualFold(entry.Dn, dn) && entry.Password != "" && entry.Password == password {
+			return goldap.LDAPResultSuccess
+		}
+	}
+	return goldap.LDAPResultInvalidCredentials
+}
+
+// search: [baseObject, scope, derefAliases, sizeLimit, timeLimit, typesOnly, filter, attributes]
+func (s *Server) search(op *ber.Packet) []*ber.Packet {
+	res := make([]*ber.Packet, 0)
+	if len(op.Children) < 8 {
+		return res
+	}
+	base := strings.ToLower(op.Children[0].Data.String())
+	scope, _ := op.Children[1].Value.(int64)
+	filter := op.Children[6]
+	attrs := make([]string, 0)
+	for _, attr := range op.Children[7].Children {
+		attrs = append(attrs, attr.Data.String())
+	}
+	for _, entry := range s.Entries {
+		dn := strings.ToLower(entry.Dn)
+		switch scope {
+		case goldap.ScopeBaseObject:
+			if dn != base {
+				continue
+			}
+		case goldap.ScopeSingleLevel:
+			if !strings.HasSuffix(dn, ","+base) || strings.Contains(strings.TrimSuffix(dn, ","+base), ",") {
+				continue
+			}
+		default:
+			if dn != base && !strings.HasSuffix(dn, ","+base) {
+				continue
+			}
+		}
+		if match(filter, entry) {
+			res = append(res, searchEntry(entry, attrs))
+		}
+	}
+	return res
+}
+
+// 判断条目是否满足查询条件
+func match(filter *ber.Packet, entry Entry) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !match(child, entry) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, child := range filter.Children {
+			if match(child, entry) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return len(filter.Children) == 1 && !match(filter.Children[0], entry)
+	case goldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		value := filter.Children[1].Data.String()
+		for _, v := range values(entry, filter.Children[0].Data.String()) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterPresent:
+		return len(values(entry, filter.Data.String())) > 0
+	}
+	return false
+}
+
+// 获取属性值, 属性名不区分大小写
+func values(entry Entry, name string) []string {
+	if strings.EqualFold(name, "objectClass") && len(entry.Attributes["objectClass"]) == 0 {
+		// 未指定objectClass时, 保证(objectClass=*)能够匹配
+		return []string{"top"}
+	}
+	for k, v := range entry.Attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+// 构造查询结果条目, attrs为空时返回全部属性
+func searchEntry(entry Entry, attrs []string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.Dn, "DN"))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for k, v := range entry.Attributes {
+		if len(attrs) > 0 && !containsFold(attrs, k) {
+			continue
+		}
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, k, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, item := range v {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, item, "Value"))
+		}
+		attr.AppendChild(set)
+		list.AppendChild(attr)
+	}
+	p.AppendChild(list)
+	return p
+}
+
+// 构造结果: [resultCode, matchedDN, diagnosticMessage]
+func result(tag ber.Tag, code uint16) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return p
+}
+
+// 发送响应
+func write(conn net.Conn, messageId int64, op *ber.Packet) {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageId, "Message ID"))
+	envelope.AppendChild(op)
+	_, _ = conn.Write(envelope.Bytes())
+}
+
+func containsFold(arr []string, item string) bool {
+	for _, v := range arr {
+		if strings.EqualFold(v, item) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// 邮件发送, 业务代码只依赖Mailer接口, 可替换为SMTP/日志/第三方服务等实现
+
+// 邮件内容(纯文本)
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// 邮件发送接口
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// 使用函数实现Mailer接口
+type MailerFunc func(msg Message) error
+
+func (f MailerFunc) Send(msg Message) error {
+	return f(msg)
+}
+
+// SMTP配置
+type SmtpConfig struct {
+	Host     string
+	Port     int
+	Username string // 为空时不认证
+	Password string
+	From     string // 发件人地址
+	Timeout  time.Duration
+}
+
+// SMTP发送, 服务端支持STARTTLS时自动加密
+type SmtpMailer struct {
+	conf SmtpConfig
+}
+
+func NewSmtpMailer(conf SmtpConfig) *SmtpMailer {
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+	return &SmtpMailer{
+		conf: conf,
+	}
+}
+
+// 发送邮件
+func (m *SmtpMailer) Send(msg Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("收件人不能为空")
+	}
+	data, err := m.build(msg)
+	if err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(m.conf.Host, fmt.Sprintf("%d", m.conf.Port))
+	conn, err := net.DialTimeout("tcp", addr, m.conf.Timeout)
+	if err != nil {
+		return err
+	}
+	// 整个会话的超时时间
+	_ = conn.SetDeadline(time.Now().Add(m.conf.Timeout))
+	client, err := smtp.NewClient(conn, m.conf.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{
+			ServerName: m.conf.Host,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if m.conf.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", m.conf.Username, m.conf.Password, m.conf.Host))
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(m.conf.From)
+	if err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// 构造邮件报文, 正文使用base64编码
+func (m *SmtpMailer) build(msg Message) ([]byte, error) {
+	// 地址/主题中不能包含换行, 避免邮件头注入
+	for _, v := range append([]string{m.conf.From, msg.Subject}, msg.To...) {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, errors.New("邮件头不能包含换行符")
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString("From: " + m.conf.From + "\r\n")
+	buf.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	// 每行最多76个字符
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package mail
+
+import (
+	"encoding/base64"
+	"gin-web/pkg/mail/mailtest"
+	"io/ioutil"
+	"mime"
+	netmail "net/mail"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSmtpMailer_Send(t *testing.T) {
+	server := mailtest.NewServer()
+	defer server.Close()
+	tests := []struct {
+		name     string
+		username string
+		msg      Message
+		wantErr  bool
+	}{
+		{"case1", "", Message{
+			To:      []string{"zhangsan@example.com"},
+			Subject: "找回密码",
+			Body:    "请点击以下链接重置密码:\nhttp://127.0.0.1/reset?token=abc",
+		}, false},
+		{"case2", "noreply@example.com", Message{
+			To:      []string{"zhangsan@example.com", "lisi@example.com"},
+			Subject: "hello",
+			// 以.开头的行需要点填充
+			Body: strings.Repeat("long body ", 20) + "\n.\n..end",
+		}, false},
+		{"case3", "", Message{
+			Subject: "no recipient",
+		}, true},
+		// 邮件头注入
+		{"case4", "", Message{
+			To:      []string{"zhangsan@example.com"},
+			Subject: "hello\r\nBcc: lisi@example.com",
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(server.Messages())
+			m := NewSmtpMailer(SmtpConfig{
+				Host:     server.Host,
+				Port:     server.Port,
+				Username: tt.username,
+				Password: "secret",
+				From:     "noreply@example.com",
+				Timeout:  5 * time.Second,
+			})
+			err := m.Send(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			messages := server.Messages()
+			if tt.wantErr {
+				if len(messages) != before {
+					t.Errorf("Send() sent %d messages, want 0", len(messages)-before)
+				}
+				return
+			}
+			if len(messages) != before+1 {
+				t.Errorf("Send() sent %d messages, want 1", len(messages)-before)
+				return
+			}
+			got := messages[len(messages)-1]
+			if got.Username != tt.username || got.From != "noreply@example.com" || !reflect.DeepEqual(got.To, tt.msg.To) {
+				t.Errorf("Send() envelope = %v", got)
+			}
+			parsed, err := netmail.ReadMessage(strings.NewReader(got.Data))
+			if err != nil {
+				t.Errorf("ReadMessage() error = %v", err)
+				return
+			}
+			subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+			if subject != tt.msg.Subject {
+				t.Errorf("Send() subject = %v, want %v", subject, tt.msg.Subject)
+			}
+			raw, _ := ioutil.ReadAll(parsed.Body)
+			body, err := base64.StdEncoding.DecodeString(strings.Replace(string(raw), "\r\n", "", -1))
+			if err != nil || string(body) != tt.msg.Body {
+				t.Errorf("Send() body = %v, want %v", string(body), tt.msg.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package mailtest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// 进程内SMTP服务, 用于测试及本地联调邮件发送
+// 只接收并保存邮件, 不会投递; 支持EHLO/HELO/AUTH PLAIN/MAIL/RCPT/DATA/RSET/NOOP/QUIT
+type Server struct {
+	Host     string
+	Port     int
+	listener net.Listener
+	wg       sync.WaitGroup
+	lock     sync.Mutex
+	messages []Message
+}
+
+// 收到的邮件
+type Message struct {
+	Username string // AUTH PLAIN认证的用户名, 未认证为空
+	From     string
+	To       []string
+	Data     string // 原始报文(已去除结尾的.)
+}
+
+// 启动SMTP服务, 监听本地随机端口
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("[mailtest]监听端口失败: %v", err))
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	s := &Server{
+		Host:     addr.IP.String(),
+		Port:     addr.Port,
+		listener: listener,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// 关闭服务
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+// 获取已收到的邮件
+func (s *Server) Messages() []Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Message{}, s.messages...)
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 mailtest ESMTP ready")
+	var msg Message
+	var username string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-mailtest")
+			reply("250-8BITMIME")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "HELO"):
+			reply("250 mailtest")
+		case strings.HasPrefix(cmd, "AUTH PLAIN"):
+			// 格式: AUTH PLAIN base64(identity\x00username\x00password), 不校验密码
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				reply("501 syntax error")
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(fields[2])
+			parts := strings.Split(string(decoded), "\x00")
+			if err != nil || len(parts) != 3 {
+				reply("535 authentication failed")
+				continue
+			}
+			username = parts[1]
+			reply("235 authentication succeeded")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg = Message{
+				Username: username,
+				From:     address(line[len("MAIL FROM:"):]),
+			}
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.To = append(msg.To, address(line[len("RCPT TO:"):]))
+			reply("250 ok")
+		case cmd == "DATA":
+			if len(msg.To) == 0 {
+				reply("503 need RCPT")
+				continue
+			}
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" || dataLine == ".\n" {
+					break
+				}
+				// 去除点填充
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			msg.Data = data.String()
+			s.lock.Lock()
+			s.messages = append(s.messages, msg)
+			s.lock.Unlock()
+			msg = Message{}
+			reply("250 ok: queued")
+		case cmd == "RSET":
+			msg = Message{}
+			reply("250 ok")
+		case cmd == "NOOP":
+			reply("250 ok")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// 解析<addr>格式的地址
+func address(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, " "); i > 0 {
+		// 忽略SIZE等参数
+		s = s[:i]
+	}
+	return strings.Trim(s, "<>")
+}
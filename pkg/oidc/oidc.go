@@ -0,0 +1,349 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDC授权码模式(PKCE)客户端, 仅依赖标准库
+// ID令牌签名只支持RS256(OIDC规范要求IdP必须支持的算法)
+
+// 客户端配置
+type Config struct {
+	Issuer       string   // IdP地址, 用于服务发现及校验iss
+	ClientId     string   // 客户端编号, 用于校验aud
+	ClientSecret string   // 客户端密钥, 公开客户端可为空(仅使用PKCE)
+	RedirectUrl  string   // 回调地址, 需与IdP中登记的一致
+	Scopes       []string // 申请的scope, 必须包含openid
+}
+
+// ID令牌声明
+type Claims map[string]interface{}
+
+// 获取字符串声明
+func (c Claims) String(name string) string {
+	v, _ := c[name].(string)
+	return v
+}
+
+// 获取字符串数组声明, 兼容单个字符串
+func (c Claims) Strings(name string) []string {
+	arr := make([]string, 0)
+	switch v := c[name].(type) {
+	case string:
+		arr = append(arr, v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				arr = append(arr, s)
+			}
+		}
+	}
+	return arr
+}
+
+// 服务发现结果
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+	lock                  sync.Mutex
+	keys                  map[string]*rsa.PublicKey // 签名公钥, 以kid为键
+}
+
+// 允许的时钟误差
+const clockSkew = time.Minute
+
+var httpClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// 已发现的IdP, 避免每次登录都请求服务发现接口
+var providers = struct {
+	sync.Mutex
+	m map[string]*Provider
+}{
+	m: make(map[string]*Provider),
+}
+
+// 服务发现(/.well-known/openid-configuration), 结果会被缓存
+func Discover(issuer string) (*Provider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	providers.Lock()
+	defer providers.Unlock()
+	if p, ok := providers.m[issuer]; ok {
+		return p, nil
+	}
+	var p Provider
+	err := getJson(issuer+"/.well-known/openid-configuration", &p)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC服务发现失败: %v", err)
+	}
+	// 规范要求发现文档中的issuer必须与配置完全一致
+	if strings.TrimSuffix(p.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC服务发现失败: issuer不匹配(%s)", p.Issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JwksUri == "" {
+		return nil, errors.New("OIDC服务发现失败: 缺少必要的端点")
+	}
+	providers.m[issuer] = &p
+	return &p, nil
+}
+
+// 生成PKCE code_verifier
+func GenCodeVerifier() string {
+	return randomString(32)
+}
+
+// 生成随机字符串(state/nonce等)
+func GenState() string {
+	return randomString(24)
+}
+
+// 计算PKCE code_challenge(S256)
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// 生成授权地址
+func (p *Provider) AuthCodeUrl(conf Config, state string, nonce string, codeChallenge string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", conf.ClientId)
+	v.Set("redirect_uri", conf.RedirectUrl)
+	v.Set("scope", strings.Join(scopes(conf.Scopes), " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", codeChallenge)
+	v.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.AuthorizationEndpoint + sep + v.Encode()
+}
+
+// 使用授权码换取ID令牌
+func (p *Provider) Exchange(conf Config, code string, codeVerifier string) (string, error) {
+	v := url.Values{}
+	v.Set("grant_type", "authorization_code")
+	v.Set("code", code)
+	v.Set("redirect_uri", conf.RedirectUrl)
+	v.Set("client_id", conf.ClientId)
+	v.Set("code_verifier", codeVerifier)
+	req, err := http.NewRequest(http.MethodPost, p.TokenEndpoint, strings.NewReader(v.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if conf.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(conf.ClientId), url.QueryEscape(conf.ClientSecret))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = json.Unmarshal(body, &token)
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("授权码换取令牌失败: %d %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IdToken == "" {
+		return "", errors.New("授权码换取令牌失败: 响应中缺少id_token")
+	}
+	return token.IdToken, nil
+}
+
+// 校验ID令牌(签名/iss/aud/exp/nonce), 通过返回声明
+func (p *Provider) VerifyIdToken(conf Config, rawIdToken string, nonce string, now time.Time) (Claims, error) {
+	parts := strings.Split(rawIdToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("ID令牌格式错误")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, fmt.Errorf("ID令牌头部解析失败: %v", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("不支持的ID令牌签名算法: %s", header.Alg)
+	}
+	key, err := p.publicKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("ID令牌签名格式错误")
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig)
+	if err != nil {
+		return nil, errors.New("ID令牌签名校验失败")
+	}
+	var claims Claims
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, fmt.Errorf("ID令牌声明解析失败: %v", err)
+	}
+	if strings.TrimSuffix(claims.String("iss"), "/") != strings.TrimSuffix(p.Issuer, "/") {
+		return nil, errors.New("ID令牌签发方不匹配")
+	}
+	audOk := false
+	for _, aud := range claims.Strings("aud") {
+		if aud == conf.ClientId {
+			audOk = true
+		}
+	}
+	if !audOk {
+		return nil, errors.New("ID令牌受众不匹配")
+	}
+	exp, _ := claims["exp"].(float64)
+	if now.Add(-clockSkew).Unix() > int64(exp) {
+		return nil, errors.New("ID令牌已过期")
+	}
+	if iat, ok := claims["iat"].(float64); ok && int64(iat) > now.Add(clockSkew).Unix() {
+		return nil, errors.New("ID令牌签发时间无效")
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.String("nonce")), []byte(nonce)) != 1 {
+		return nil, errors.New("ID令牌nonce不匹配")
+	}
+	if claims.String("sub") == "" {
+		return nil, errors.New("ID令牌缺少sub")
+	}
+	return claims, nil
+}
+
+// 获取签名公钥, 未找到时重新拉取jwks(IdP可能已轮换密钥)
+func (p *Provider) publicKey(kid string) (*rsa.PublicKey, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if key := findKey(p.keys, kid); key != nil {
+		return key, nil
+	}
+	keys, err := fetchKeys(p.JwksUri)
+	if err != nil {
+		return nil, fmt.Errorf("获取IdP签名公钥失败: %v", err)
+	}
+	p.keys = keys
+	if key := findKey(p.keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, errors.New("未找到ID令牌对应的签名公钥")
+}
+
+// 按kid查找公钥, kid为空且只有一个公钥时直接使用
+func findKey(keys map[string]*rsa.PublicKey, kid string) *rsa.PublicKey {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// 拉取jwks中的RSA签名公钥
+func fetchKeys(jwksUri string) (map[string]*rsa.PublicKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	err := getJson(jwksUri, &jwks)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// 发送GET请求并解析json
+func getJson(u string, v interface{}) error {
+	resp, err := httpClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求%s失败: %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// 解码jwt片段
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(seg, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// scope必须包含openid
+func scopes(arr []string) []string {
+	for _, s := range arr {
+		if s == "openid" {
+			return arr
+		}
+	}
+	return append([]string{"openid"}, arr...)
+}
+
+// 生成url安全的随机字符串, n为随机字节数
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("[randomString]生成随机数失败: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
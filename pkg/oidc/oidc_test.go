@@ -0,0 +1,123 @@
+package oidc
+
+import (
+	"gin-web/pkg/oidc/oidctest"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// 完整的授权码+PKCE流程
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := oidctest.NewServer("gin-web", "secret", map[string]interface{}{
+		"sub":                "10001",
+		"preferred_username": "sso-user",
+		"groups":             []string{"admins"},
+	})
+	defer idp.Close()
+	conf := Config{
+		Issuer:       idp.URL,
+		ClientId:     "gin-web",
+		ClientSecret: "secret",
+		RedirectUrl:  "http://127.0.0.1/sso/callback",
+		Scopes:       []string{"profile"},
+	}
+	p, err := Discover(conf.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := GenState()
+	nonce := GenState()
+	verifier := GenCodeVerifier()
+	code := authorize(t, p.AuthCodeUrl(conf, state, nonce, CodeChallengeS256(verifier)), state)
+
+	tests := []struct {
+		name     string
+		code     string
+		verifier string
+		nonce    string
+		wantErr  bool
+	}{
+		{"case1", code, "wrong-verifier", nonce, true},
+		// 授权码只能使用一次, 因此重新授权
+		{"case2", authorize(t, p.AuthCodeUrl(conf, state, nonce, CodeChallengeS256(verifier)), state), verifier, "wrong-nonce", true},
+		{"case3", authorize(t, p.AuthCodeUrl(conf, state, nonce, CodeChallengeS256(verifier)), state), verifier, nonce, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idToken, err := p.Exchange(conf, tt.code, tt.verifier)
+			if err == nil {
+				var claims Claims
+				claims, err = p.VerifyIdToken(conf, idToken, tt.nonce, time.Now())
+				if err == nil && (claims.String("preferred_username") != "sso-user" || claims.Strings("groups")[0] != "admins") {
+					t.Errorf("VerifyIdToken() claims = %v", claims)
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvider_VerifyIdToken(t *testing.T) {
+	idp := oidctest.NewServer("gin-web", "", nil)
+	defer idp.Close()
+	other := oidctest.NewServer("gin-web", "", nil)
+	defer other.Close()
+	conf := Config{
+		Issuer:   idp.URL,
+		ClientId: "gin-web",
+	}
+	p, err := Discover(conf.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"case1", idp.SignIdToken(map[string]interface{}{"sub": "1", "nonce": "n"}), false},
+		{"case2", idp.SignIdToken(map[string]interface{}{"sub": "1", "nonce": "n", "aud": "other"}), true},
+		{"case3", idp.SignIdToken(map[string]interface{}{"sub": "1", "nonce": "n", "aud": []string{"other", "gin-web"}}), false},
+		{"case4", idp.SignIdToken(map[string]interface{}{"sub": "1", "nonce": "n", "iss": other.URL}), true},
+		{"case5", idp.SignIdToken(map[string]interface{}{"sub": "1", "nonce": "n", "exp": now.Add(-time.Hour).Unix()}), true},
+		{"case6", idp.SignIdToken(map[string]interface{}{"nonce": "n"}), true},
+		// 其他IdP签名的令牌(kid相同, 公钥不同)
+		{"case7", other.SignIdToken(map[string]interface{}{"sub": "1", "nonce": "n", "iss": idp.URL}), true},
+		{"case8", "not.a.jwt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.VerifyIdToken(conf, tt.token, "n", now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyIdToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 访问授权地址(模拟IdP自动同意), 返回回调中的授权码
+func authorize(t *testing.T, authUrl string, state string) string {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(authUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Query().Get("state") != state {
+		t.Fatalf("state不匹配: %s", location.Query().Get("state"))
+	}
+	return location.Query().Get("code")
+}
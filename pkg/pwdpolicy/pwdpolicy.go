@@ -0,0 +1,55 @@
+package pwdpolicy
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/pkg/global"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 校验密码是否符合密码策略
+func Check(policy global.PwdPolicyConfiguration, username string, pwd string) error {
+	length := utf8.RuneCountInString(pwd)
+	if length < policy.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", policy.MinLength)
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		return fmt.Errorf("密码长度不能超过%d位", policy.MaxLength)
+	}
+	var upper, lower, digit, special bool
+	for _, r := range pwd {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			special = true
+		}
+	}
+	if policy.RequireUpper && !upper {
+		return errors.New("密码必须包含大写字母")
+	}
+	if policy.RequireLower && !lower {
+		return errors.New("密码必须包含小写字母")
+	}
+	if policy.RequireDigit && !digit {
+		return errors.New("密码必须包含数字")
+	}
+	if policy.RequireSpecial && !special {
+		return errors.New("密码必须包含特殊字符")
+	}
+	if policy.NotUsername && username != "" && strings.EqualFold(pwd, username) {
+		return errors.New("密码不能与用户名相同")
+	}
+	for _, item := range policy.Banned {
+		if strings.EqualFold(pwd, item) {
+			return errors.New("密码过于简单, 请更换")
+		}
+	}
+	return nil
+}
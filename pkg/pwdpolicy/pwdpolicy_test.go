@@ -0,0 +1,41 @@
+package pwdpolicy
+
+import (
+	"gin-web/pkg/global"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	policy := global.PwdPolicyConfiguration{
+		MinLength:    8,
+		MaxLength:    16,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+		NotUsername:  true,
+		Banned:       []string{"Password1"},
+	}
+	tests := []struct {
+		name     string
+		username string
+		pwd      string
+		wantErr  bool
+	}{
+		{"case1", "admin", "Abc12345", false},
+		{"case2", "admin", "Abc1234", true},
+		{"case3", "admin", "Abc12345678901234", true},
+		{"case4", "admin", "abc12345", true},
+		{"case5", "admin", "ABC12345", true},
+		{"case6", "admin", "Abcdefgh", true},
+		{"case7", "Admin1234", "admin1234", true},
+		{"case8", "Admin1234", "Admin1234", true},
+		{"case9", "admin", "password1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Check(policy, tt.username, tt.pwd); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
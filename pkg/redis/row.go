@@ -11,94 +11,105 @@ import (
 )
 
 const (
-	idName        = "id"
 	deletedAtName = "deletedAt"
+	tenantIdName  = "tenantId"
 )
 
 // mysql数据行发生变化, 同步数据到redis
 func RowChange(e *canal.RowsEvent) {
-	database := e.Table.Schema
 	table := e.Table.Name
-	// 默认以id为主键, 查找id的索引位置
-	idIndex := -1
+	// 查找软删除/租户字段的索引位置
 	deletedAtIndex := -1
+	tenantIdIndex := -1
 	for i, column := range e.Table.Columns {
 		name := utils.CamelCaseLowerFirst(column.Name)
-		if name == idName {
-			idIndex = i
-		}
-		if name == deletedAtName {
+		switch name {
+		case deletedAtName:
 			deletedAtIndex = i
+		case tenantIdName:
+			tenantIdIndex = i
 		}
-		if idIndex >= 0 && deletedAtIndex >= 0 {
-			break
+	}
+	// 按租户隔离的表, 每个租户的数据使用单独的缓存键(参见global.CacheKey)
+	cache := make(map[string][]map[string]interface{}, 0)
+	getCacheKey := func(data []interface{}) string {
+		var tenantId uint
+		if tenantIdIndex >= 0 && tenantIdIndex < len(data) {
+			// 数字在json解析后为float64
+			if v, ok := data[tenantIdIndex].(float64); ok {
+				tenantId = uint(v)
+			}
+		}
+		cacheKey := global.CacheKey(table, tenantId)
+		if _, ok := cache[cacheKey]; !ok {
+			// 读取redis历史数据
+			rows := make([]map[string]interface{}, 0)
+			oldRows, err := global.Redis.Get(cacheKey).Result()
+			if err == nil {
+				// 将旧数据解析为对象
+				utils.Json2Struct(oldRows, &rows)
+			}
+			cache[cacheKey] = rows
 		}
+		return cacheKey
 	}
-	// 缓存键由数据库名与表名组成
-	cacheKey := fmt.Sprintf("%s_%s", database, table)
-	// 读取redis历史数据
-	oldRows, err := global.Redis.Get(cacheKey).Result()
-	newRows := make([]map[string]interface{}, 0)
 	changeRows := make([][]interface{}, 0)
-	if err == nil {
-		// 将旧数据解析为对象
-		utils.Json2Struct(oldRows, &newRows)
-	}
-	rowCount := len(newRows)
 	// 将rows用json解析一下, 否则查找相同元素时可能出现类型不一致
 	utils.Struct2StructByJson(e.Rows, &changeRows)
 	// 选择事件类型
 	switch e.Action {
 	case canal.InsertAction:
 		// 插入数据
+		cacheKey := getCacheKey(changeRows[0])
 		row := getRow(changeRows[0], e.Table)
 		if row[deletedAtName] == nil {
 			// 由于gorm默认执行软删除, 当delete_at为空时才加入redis缓存
-			newRows = append(newRows, row)
+			cache[cacheKey] = append(cache[cacheKey], row)
 		}
 		break
 	case canal.UpdateAction:
 		// 更新数据
 		// 通过历史数据changeRows[0]去匹配需要更新的数据所在索引
-		index := getOldRowIndex(newRows, changeRows[0], e.Table)
+		oldCacheKey := getCacheKey(changeRows[0])
+		cacheKey := getCacheKey(changeRows[1])
+		index := getOldRowIndex(cache[oldCacheKey], changeRows[0], e.Table)
 		if deletedAtIndex >= 0 && changeRows[0][deletedAtIndex] == nil && changeRows[1][deletedAtIndex] != nil {
 			// 由于gorm默认执行软删除, 当delete_at发生变化时清理redis缓存
-			if index < rowCount-1 {
-				newRows = append(newRows[:index], newRows[index+1:]...)
-			} else {
-				newRows = append(newRows[:index])
-			}
+			cache[oldCacheKey] = removeRow(cache[oldCacheKey], index)
+		} else if oldCacheKey != cacheKey || index < 0 {
+			// 租户发生变化, 从原租户缓存中移除后加入新租户缓存
+			cache[oldCacheKey] = removeRow(cache[oldCacheKey], index)
+			cache[cacheKey] = append(cache[cacheKey], getRow(changeRows[1], e.Table))
 		} else {
 			// 执行更新
-			newRows[index] = getRow(changeRows[1], e.Table)
+			cache[cacheKey][index] = getRow(changeRows[1], e.Table)
 		}
 		break
 	case canal.DeleteAction:
 		// 删除数据
-		// 找到需要删除的索引
-		indexes := make([]int, 0)
 		for _, changeRow := range changeRows {
 			// 找到没有改变的数据所在索引
-			index := getOldRowIndex(newRows, changeRow, e.Table)
-			if index > -1 {
-				indexes = append(indexes, index)
-			}
-		}
-		// 删除对应数据
-		for _, index := range indexes {
-			if index < rowCount-1 {
-				newRows = append(newRows[:index], newRows[index+1:]...)
-			} else {
-				newRows = append(newRows[:index])
-			}
+			cacheKey := getCacheKey(changeRow)
+			index := getOldRowIndex(cache[cacheKey], changeRow, e.Table)
+			cache[cacheKey] = removeRow(cache[cacheKey], index)
 		}
 		break
 	}
-	// 将数据转为json字符串写入redis, expiration=0永不过期
-	err = global.Redis.Set(cacheKey, utils.Struct2Json(newRows), 0).Err()
-	if err != nil {
-		global.Log.Error("同步binlog增量数据到redis失败: ", err, e)
+	for cacheKey, rows := range cache {
+		// 将数据转为json字符串写入redis, expiration=0永不过期
+		err := global.Redis.Set(cacheKey, utils.Struct2Json(rows), 0).Err()
+		if err != nil {
+			global.Log.Error("同步binlog增量数据到redis失败: ", err, e)
+		}
+	}
+}
+
+// 删除指定索引的数据, 索引无效时不处理
+func removeRow(rows []map[string]interface{}, index int) []map[string]interface{} {
+	if index < 0 || index >= len(rows) {
+		return rows
 	}
+	return append(rows[:index], rows[index+1:]...)
 }
 
 // 获取旧数据所在行索引
@@ -3,6 +3,7 @@ package request
 import (
 	"gin-web/models"
 	"gin-web/pkg/utils"
+	"strings"
 )
 
 // 适用于大多数场景的请求参数绑定
@@ -15,6 +16,18 @@ func (s *Req) GetUintIds() []uint {
 	return utils.Str2UintArr(s.Ids)
 }
 
+// 获取字符串类型的id集合(如会话编号)
+func (s *Req) GetStringIds() []string {
+	ids := make([]string, 0)
+	for _, v := range strings.Split(s.Ids, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			ids = append(ids, v)
+		}
+	}
+	return ids
+}
+
 // 增量更新id集合结构体
 type UpdateIncrementalIdsRequestStruct struct {
 	Create []uint `json:"create"` // 需要新增的编号集合
@@ -10,6 +10,7 @@ type ApiListRequestStruct struct {
 	Path              string `json:"path" form:"path"`
 	Category          string `json:"category" form:"category"`
 	Creator           string `json:"creator" form:"creator"`
+	Stale             *bool  `json:"stale" form:"stale"`
 	response.PageInfo        // 分页参数
 }
 
@@ -0,0 +1,17 @@
+package request
+
+// 创建API密钥结构体
+type CreateApiKeyRequestStruct struct {
+	Name       string `json:"name" validate:"required"`
+	ApiIds     []uint `json:"apiIds" validate:"required"` // 权限范围(接口编号), 只能选择当前角色已授权的接口
+	ExpireDays uint   `json:"expireDays"`                 // 有效天数, 0表示永不过期
+	Creator    string `json:"creator"`
+}
+
+// 翻译需要校验的字段名称
+func (s CreateApiKeyRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["Name"] = "密钥名称"
+	m["ApiIds"] = "权限范围"
+	return m
+}
@@ -0,0 +1,17 @@
+package request
+
+// 权限检查结构体, 用户与角色二选一
+type PermissionExplainRequestStruct struct {
+	UserId uint   `json:"userId" form:"userId"`
+	RoleId uint   `json:"roleId" form:"roleId"`
+	Method string `json:"method" form:"method" validate:"required"`
+	Path   string `json:"path" form:"path" validate:"required"`
+}
+
+// 翻译需要校验的字段名称
+func (s PermissionExplainRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["Method"] = "请求方式"
+	m["Path"] = "访问路径"
+	return m
+}
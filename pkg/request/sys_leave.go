@@ -7,22 +7,25 @@ import (
 
 // 获取请假列表结构体
 type LeaveListRequestStruct struct {
-	UserId            uint   `json:"userId"`
-	Status            *uint  `json:"status" form:"status"`
-	ApprovalOpinion   string `json:"approvalOpinion" form:"approvalOpinion"`
-	Desc              string `json:"desc" form:"desc"`
-	response.PageInfo        // 分页参数
+	UserId            uint                 `json:"-" form:"-"` // 不允许前端指定, 按数据范围过滤
+	Status            *uint                `json:"status" form:"status"`
+	ApprovalOpinion   string               `json:"approvalOpinion" form:"approvalOpinion"`
+	Desc              string               `json:"desc" form:"desc"`
+	DataScope         *models.SysDataScope `json:"-" form:"-"` // 数据范围, 由接口根据当前用户设置, 为空时不限制
+	response.PageInfo                      // 分页参数
 }
 
 // 创建请假结构体
 type CreateLeaveRequestStruct struct {
 	User models.SysUser `json:"user"`
 	Desc string         `json:"desc" validate:"required"`
+	Days float64        `json:"days" validate:"required,gt=0"`
 }
 
 // 翻译需要校验的字段名称
 func (s CreateLeaveRequestStruct) FieldTrans() map[string]string {
 	m := make(map[string]string, 0)
 	m["Desc"] = "说明"
+	m["Days"] = "请假天数"
 	return m
 }
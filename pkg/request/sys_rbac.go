@@ -0,0 +1,6 @@
+package request
+
+// 导出权限配置包结构体
+type RbacExportRequestStruct struct {
+	Format string `json:"format" form:"format"` // 导出格式(json/csv, 默认json)
+}
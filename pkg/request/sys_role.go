@@ -15,11 +15,14 @@ type RoleListRequestStruct struct {
 
 // 创建角色结构体
 type CreateRoleRequestStruct struct {
-	Name    string `json:"name" validate:"required"`
-	Keyword string `json:"keyword" validate:"required"`
-	Desc    string `json:"desc"`
-	Status  *bool  `json:"status"`
-	Creator string `json:"creator"`
+	Name         string `json:"name" validate:"required"`
+	Keyword      string `json:"keyword" validate:"required"`
+	Desc         string `json:"desc"`
+	Status       *bool  `json:"status"`
+	Creator      string `json:"creator"`
+	TotpRequired *bool  `json:"totpRequired"` // 是否强制开启二次验证
+	DataScope    uint   `json:"dataScope"`    // 数据范围(0:仅本人 2:本部门 3:本部门及以下 4:自定义部门 5:全部)
+	DeptIds      []uint `json:"deptIds"`      // 自定义数据范围的部门
 }
 
 // 翻译需要校验的字段名称
@@ -29,3 +32,10 @@ func (s CreateRoleRequestStruct) FieldTrans() map[string]string {
 	m["Keyword"] = "角色关键字"
 	return m
 }
+
+// 更新角色权限接口结构体, Create/Delete为允许访问的接口, DenyCreate/DenyDelete为拒绝访问的接口(拒绝优先)
+type UpdateRoleApisRequestStruct struct {
+	UpdateIncrementalIdsRequestStruct
+	DenyCreate []uint `json:"denyCreate"` // 需要新增的拒绝接口编号集合
+	DenyDelete []uint `json:"denyDelete"` // 需要删除的拒绝接口编号集合
+}
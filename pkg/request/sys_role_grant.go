@@ -0,0 +1,43 @@
+package request
+
+import (
+	"gin-web/pkg/response"
+)
+
+// 获取临时授权列表结构体
+type RoleGrantListRequestStruct struct {
+	UserId            uint  `json:"userId" form:"userId"`
+	RoleId            uint  `json:"roleId" form:"roleId"`
+	Status            *uint `json:"status" form:"status"`
+	Active            *bool `json:"active" form:"active"` // 是否当前生效
+	response.PageInfo       // 分页参数
+}
+
+// 创建临时授权结构体
+type CreateRoleGrantRequestStruct struct {
+	UserId  uint   `json:"userId" validate:"required"`
+	RoleId  uint   `json:"roleId" validate:"required"`
+	StartAt int64  `json:"startAt"` // 生效时间(unix秒), 为空表示立即生效
+	EndAt   int64  `json:"endAt" validate:"required"`
+	Reason  string `json:"reason" validate:"required"`
+	Creator string `json:"creator"`
+}
+
+// 翻译需要校验的字段名称
+func (s CreateRoleGrantRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["UserId"] = "被授权用户"
+	m["RoleId"] = "授权角色"
+	m["EndAt"] = "失效时间"
+	m["Reason"] = "授权原因"
+	return m
+}
+
+// 获取临时授权审计日志结构体
+type RoleGrantLogListRequestStruct struct {
+	GrantId           uint   `json:"grantId" form:"grantId"`
+	UserId            uint   `json:"userId" form:"userId"`
+	RoleId            uint   `json:"roleId" form:"roleId"`
+	Action            string `json:"action" form:"action"`
+	response.PageInfo        // 分页参数
+}
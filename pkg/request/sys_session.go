@@ -0,0 +1,10 @@
+package request
+
+import "gin-web/pkg/response"
+
+// 获取会话列表结构体
+type SessionListRequestStruct struct {
+	UserId            uint   `json:"userId" form:"userId"`
+	Username          string `json:"username" form:"username"`
+	response.PageInfo        // 分页参数
+}
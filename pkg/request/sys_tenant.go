@@ -0,0 +1,31 @@
+package request
+
+import (
+	"gin-web/pkg/response"
+)
+
+// 获取租户列表结构体
+type TenantListRequestStruct struct {
+	Name              string `json:"name" form:"name"`
+	Status            *bool  `json:"status" form:"status"`
+	Creator           string `json:"creator" form:"creator"`
+	response.PageInfo        // 分页参数
+}
+
+// 创建租户结构体, 填写管理员用户名时同时创建租户的管理员角色与用户
+type CreateTenantRequestStruct struct {
+	Name          string `json:"name" validate:"required"`
+	Desc          string `json:"desc"`
+	Status        *bool  `json:"status"`
+	Creator       string `json:"creator"`
+	AdminUsername string `json:"adminUsername"` // 管理员用户名
+	AdminPassword string `json:"adminPassword"` // 管理员初始密码, 首次登录必须修改
+	AdminMobile   string `json:"adminMobile"`   // 管理员手机号
+}
+
+// 翻译需要校验的字段名称
+func (s CreateTenantRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["Name"] = "租户名称"
+	return m
+}
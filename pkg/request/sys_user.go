@@ -1,11 +1,50 @@
 package request
 
-import "gin-web/pkg/response"
+import (
+	"gin-web/models"
+	"gin-web/pkg/response"
+)
 
 // User login structure
 type RegisterAndLoginRequestStruct struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Device   string `json:"device"` // 登录设备标识, 每个设备单独保存刷新令牌
+	// 开启二次验证时, 第二步提交密码校验返回的挑战令牌与验证码(或恢复码), 无需再次提交用户名密码
+	Challenge string `json:"challenge"`
+	TotpCode  string `json:"totpCode"`
+}
+
+// 二次验证码结构体
+type TotpCodeRequestStruct struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// 翻译需要校验的字段名称
+func (s TotpCodeRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["Code"] = "验证码"
+	return m
+}
+
+// SSO回调结构体
+type OidcCallbackRequestStruct struct {
+	Code   string `json:"code" validate:"required"`
+	State  string `json:"state" validate:"required"`
+	Device string `json:"device"` // 登录设备标识, 每个设备单独保存刷新令牌
+}
+
+// 翻译需要校验的字段名称
+func (s OidcCallbackRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["Code"] = "授权码"
+	m["State"] = "状态码"
+	return m
+}
+
+// 刷新令牌结构体
+type RefreshTokenRequestStruct struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 // 修改密码结构体
@@ -14,18 +53,47 @@ type ChangePwdRequestStruct struct {
 	NewPassword string `json:"newPassword" form:"newPassword"`
 }
 
+// 找回密码结构体
+type PwdResetMailRequestStruct struct {
+	Username string `json:"username" validate:"required"`
+}
+
+// 翻译需要校验的字段名称
+func (s PwdResetMailRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["Username"] = "用户名"
+	return m
+}
+
+// 重置密码结构体
+type PwdResetRequestStruct struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+// 翻译需要校验的字段名称
+func (s PwdResetRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["Token"] = "重置令牌"
+	m["NewPassword"] = "新密码"
+	return m
+}
+
 // 获取用户列表结构体
 type UserListRequestStruct struct {
-	Id                uint   `json:"id" form:"id"`
-	Username          string `json:"username" form:"username"`
-	Mobile            string `json:"mobile" form:"mobile"`
-	Avatar            string `json:"avatar" form:"avatar"`
-	Nickname          string `json:"nickname" form:"nickname"`
-	Introduction      string `json:"introduction" form:"introduction"`
-	Status            *bool  `json:"status" form:"status"`
-	RoleId            uint   `json:"roleId" form:"roleId"`
-	Creator           string `json:"creator" form:"creator"`
-	response.PageInfo        // 分页参数
+	Id                uint                 `json:"id" form:"id"`
+	Username          string               `json:"username" form:"username"`
+	Mobile            string               `json:"mobile" form:"mobile"`
+	Email             string               `json:"email" form:"email"`
+	Avatar            string               `json:"avatar" form:"avatar"`
+	Nickname          string               `json:"nickname" form:"nickname"`
+	Introduction      string               `json:"introduction" form:"introduction"`
+	Status            *bool                `json:"status" form:"status"`
+	RoleId            uint                 `json:"roleId" form:"roleId"`
+	DeptId            uint                 `json:"deptId" form:"deptId"`
+	Creator           string               `json:"creator" form:"creator"`
+	DataScope         *models.SysDataScope `json:"-" form:"-"` // 数据范围, 由接口根据当前用户设置, 为空时不限制
+	response.PageInfo                      // 分页参数
 }
 
 // 创建用户结构体
@@ -33,11 +101,14 @@ type CreateUserRequestStruct struct {
 	Username     string `json:"username" validate:"required"`
 	InitPassword string `json:"initPassword" validate:"required"` // 不使用SysUser的Password字段, 避免请求劫持绕过系统校验
 	Mobile       string `json:"mobile" validate:"required"`
+	Email        string `json:"email" validate:"omitempty,email"`
 	Avatar       string `json:"avatar"`
 	Nickname     string `json:"nickname"`
 	Introduction string `json:"introduction"`
 	Status       *bool  `json:"status"`
 	RoleId       uint   `json:"roleId" validate:"required"`
+	RoleIds      []uint `json:"roleIds"` // 附加角色
+	DeptId       uint   `json:"deptId"`
 	Creator      string `json:"creator"`
 }
 
@@ -47,6 +118,7 @@ func (s CreateUserRequestStruct) FieldTrans() map[string]string {
 	m["Username"] = "用户名"
 	m["InitPassword"] = "初始密码"
 	m["Mobile"] = "手机号"
+	m["Email"] = "邮箱"
 	m["RoleId"] = "角色"
 	return m
 }
@@ -55,6 +55,11 @@ type UpdateWorkflowLineRequestStruct struct {
 	UserIds []uint `json:"userIds"`
 	Name    string `json:"name" validate:"required"`
 	Edit    *bool  `json:"edit"`
+	// 审批时限
+	RemindHours    uint `json:"remindHours"`
+	TimeoutHours   uint `json:"timeoutHours"`
+	TimeoutAction  uint `json:"timeoutAction" validate:"max=3"`
+	EscalateRoleId uint `json:"escalateRoleId"`
 }
 
 // 翻译需要校验的字段名称
@@ -66,6 +71,7 @@ func (s UpdateWorkflowLineRequestStruct) FieldTrans() map[string]string {
 	m["UserIds"] = "审批人"
 	m["Name"] = "流水线名称"
 	m["Edit"] = "编辑权限"
+	m["TimeoutAction"] = "超时操作"
 	return m
 }
 
@@ -84,6 +90,39 @@ func (s UpdateWorkflowLineIncrementalRequestStruct) FieldTrans() map[string]stri
 	return m
 }
 
+// 流程连线结构体
+type WorkflowEdgeRequestStruct struct {
+	FromLineId uint   `json:"fromLineId"` // 0表示开始
+	ToLineId   uint   `json:"toLineId"`   // 0表示结束
+	Condition  string `json:"condition"`  // 流转条件, 例如 days > 3 && user.role == "leave", 为空表示无条件
+	IsDefault  bool   `json:"isDefault"`  // 默认连线, 同一起点的其他连线都不满足条件时流转
+}
+
+// 更新流程连线结构体(全量)
+type UpdateWorkflowEdgeRequestStruct struct {
+	FlowId uint                        `json:"flowId" validate:"required"`
+	Edges  []WorkflowEdgeRequestStruct `json:"edges"` // 为空表示按流水线排序依次流转
+}
+
+// 翻译需要校验的字段名称
+func (s UpdateWorkflowEdgeRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["FlowId"] = "流程号"
+	return m
+}
+
+// 获取流程连线结构体
+type WorkflowEdgeListRequestStruct struct {
+	FlowId uint `json:"flowId" form:"flowId" validate:"required"`
+}
+
+// 翻译需要校验的字段名称
+func (s WorkflowEdgeListRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["FlowId"] = "流程号"
+	return m
+}
+
 // 工作流转移结构体
 type WorkflowTransitionRequestStruct struct {
 	FlowId          uint   `json:"flowId" validate:"required"`
@@ -93,7 +132,10 @@ type WorkflowTransitionRequestStruct struct {
 	SubmitDetail    string `json:"submitDetail"`
 	ApprovalUserId  uint   `json:"approvalUserId"`
 	ApprovalOpinion string `json:"approvalOpinion"`
-	ApprovalStatus  *uint  `json:"approvalStatus" validate:"required,min=1,max=4"`
+	ApprovalStatus  *uint  `json:"approvalStatus" validate:"required,oneof=1 2 3 4 7 8 9"`
+	LineId          uint   `json:"lineId"`         // 并行审批时指定审批的流水线, 为空时自动选择审批人有权限的流水线
+	AssigneeUserId  uint   `json:"assigneeUserId"` // 转交/加签的用户(审批状态为7:转交 8:前加签 9:后加签时必填)
+	System          bool   `json:"-"`              // 系统操作(超时自动审批), 不校验审批人, 只能由后台任务设置
 }
 
 // 翻译需要校验的字段名称
@@ -105,3 +147,31 @@ func (s WorkflowTransitionRequestStruct) FieldTrans() map[string]string {
 	m["ApprovalStatus"] = "审批状态"
 	return m
 }
+
+// 获取审批委托列表结构体
+type WorkflowDelegateListRequestStruct struct {
+	CurrentUserId     uint  `json:"-" form:"-"` // 当前用户, 只能查看自己作为委托人或被委托人的委托
+	UserId            uint  `json:"userId" form:"userId"`
+	DelegateUserId    uint  `json:"delegateUserId" form:"delegateUserId"`
+	FlowId            uint  `json:"flowId" form:"flowId"`
+	Active            *bool `json:"active" form:"active"` // 是否当前生效
+	response.PageInfo       // 分页参数
+}
+
+// 创建审批委托结构体
+type CreateWorkflowDelegateRequestStruct struct {
+	DelegateUserId uint   `json:"delegateUserId" validate:"required"`
+	FlowId         uint   `json:"flowId"`  // 为空表示全部流程
+	StartAt        int64  `json:"startAt"` // 生效时间(unix秒), 为空表示立即生效
+	EndAt          int64  `json:"endAt" validate:"required"`
+	Reason         string `json:"reason"`
+	Creator        string `json:"creator"`
+}
+
+// 翻译需要校验的字段名称
+func (s CreateWorkflowDelegateRequestStruct) FieldTrans() map[string]string {
+	m := make(map[string]string, 0)
+	m["DelegateUserId"] = "被委托人"
+	m["EndAt"] = "失效时间"
+	return m
+}
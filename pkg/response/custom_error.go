@@ -7,6 +7,8 @@ const (
 	NotOk               = 405
 	Unauthorized        = 401
 	Forbidden           = 403
+	TotpRequired        = 406
+	TooManyRequests     = 429
 	InternalServerError = 500
 )
 
@@ -15,6 +17,14 @@ const (
 	NotOkMsg               = "操作失败"
 	UnauthorizedMsg        = "登录过期, 需要重新登录"
 	LoginCheckErrorMsg     = "用户名或密码错误"
+	LoginLockedMsg         = "登录失败次数过多, 账号已临时锁定, 请稍后再试或联系管理员解锁"
+	LoginIpLockedMsg       = "当前IP登录失败次数过多, 请稍后再试"
+	LoginTooFrequentMsg    = "登录尝试过于频繁, 请稍后再试"
+	TotpRequiredMsg        = "已开启二次验证, 请输入验证器中的验证码或恢复码"
+	TotpCheckErrorMsg      = "二次验证码错误"
+	TotpChallengeErrorMsg  = "登录验证已失效, 请重新输入用户名和密码"
+	TotpEnrollRequiredMsg  = "当前角色要求开启二次验证, 请先完成绑定"
+	PwdChangeRequiredMsg   = "密码已过期或为初始密码, 请先修改密码"
 	ForbiddenMsg           = "无权访问该资源, 请联系网站管理员授权"
 	InternalServerErrorMsg = "服务器内部错误"
 )
@@ -24,5 +34,7 @@ var CustomError = map[int]string{
 	NotOk:               NotOkMsg,
 	Unauthorized:        UnauthorizedMsg,
 	Forbidden:           ForbiddenMsg,
+	TotpRequired:        TotpRequiredMsg,
+	TooManyRequests:     LoginTooFrequentMsg,
 	InternalServerError: InternalServerErrorMsg,
 }
@@ -13,6 +13,7 @@ type ApiListResponseStruct struct {
 	Creator   string           `json:"creator"`
 	Desc      string           `json:"desc"`
 	Title     string           `json:"title"`
+	Stale     bool             `json:"stale"`
 	CreatedAt models.LocalTime `json:"createdAt"`
 }
 
@@ -27,4 +28,5 @@ type ApiGroupByCategoryResponseStruct struct {
 type ApiTreeWithAccessResponseStruct struct {
 	List      []ApiGroupByCategoryResponseStruct `json:"list"`
 	AccessIds []uint                             `json:"accessIds"`
+	DenyIds   []uint                             `json:"denyIds"` // 拒绝访问的id列表(拒绝优先)
 }
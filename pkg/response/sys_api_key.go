@@ -0,0 +1,18 @@
+package response
+
+import (
+	"gin-web/models"
+)
+
+// API密钥信息响应, 字段含义见models.SysApiKey
+type ApiKeyListResponseStruct struct {
+	Id         uint                    `json:"id"`
+	Name       string                  `json:"name"`
+	Prefix     string                  `json:"prefix"`
+	ExpiresAt  int64                   `json:"expiresAt"`
+	LastUsedAt int64                   `json:"lastUsedAt"`
+	LastUsedIp string                  `json:"lastUsedIp"`
+	Creator    string                  `json:"creator"`
+	Apis       []ApiListResponseStruct `json:"apis"`
+	CreatedAt  models.LocalTime        `json:"createdAt"`
+}
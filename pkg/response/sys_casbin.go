@@ -0,0 +1,23 @@
+package response
+
+// 权限检查结果响应
+type PermissionExplainResponseStruct struct {
+	Allowed  bool                            `json:"allowed"`  // 是否允许访问(casbin检查结果, 匹配到拒绝策略时禁止访问)
+	Path     string                          `json:"path"`     // 实际检查的访问路径(已清除path前缀)
+	Method   string                          `json:"method"`   // 实际检查的请求方式
+	Subjects []string                        `json:"subjects"` // 用户(或指定角色)的角色关键字
+	Roles    []string                        `json:"roles"`    // 参与匹配的全部角色关键字(含继承的角色)
+	Api      *ApiListResponseStruct          `json:"api"`      // 访问路径对应的接口, 未登记时为空
+	Matched  []PermissionExplainPolicyStruct `json:"matched"`  // 匹配到的策略
+}
+
+// 匹配到的策略
+type PermissionExplainPolicyStruct struct {
+	Subject string                 `json:"subject"` // 请求的角色
+	Keyword string                 `json:"keyword"` // 策略中的角色(可能是继承的角色)
+	Path    string                 `json:"path"`    // 策略中的资源名称
+	Method  string                 `json:"method"`  // 策略中的请求类型
+	Effect  string                 `json:"effect"`  // 策略效果(allow/deny), 拒绝优先
+	Matcher string                 `json:"matcher"` // 资源名称的匹配方式(keyMatch2/keyMatch)
+	Api     *ApiListResponseStruct `json:"api"`     // 策略对应的接口, 未登记时为空
+}
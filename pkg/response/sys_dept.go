@@ -0,0 +1,12 @@
+package response
+
+// 部门树信息响应, 字段含义见models.SysDept
+type DeptTreeResponseStruct struct {
+	Id       uint                     `json:"id"`
+	ParentId uint                     `json:"parentId"`
+	Name     string                   `json:"name"`
+	Sort     int                      `json:"sort"`
+	Status   bool                     `json:"status"`
+	Creator  string                   `json:"creator"`
+	Children []DeptTreeResponseStruct `json:"children"`
+}
@@ -9,6 +9,7 @@ type LeaveListResponseStruct struct {
 	Id        uint             `json:"id"`
 	Status    *uint            `json:"status"`
 	Desc      string           `json:"desc"`
+	Days      float64          `json:"days"`
 	CreatedAt models.LocalTime `json:"createdAt"`
 }
 
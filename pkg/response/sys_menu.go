@@ -24,3 +24,8 @@ type MenuTreeWithAccessResponseStruct struct {
 	List      []MenuTreeResponseStruct `json:"list"`
 	AccessIds []uint                   `json:"accessIds"`
 }
+
+// 菜单(按钮)依赖的接口响应
+type MenuApisResponseStruct struct {
+	ApiIds []uint `json:"apiIds"`
+}
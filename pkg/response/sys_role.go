@@ -6,11 +6,20 @@ import (
 
 // 角色信息响应, 字段含义见models.SysRole
 type RoleListResponseStruct struct {
-	Id        uint             `json:"id"`
-	Name      string           `json:"name"`
-	Keyword   string           `json:"keyword"`
-	Desc      string           `json:"desc"`
-	Status    *bool            `json:"status"`
-	Creator   string           `json:"creator"`
-	CreatedAt models.LocalTime `json:"createdAt"`
+	Id           uint             `json:"id"`
+	Name         string           `json:"name"`
+	Keyword      string           `json:"keyword"`
+	Desc         string           `json:"desc"`
+	Status       *bool            `json:"status"`
+	Creator      string           `json:"creator"`
+	TotpRequired *bool            `json:"totpRequired"`
+	DataScope    uint             `json:"dataScope"`
+	DeptIds      []uint           `json:"deptIds"` // 自定义数据范围的部门
+	CreatedAt    models.LocalTime `json:"createdAt"`
+}
+
+// 角色继承关系响应
+type RoleParentsResponseStruct struct {
+	ParentIds    []uint `json:"parentIds"`    // 直接继承的父角色
+	InheritedIds []uint `json:"inheritedIds"` // 直接或间接继承的全部角色(不含自身)
 }
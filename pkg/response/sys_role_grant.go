@@ -0,0 +1,35 @@
+package response
+
+import (
+	"gin-web/models"
+)
+
+// 临时授权信息响应, 字段含义见models.SysRoleGrant
+type RoleGrantListResponseStruct struct {
+	Id           uint             `json:"id"`
+	UserId       uint             `json:"userId"`
+	Username     string           `json:"username"`
+	UserNickname string           `json:"userNickname"`
+	RoleId       uint             `json:"roleId"`
+	RoleName     string           `json:"roleName"`
+	RoleKeyword  string           `json:"roleKeyword"`
+	StartAt      int64            `json:"startAt"`
+	EndAt        int64            `json:"endAt"`
+	Reason       string           `json:"reason"`
+	Status       uint             `json:"status"`
+	Active       bool             `json:"active"` // 是否当前生效
+	Creator      string           `json:"creator"`
+	CreatedAt    models.LocalTime `json:"createdAt"`
+}
+
+// 临时授权审计日志响应, 字段含义见models.SysRoleGrantLog
+type RoleGrantLogListResponseStruct struct {
+	Id        uint             `json:"id"`
+	GrantId   uint             `json:"grantId"`
+	UserId    uint             `json:"userId"`
+	RoleId    uint             `json:"roleId"`
+	Action    string           `json:"action"`
+	Operator  string           `json:"operator"`
+	Detail    string           `json:"detail"`
+	CreatedAt models.LocalTime `json:"createdAt"`
+}
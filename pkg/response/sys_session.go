@@ -0,0 +1,16 @@
+package response
+
+// 会话信息响应, 字段含义见models.SysSession
+type SessionListResponseStruct struct {
+	Jti        string `json:"jti"`
+	UserId     uint   `json:"userId"`
+	Username   string `json:"username"`
+	Device     string `json:"device"`
+	Ip         string `json:"ip"`
+	UserAgent  string `json:"userAgent"`
+	LoginAt    int64  `json:"loginAt"`
+	IssuedAt   int64  `json:"issuedAt"`
+	LastSeenAt int64  `json:"lastSeenAt"`
+	ExpiresAt  int64  `json:"expiresAt"`
+	Current    bool   `json:"current"` // 是否为当前请求使用的会话
+}
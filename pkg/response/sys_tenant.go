@@ -0,0 +1,15 @@
+package response
+
+import (
+	"gin-web/models"
+)
+
+// 租户信息响应, 字段含义见models.SysTenant
+type TenantListResponseStruct struct {
+	Id        uint             `json:"id"`
+	Name      string           `json:"name"`
+	Desc      string           `json:"desc"`
+	Status    *bool            `json:"status"`
+	Creator   string           `json:"creator"`
+	CreatedAt models.LocalTime `json:"createdAt"`
+}
@@ -13,14 +13,19 @@ type LoginResponseStruct struct {
 
 // 用户信息响应
 type UserInfoResponseStruct struct {
-	Id           uint     `json:"id"`
-	Username     string   `json:"username"`
-	Mobile       string   `json:"mobile"`
-	Avatar       string   `json:"avatar"`
-	Nickname     string   `json:"nickname"`
-	Introduction string   `json:"introduction"`
-	Roles        []string `json:"roles"`
-	Permissions  []string `json:"permissions"`
+	Id            uint     `json:"id"`
+	Username      string   `json:"username"`
+	Mobile        string   `json:"mobile"`
+	Email         string   `json:"email"`
+	Avatar        string   `json:"avatar"`
+	Nickname      string   `json:"nickname"`
+	Introduction  string   `json:"introduction"`
+	TotpEnabled   *bool    `json:"totpEnabled"`
+	TotpRequired  bool     `json:"totpRequired"`  // 当前角色(任一角色)是否要求开启二次验证
+	MustChangePwd bool     `json:"mustChangePwd"` // 是否必须修改密码(初始密码或密码已过期)
+	PwdExpired    bool     `json:"pwdExpired"`    // 密码是否已过期
+	Roles         []string `json:"roles"`
+	Permissions   []string `json:"permissions"`
 }
 
 // 用户信息响应, 字段含义见models.SysUser
@@ -28,11 +33,17 @@ type UserListResponseStruct struct {
 	Id           uint             `json:"id"`
 	Username     string           `json:"username"`
 	Mobile       string           `json:"mobile"`
+	Email        string           `json:"email"`
 	Avatar       string           `json:"avatar"`
 	Nickname     string           `json:"nickname"`
 	Introduction string           `json:"introduction"`
 	Status       *bool            `json:"status"`
 	RoleId       uint             `json:"roleId"`
+	RoleIds      []uint           `json:"roleIds"` // 附加角色
+	DeptId       uint             `json:"deptId"`
 	Creator      string           `json:"creator"`
+	LockedUntil  int64            `json:"lockedUntil"`
+	Locked       bool             `json:"locked"` // 是否处于锁定状态
+	TotpEnabled  *bool            `json:"totpEnabled"`
 	CreatedAt    models.LocalTime `json:"createdAt"`
 }
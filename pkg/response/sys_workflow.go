@@ -13,6 +13,8 @@ type WorkflowLogsListResponseStruct struct {
 	FlowTargetCategory    uint             `json:"flowTargetCategory"`
 	FlowTargetCategoryStr string           `json:"flowTargetCategoryStr"`
 	TargetId              uint             `json:"targetId"`
+	CurrentLineId         uint             `json:"currentLineId"`
+	CurrentLineName       string           `json:"currentLineName"`
 	Status                *uint            `json:"status"`
 	StatusStr             string           `json:"statusStr"`
 	SubmitUsername        string           `json:"submitUsername"`
@@ -21,6 +23,11 @@ type WorkflowLogsListResponseStruct struct {
 	ApprovalUsername      string           `json:"approvalUsername"`
 	ApprovalUserNickname  string           `json:"approvalUserNickname"`
 	ApprovalOpinion       string           `json:"approvalOpinion"`
+	Actor                 string           `json:"actor"`
+	OnBehalfOfUsername    string           `json:"onBehalfOfUsername"`
+	OnBehalfOfNickname    string           `json:"onBehalfOfNickname"`
+	AssigneeUsername      string           `json:"assigneeUsername"`
+	AssigneeNickname      string           `json:"assigneeNickname"`
 	ApprovingUserIds      []uint           `json:"approvingUserIds"`
 	CreatedAt             models.LocalTime `json:"createdAt"`
 	UpdatedAt             models.LocalTime `json:"updatedAt"`
@@ -50,4 +57,38 @@ type WorkflowLineListResponseStruct struct {
 	UserIds []uint `json:"userIds"`
 	Edit    *bool  `json:"edit"`
 	Name    string `json:"name"`
+	// 审批时限
+	RemindHours    uint `json:"remindHours"`
+	TimeoutHours   uint `json:"timeoutHours"`
+	TimeoutAction  uint `json:"timeoutAction"`
+	EscalateRoleId uint `json:"escalateRoleId"`
+}
+
+// 流程连线信息响应, 字段含义见models.SysWorkflowEdge
+type WorkflowEdgeListResponseStruct struct {
+	Id         uint   `json:"id"`
+	FlowId     uint   `json:"flowId"`
+	FromLineId uint   `json:"fromLineId"`
+	ToLineId   uint   `json:"toLineId"`
+	Condition  string `json:"condition"`
+	IsDefault  bool   `json:"isDefault"`
+}
+
+// 审批委托信息响应, 字段含义见models.SysWorkflowDelegate
+type WorkflowDelegateListResponseStruct struct {
+	Id                   uint             `json:"id"`
+	UserId               uint             `json:"userId"`
+	Username             string           `json:"username"`
+	UserNickname         string           `json:"userNickname"`
+	DelegateUserId       uint             `json:"delegateUserId"`
+	DelegateUsername     string           `json:"delegateUsername"`
+	DelegateUserNickname string           `json:"delegateUserNickname"`
+	FlowId               uint             `json:"flowId"`
+	FlowName             string           `json:"flowName"`
+	StartAt              int64            `json:"startAt"`
+	EndAt                int64            `json:"endAt"`
+	Reason               string           `json:"reason"`
+	Active               bool             `json:"active"` // 是否当前生效
+	Creator              string           `json:"creator"`
+	CreatedAt            models.LocalTime `json:"createdAt"`
 }
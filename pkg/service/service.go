@@ -1,6 +1,7 @@
 package service
 
 import (
+	"gin-web/models"
 	"gin-web/pkg/global"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
@@ -15,8 +16,41 @@ type MysqlService struct {
 func New(c *gin.Context) MysqlService {
 	// 获取事务对象
 	tx := global.GetTx(c)
+	if c != nil && tx != nil {
+		// 按当前请求的租户过滤数据
+		return NewWithTx(tx).WithTenant(global.GetTenantId(c))
+	}
+	return NewWithTx(tx)
+}
+
+// 使用指定事务初始化服务, 用于没有请求上下文的场景(如命令行)
+func NewWithTx(tx *gorm.DB) MysqlService {
 	return MysqlService{
 		tx: tx,
 		db: global.Mysql,
 	}
 }
+
+// 切换到指定租户, 用于没有请求上下文的场景(如命令行/创建租户)
+func (s MysqlService) WithTenant(tenantId uint) MysqlService {
+	return MysqlService{
+		tx: s.tx.Set(global.TenantIdKey, tenantId),
+		db: s.db.Set(global.TenantIdKey, tenantId),
+	}
+}
+
+// 当前租户编号, 未区分租户时(如启动任务)返回false
+func (s *MysqlService) TenantId() (uint, bool) {
+	if s.tx == nil {
+		return models.SysTenantDefault, false
+	}
+	v, ok := s.tx.Get(global.TenantIdKey)
+	tenantId, _ := v.(uint)
+	return tenantId, ok
+}
+
+// 当前租户的casbin域, 未区分租户时为默认租户
+func (s *MysqlService) domain() string {
+	tenantId, _ := s.TenantId()
+	return models.TenantDomain(tenantId)
+}
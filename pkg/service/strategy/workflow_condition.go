@@ -0,0 +1,32 @@
+package strategy
+
+import (
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/utils"
+	"github.com/jinzhu/gorm"
+)
+
+// 获取审批目标的字段(json字段名), 用于计算流程连线的流转条件
+func NewConditionTarget(tx *gorm.DB, targetCategory uint, targetId uint) (map[string]interface{}, error) {
+	var target interface{}
+	var err error
+	switch targetCategory {
+	case models.SysWorkflowTargetCategoryLeave:
+		var leave models.SysLeave
+		err = tx.Where("id = ?", targetId).First(&leave).Error
+		target = leave
+	case models.SysWorkflowTargetCategoryRoleGrant:
+		var grant models.SysRoleGrant
+		err = tx.Preload("Role").Where("id = ?", targetId).First(&grant).Error
+		target = grant
+	default:
+		return nil, fmt.Errorf("[NewConditionTarget]审批目标获取失败, 请检查参数targetCategory: %d", targetCategory)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	utils.Struct2StructByJson(target, &m)
+	return m, nil
+}
@@ -29,6 +29,61 @@ func (s *LeaveApproval) UpdateTarget() error {
 	return err
 }
 
+// 临时授权审批
+type RoleGrantApproval struct {
+	tx       *gorm.DB
+	targetId uint
+	lastLog  models.SysWorkflowLog
+}
+
+// 工作流状态对应的临时授权状态及审计操作
+var roleGrantTransitions = map[uint]struct {
+	status uint
+	action string
+}{
+	models.SysWorkflowLogStateSubmit: {models.SysRoleGrantStatusPending, models.SysRoleGrantActionCreate},
+	models.SysWorkflowLogStateDeny:   {models.SysRoleGrantStatusDenied, models.SysRoleGrantActionDeny},
+	models.SysWorkflowLogStateCancel: {models.SysRoleGrantStatusCanceled, models.SysRoleGrantActionCancel},
+	models.SysWorkflowLogStateEnd:    {models.SysRoleGrantStatusApproved, models.SysRoleGrantActionApprove},
+}
+
+func (s *RoleGrantApproval) UpdateTarget() error {
+	transition, ok := roleGrantTransitions[*s.lastLog.Status]
+	if !ok {
+		return nil
+	}
+	var grant models.SysRoleGrant
+	err := s.tx.Where("id = ?", s.targetId).First(&grant).Error
+	if err != nil {
+		return err
+	}
+	// 已撤销/已过期/已生效的授权不再受审批流程影响
+	if grant.Status == transition.status || grant.Status == models.SysRoleGrantStatusApproved ||
+		grant.Status == models.SysRoleGrantStatusRevoked || grant.Status == models.SysRoleGrantStatusExpired {
+		return nil
+	}
+	// 更新授权状态
+	err = s.tx.Model(&grant).Update("status", transition.status).Error
+	if err != nil {
+		return err
+	}
+	// 记录审计日志, 重新提交时操作人为提交人
+	operatorId := s.lastLog.ApprovalUserId
+	if *s.lastLog.Status == models.SysWorkflowLogStateSubmit {
+		operatorId = s.lastLog.SubmitUserId
+	}
+	var operator models.SysUser
+	s.tx.Where("id = ?", operatorId).First(&operator)
+	return s.tx.Create(&models.SysRoleGrantLog{
+		GrantId:  grant.Id,
+		UserId:   grant.UserId,
+		RoleId:   grant.RoleId,
+		Action:   transition.action,
+		Operator: operator.Nickname + operator.Username,
+		Detail:   s.lastLog.ApprovalOpinion,
+	}).Error
+}
+
 // 策略类
 type AfterTransitionContext struct {
 	Strategy AfterTransitionStrategy
@@ -45,6 +100,13 @@ func NewAfterTransitionContext(tx *gorm.DB, targetCategory uint, targetId uint,
 			lastLog:  lastLog,
 		}
 		break
+	case models.SysWorkflowTargetCategoryRoleGrant:
+		ctx.Strategy = &RoleGrantApproval{
+			tx:       tx,
+			targetId: targetId,
+			lastLog:  lastLog,
+		}
+		break
 	default:
 		return nil, fmt.Errorf("[NewAfterTransitionContext]策略获取失败, 请检查参数targetCategory: %d", targetCategory)
 	}
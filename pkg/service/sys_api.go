@@ -39,6 +39,9 @@ func (s *MysqlService) GetApis(req *request.ApiListRequestStruct) ([]models.SysA
 	if creator != "" {
 		query = query.Where("creator LIKE ?", fmt.Sprintf("%%%s%%", creator))
 	}
+	if req.Stale != nil {
+		query = query.Where("stale = ?", *req.Stale)
+	}
 	// 查询条数
 	err = query.Find(&list).Count(&req.PageInfo.Total).Error
 	if err == nil {
@@ -55,21 +58,23 @@ func (s *MysqlService) GetApis(req *request.ApiListRequestStruct) ([]models.SysA
 }
 
 // 根据权限编号获取以api分类分组的权限接口
-func (s *MysqlService) GetAllApiGroupByCategoryByRoleId(roleId uint) ([]response.ApiGroupByCategoryResponseStruct, []uint, error) {
+func (s *MysqlService) GetAllApiGroupByCategoryByRoleId(roleId uint) ([]response.ApiGroupByCategoryResponseStruct, []uint, []uint, error) {
 	// 接口树
 	tree := make([]response.ApiGroupByCategoryResponseStruct, 0)
 	// 有权限访问的id列表
 	accessIds := make([]uint, 0)
+	// 拒绝访问的id列表
+	denyIds := make([]uint, 0)
 	allApi := make([]models.SysApi, 0)
 	// 查询全部api
 	err := s.tx.Find(&allApi).Error
 	if err != nil {
-		return tree, accessIds, err
+		return tree, accessIds, denyIds, err
 	}
 	// 查询当前角色拥有api访问权限的casbin规则
 	casbins, err := s.GetCasbinListByRoleId(roleId)
 	if err != nil {
-		return tree, accessIds, err
+		return tree, accessIds, denyIds, err
 	}
 
 	// 通过分类进行分组归纳
@@ -78,17 +83,25 @@ func (s *MysqlService) GetAllApiGroupByCategoryByRoleId(roleId uint) ([]response
 		path := api.Path
 		method := api.Method
 		access := false
+		deny := false
 		for _, casbin := range casbins {
-			// 该api有权限
-			if path == casbin.V1 && method == casbin.V2 {
-				access = true
-				break
+			if path == casbin.V2 && method == casbin.V3 {
+				if casbin.V4 == models.SysCasbinEffectDeny {
+					// 该api被拒绝
+					deny = true
+				} else {
+					// 该api有权限
+					access = true
+				}
 			}
 		}
 		// 加入权限集合
 		if access {
 			accessIds = append(accessIds, api.Id)
 		}
+		if deny {
+			denyIds = append(denyIds, api.Id)
+		}
 		// 生成接口树
 		existIndex := -1
 		children := make([]response.ApiListResponseStruct, 0)
@@ -116,7 +129,7 @@ func (s *MysqlService) GetAllApiGroupByCategoryByRoleId(roleId uint) ([]response
 			})
 		}
 	}
-	return tree, accessIds, err
+	return tree, accessIds, denyIds, err
 }
 
 // 创建接口
@@ -164,6 +177,9 @@ func (s *MysqlService) UpdateApiById(id uint, req gin.H) (err error) {
 	oldApi := api
 	// 更新指定列
 	err = query.Updates(m).Error
+	if err != nil {
+		return
+	}
 
 	var diff models.SysApi
 	// 对比api发生了哪些变化
@@ -171,30 +187,36 @@ func (s *MysqlService) UpdateApiById(id uint, req gin.H) (err error) {
 
 	if diff.Path != "" || diff.Method != "" {
 		// path或method变化, 需要更新casbin规则
-		// 查找当前接口都有哪些角色在使用
-		oldCasbins := s.GetRoleCasbins(models.SysRoleCasbin{
-			Path:   oldApi.Path,
-			Method: oldApi.Method,
-		})
-		if len(oldCasbins) > 0 {
-			keywords := make([]string, 0)
-			for _, oldCasbin := range oldCasbins {
-				keywords = append(keywords, oldCasbin.Keyword)
-			}
-			// 删除旧规则, 添加新规则
-			s.BatchDeleteRoleCasbins(oldCasbins)
-			// 构建新casbin规则
-			newCasbins := make([]models.SysRoleCasbin, 0)
-			for _, keyword := range keywords {
-				newCasbins = append(newCasbins, models.SysRoleCasbin{
-					Keyword: keyword,
-					Path:    api.Path,
-					Method:  api.Method,
-				})
-			}
-			// 批量创建
-			_, err = s.BatchCreateRoleCasbins(newCasbins)
+		// 查找全部租户中使用当前接口的规则
+		e, err := s.Casbin()
+		if err != nil {
+			return err
+		}
+		oldRules := apiCasbinRules(e, oldApi)
+		if len(oldRules) == 0 {
+			return nil
+		}
+		// 构建新casbin规则(保留角色/租户域/策略效果)
+		newRules := make([][]string, 0)
+		for _, oldRule := range oldRules {
+			newRule := append([]string{}, oldRule...)
+			newRule[2] = api.Path
+			newRule[3] = api.Method
+			newRules = append(newRules, newRule)
+		}
+		// 删除旧规则, 添加新规则
+		ok, err := e.RemovePolicies(oldRules)
+		if err == nil && !ok {
+			err = errors.New("删除接口casbin规则失败")
 		}
+		if err != nil {
+			return err
+		}
+		ok, err = e.AddPolicies(newRules)
+		if err == nil && !ok {
+			err = errors.New("创建接口casbin规则失败, 新接口可能已存在相同规则")
+		}
+		return err
 	}
 	return
 }
@@ -206,15 +228,130 @@ func (s *MysqlService) DeleteApiByIds(ids []uint) (err error) {
 	if query.Error != nil {
 		return
 	}
-	// 查找当前接口都有哪些角色在使用
-	casbins := make([]models.SysRoleCasbin, 0)
+	// 查找全部租户中使用当前接口的规则
+	rules := make([][]string, 0)
+	e, err := s.Casbin()
+	if err != nil {
+		return
+	}
 	for _, api := range list {
-		casbins = append(casbins, s.GetRoleCasbins(models.SysRoleCasbin{
-			Path:   api.Path,
-			Method: api.Method,
-		})...)
+		rules = append(rules, apiCasbinRules(e, api)...)
+	}
+	if len(rules) > 0 {
+		// 删除所有规则
+		ok, err := e.RemovePolicies(rules)
+		if err == nil && !ok {
+			err = errors.New("删除接口casbin规则失败")
+		}
+		if err != nil {
+			return err
+		}
 	}
-	// 删除所有规则
-	s.BatchDeleteRoleCasbins(casbins)
 	return query.Delete(models.SysApi{}).Error
 }
+
+// 查询接口在全部租户域中的casbin规则, 接口为各租户共享, 不能只处理当前租户
+func apiCasbinRules(e *CasbinEnforcer, api models.SysApi) [][]string {
+	// v2=path, v3=method, 不限制v0(角色)/v1(租户域)
+	return e.GetFilteredPolicy(2, api.Path, api.Method)
+}
+
+// 不鉴权的路由分组: 公共路由(如重置密码)与基础路由(登录/刷新令牌/SSO回调), 任何人可访问, 无需生成接口
+var routeApiSkipCategories = map[string]bool{
+	"public": true,
+	"base":   true,
+}
+
+// 根据路由表生成接口, 路径去掉url前缀, 分类取v1后的路由分组(如/v1/user/list属于user)
+// 只处理v1分组, 跳过不鉴权的路由分组
+func RouteApis(routes gin.RoutesInfo, urlPathPrefix string) []models.SysApi {
+	apis := make([]models.SysApi, 0)
+	for _, route := range routes {
+		path := route.Path
+		if urlPathPrefix != "" {
+			path = strings.TrimPrefix(path, "/"+urlPathPrefix)
+		}
+		segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+		if len(segments) < 3 || segments[0] != "v1" || routeApiSkipCategories[segments[1]] {
+			continue
+		}
+		// 说明默认使用处理函数名, 如gin-web/api/v1.GetUsers取GetUsers
+		desc := route.Handler
+		if index := strings.LastIndex(desc, "."); index >= 0 {
+			desc = desc[index+1:]
+		}
+		apis = append(apis, models.SysApi{
+			Method:   route.Method,
+			Path:     path,
+			Category: segments[1],
+			Desc:     desc,
+		})
+	}
+	return apis
+}
+
+// 根据路由表同步接口: 新增不存在的接口, 更新分类, 标记路由已失效的接口
+// roleKeyword不为空时将新增接口授权给该角色, 返回新增接口与失效接口
+func (s *MysqlService) SyncApis(routeApis []models.SysApi, roleKeyword string) ([]models.SysApi, []models.SysApi, error) {
+	created := make([]models.SysApi, 0)
+	stale := make([]models.SysApi, 0)
+	oldApis := make([]models.SysApi, 0)
+	err := s.tx.Find(&oldApis).Error
+	if err != nil {
+		return created, stale, err
+	}
+	oldMap := make(map[string]models.SysApi, 0)
+	for _, api := range oldApis {
+		oldMap[api.Method+" "+api.Path] = api
+	}
+	routeMap := make(map[string]bool, 0)
+	for _, api := range routeApis {
+		key := api.Method + " " + api.Path
+		if routeMap[key] {
+			continue
+		}
+		routeMap[key] = true
+		oldApi, ok := oldMap[key]
+		if !ok {
+			api.Creator = "系统自动同步"
+			err = s.tx.Create(&api).Error
+			if err != nil {
+				return created, stale, err
+			}
+			created = append(created, api)
+			continue
+		}
+		if oldApi.Category != api.Category || oldApi.Stale {
+			// 路由重新出现或分组变化
+			err = s.tx.Model(&oldApi).Updates(map[string]interface{}{
+				"category": api.Category,
+				"stale":    false,
+			}).Error
+			if err != nil {
+				return created, stale, err
+			}
+		}
+	}
+	for _, api := range oldApis {
+		// 不鉴权的路由分组不参与同步, 已有接口保持不变
+		if routeMap[api.Method+" "+api.Path] || routeApiSkipCategories[api.Category] {
+			continue
+		}
+		stale = append(stale, api)
+		if !api.Stale {
+			// 只做标记, 是否删除由管理员决定
+			err = s.tx.Model(&api).Update("stale", true).Error
+			if err != nil {
+				return created, stale, err
+			}
+		}
+	}
+	if roleKeyword != "" && len(created) > 0 {
+		createIds := make([]uint, 0)
+		for _, api := range created {
+			createIds = append(createIds, api.Id)
+		}
+		err = s.updateRoleApis(roleKeyword, createIds, nil, models.SysCasbinEffectAllow)
+	}
+	return created, stale, err
+}
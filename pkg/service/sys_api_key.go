@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"gin-web/pkg/utils"
+	"time"
+)
+
+// API密钥明文前缀, 便于在日志/代码仓库中识别泄露的密钥
+const ApiKeyPrefix = "gwk_"
+
+// 获取用户的API密钥
+func (s *MysqlService) GetApiKeysByUserId(userId uint) ([]models.SysApiKey, error) {
+	keys := make([]models.SysApiKey, 0)
+	err := s.tx.Preload("Apis").Where("user_id = ?", userId).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// 创建API密钥, 返回密钥明文(仅返回一次, 数据库保存摘要)
+func (s *MysqlService) CreateApiKey(user models.SysUser, req *request.CreateApiKeyRequestStruct) (string, error) {
+	apis := make([]models.SysApi, 0)
+	err := s.tx.Where("id IN (?)", req.ApiIds).Find(&apis).Error
+	if err != nil {
+		return "", err
+	}
+	if len(apis) == 0 || len(apis) != len(req.ApiIds) {
+		return "", errors.New("权限范围包含不存在的接口")
+	}
+	// 权限范围不能超出所属用户角色的casbin策略
+	e, err := s.Casbin()
+	if err != nil {
+		return "", err
+	}
+	for _, api := range apis {
+		pass, _ := e.EnforceAny(user.RoleKeywords(), s.domain(), api.Path, api.Method)
+		if !pass {
+			return "", errors.New("权限范围超出当前角色已授权的接口: " + api.Method + " " + api.Path)
+		}
+	}
+	key := ApiKeyPrefix + utils.GenRandomToken(24)
+	apiKey := models.SysApiKey{
+		Name:    req.Name,
+		KeyHash: utils.Sha256(key),
+		Prefix:  key[:len(ApiKeyPrefix)+6],
+		UserId:  user.Id,
+		Creator: req.Creator,
+		Apis:    apis,
+	}
+	if req.ExpireDays > 0 {
+		apiKey.ExpiresAt = time.Now().Add(time.Duration(req.ExpireDays) * 24 * time.Hour).Unix()
+	}
+	err = s.tx.Create(&apiKey).Error
+	return key, err
+}
+
+// 批量删除用户的API密钥(只能删除自己的密钥)
+func (s *MysqlService) DeleteApiKeyByIds(userId uint, ids []uint) (err error) {
+	return s.tx.Where("user_id = ? AND id IN (?)", userId, ids).Delete(models.SysApiKey{}).Error
+}
+
+// 校验API密钥, 通过返回所属用户(含角色)以及密钥(含权限范围)
+func (s *MysqlService) ApiKeyCheck(key string, ip string) (models.SysUser, models.SysApiKey, error) {
+	var user models.SysUser
+	var apiKey models.SysApiKey
+	if key == "" {
+		return user, apiKey, errors.New("API密钥不能为空")
+	}
+	notFound := s.tx.Preload("Apis").Where("key_hash = ?", utils.Sha256(key)).First(&apiKey).RecordNotFound()
+	if notFound {
+		return user, apiKey, errors.New("API密钥不存在")
+	}
+	if apiKey.IsExpired() {
+		return user, apiKey, errors.New("API密钥已过期")
+	}
+	err := s.tx.Preload("Role").Preload("Roles").Where("id = ?", apiKey.UserId).First(&user).Error
+	if err != nil {
+		return user, apiKey, err
+	}
+	if user.Status != nil && !*user.Status {
+		return user, apiKey, errors.New("API密钥所属用户已被禁用")
+	}
+	// 记录最近使用情况, 每分钟最多写一次, 避免高频调用时频繁写库
+	// 接口请求失败时也需要记录, 因此使用无事务实例
+	now := time.Now().Unix()
+	if now-apiKey.LastUsedAt >= 60 || apiKey.LastUsedIp != ip {
+		err = s.db.Model(&models.SysApiKey{}).Where("id = ?", apiKey.Id).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error
+		if err != nil {
+			global.Log.Warn("[ApiKeyCheck]", err)
+		}
+	}
+	return user, apiKey, nil
+}
@@ -2,7 +2,10 @@ package service
 
 import (
 	"fmt"
+	"gin-web/models"
 	"gin-web/tests"
+	"github.com/gin-gonic/gin"
+	"reflect"
 	"testing"
 )
 
@@ -10,10 +13,47 @@ func TestGetRoleCategoryApisByRoleId(t *testing.T) {
 	tests.InitTestEnv()
 
 	s := New(nil)
-	m1, a1, _ := s.GetAllApiGroupByCategoryByRoleId(3)
-	m2, a2, _ := s.GetAllApiGroupByCategoryByRoleId(1)
-	fmt.Println(m1, a1)
-	fmt.Println(m2, a2)
+	m1, a1, d1, _ := s.GetAllApiGroupByCategoryByRoleId(3)
+	m2, a2, d2, _ := s.GetAllApiGroupByCategoryByRoleId(1)
+	fmt.Println(m1, a1, d1)
+	fmt.Println(m2, a2, d2)
 
 	defer s.tx.Close()
 }
+
+func TestRouteApis(t *testing.T) {
+	routes := gin.RoutesInfo{
+		{Method: "GET", Path: "/api/ping", Handler: "gin-web/api.Ping"},
+		{Method: "POST", Path: "/api/v1/public/pwd/reset", Handler: "gin-web/api/v1.ResetPwd"},
+		{Method: "POST", Path: "/api/v1/base/login", Handler: "github.com/appleboy/gin-jwt/v2.(*GinJWTMiddleware).LoginHandler-fm"},
+		{Method: "POST", Path: "/api/v1/base/refresh_token", Handler: "gin-web/middleware.RefreshTokenHandler.func1"},
+		{Method: "POST", Path: "/api/v1/base/oidc/callback", Handler: "gin-web/middleware.OidcCallbackHandler.func1"},
+		{Method: "GET", Path: "/api/v1/user/list", Handler: "gin-web/api/v1.GetUsers"},
+		{Method: "PATCH", Path: "/api/v1/role/update/:roleId", Handler: "gin-web/api/v1.UpdateRoleById"},
+	}
+	tests := []struct {
+		name          string
+		urlPathPrefix string
+		routes        gin.RoutesInfo
+		want          []models.SysApi
+	}{
+		{"case1", "api", routes, []models.SysApi{
+			{Method: "GET", Path: "/v1/user/list", Category: "user", Desc: "GetUsers"},
+			{Method: "PATCH", Path: "/v1/role/update/:roleId", Category: "role", Desc: "UpdateRoleById"},
+		}},
+		// 前缀不一致时不会匹配v1分组
+		{"case2", "", routes, []models.SysApi{}},
+		{"case3", "", gin.RoutesInfo{
+			{Method: "DELETE", Path: "/v1/dept/delete/batch", Handler: "main.func1"},
+		}, []models.SysApi{
+			{Method: "DELETE", Path: "/v1/dept/delete/batch", Category: "dept", Desc: "func1"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RouteApis(tt.routes, tt.urlPathPrefix); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RouteApis() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
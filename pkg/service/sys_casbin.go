@@ -1,49 +1,26 @@
 package service
 
 import (
-	"fmt"
+	"errors"
 	"gin-web/models"
 	"gin-web/pkg/global"
-	"github.com/casbin/casbin/v2"
-	"github.com/casbin/casbin/v2/model"
-	gormadapter "github.com/casbin/gorm-adapter/v2"
+	"gin-web/pkg/request"
+	"gin-web/pkg/response"
+	"gin-web/pkg/utils"
+	"strings"
 )
 
-// 获取casbin策略管理器
-func (s *MysqlService) Casbin() (*casbin.Enforcer, error) {
-	// 初始化数据库适配器, 添加自定义表前缀, casbin不使用事务管理, 因为他内部使用到事务, 重复用会导致冲突
-	// casbin默认表名casbin_rule, 本项目添加了自定义表前缀以及sys, 因此使用prefix_sys_cabin_rule
-	a, err := gormadapter.NewAdapterByDBUsePrefix(s.db, fmt.Sprintf("%ssys_", global.Conf.Mysql.TablePrefix))
-	if err != nil {
-		return nil, err
-	}
-	// 读取配置文件
-	config, err := global.ConfBox.Find(global.Conf.Casbin.ModelPath)
-	cabinModel := model.NewModel()
-	// 从字符串中加载casbin配置
-	err = cabinModel.LoadModelFromText(string(config))
-	if err != nil {
-		return nil, err
-	}
-	e, err := casbin.NewEnforcer(cabinModel, a)
-	if err != nil {
-		return nil, err
-	}
-	// 加载策略
-	err = e.LoadPolicy()
-	return e, err
-}
-
 // 获取符合条件的casbin规则, 按角色
 func (s *MysqlService) GetRoleCasbins(c models.SysRoleCasbin) []models.SysRoleCasbin {
 	e, _ := s.Casbin()
-	policies := e.GetFilteredPolicy(0, c.Keyword, c.Path, c.Method)
+	policies := e.GetFilteredPolicy(0, c.Keyword, s.domain(), c.Path, c.Method, c.Effect)
 	cs := make([]models.SysRoleCasbin, 0)
 	for _, policy := range policies {
 		cs = append(cs, models.SysRoleCasbin{
 			Keyword: policy[0],
-			Path:    policy[1],
-			Method:  policy[2],
+			Path:    policy[2],
+			Method:  policy[3],
+			Effect:  policy[4],
 		})
 	}
 	return cs
@@ -52,7 +29,7 @@ func (s *MysqlService) GetRoleCasbins(c models.SysRoleCasbin) []models.SysRoleCa
 // 创建一条casbin规则, 按角色
 func (s *MysqlService) CreateRoleCasbin(c models.SysRoleCasbin) (bool, error) {
 	e, _ := s.Casbin()
-	return e.AddPolicy(c.Keyword, c.Path, c.Method)
+	return e.AddPolicy(c.Keyword, s.domain(), c.Path, c.Method, c.GetEffect())
 }
 
 // 批量创建多条casbin规则, 按角色
@@ -63,8 +40,10 @@ func (s *MysqlService) BatchCreateRoleCasbins(cs []models.SysRoleCasbin) (bool,
 	for _, c := range cs {
 		rules = append(rules, []string{
 			c.Keyword,
+			s.domain(),
 			c.Path,
 			c.Method,
+			c.GetEffect(),
 		})
 	}
 	return e.AddPolicies(rules)
@@ -73,7 +52,7 @@ func (s *MysqlService) BatchCreateRoleCasbins(cs []models.SysRoleCasbin) (bool,
 // 删除一条casbin规则, 按角色
 func (s *MysqlService) DeleteRoleCasbin(c models.SysRoleCasbin) (bool, error) {
 	e, _ := s.Casbin()
-	return e.RemovePolicy(c.Keyword, c.Path, c.Method)
+	return e.RemovePolicy(c.Keyword, s.domain(), c.Path, c.Method, c.GetEffect())
 }
 
 // 批量删除多条casbin规则, 按角色
@@ -84,8 +63,10 @@ func (s *MysqlService) BatchDeleteRoleCasbins(cs []models.SysRoleCasbin) (bool,
 	for _, c := range cs {
 		rules = append(rules, []string{
 			c.Keyword,
+			s.domain(),
 			c.Path,
 			c.Method,
+			c.GetEffect(),
 		})
 	}
 	return e.RemovePolicies(rules)
@@ -100,15 +81,87 @@ func (s *MysqlService) GetCasbinListByRoleId(roleId uint) ([]models.SysCasbin, e
 		return casbins, err
 	}
 	e, _ := s.Casbin()
-	// 查询符合字段v0=role.Keyword所有casbin规则
-	list := e.GetFilteredPolicy(0, role.Keyword)
+	// 查询符合字段v0=role.Keyword, v1=当前租户域的所有casbin规则
+	list := e.GetFilteredPolicy(0, role.Keyword, s.domain())
 	for _, v := range list {
 		casbins = append(casbins, models.SysCasbin{
 			PType: "p",
 			V0:    v[0],
 			V1:    v[1],
 			V2:    v[2],
+			V3:    v[3],
+			V4:    v[4],
 		})
 	}
 	return casbins, nil
 }
+
+// 解释权限检查结果, 用于排查无权访问(Forbidden)的原因
+func (s *MysqlService) ExplainPermission(req *request.PermissionExplainRequestStruct) (response.PermissionExplainResponseStruct, error) {
+	var resp response.PermissionExplainResponseStruct
+	// 与CasbinMiddleware一致: 清除path前缀, 请求方式大写
+	resp.Path = strings.Replace(req.Path, "/"+global.Conf.System.UrlPathPrefix, "", 1)
+	resp.Method = strings.ToUpper(req.Method)
+	if req.UserId > 0 {
+		user, err := s.GetUserById(req.UserId)
+		if err != nil {
+			return resp, errors.New("用户不存在")
+		}
+		resp.Subjects = user.RoleKeywords()
+	} else if req.RoleId > 0 {
+		var role models.SysRole
+		err := s.tx.Where("id = ?", req.RoleId).First(&role).Error
+		if err != nil {
+			return resp, errors.New("角色不存在")
+		}
+		resp.Subjects = []string{role.Keyword}
+	} else {
+		return resp, errors.New("用户或角色不能为空")
+	}
+	e, err := s.Casbin()
+	if err != nil {
+		return resp, err
+	}
+	resp.Allowed, err = e.EnforceAny(resp.Subjects, s.domain(), resp.Path, resp.Method)
+	if err != nil {
+		return resp, err
+	}
+	roles, rules := e.Explain(resp.Subjects, s.domain(), resp.Path, resp.Method)
+	resp.Roles = roles
+	// 查询全部接口, 与访问路径及策略对应
+	apis := make([]models.SysApi, 0)
+	err = s.tx.Find(&apis).Error
+	if err != nil {
+		return resp, err
+	}
+	for _, api := range apis {
+		if api.Method == resp.Method && casbinMatch(resp.Path, resp.Method, []string{"", "", api.Path, api.Method}) != "" {
+			resp.Api = new(response.ApiListResponseStruct)
+			utils.Struct2StructByJson(api, resp.Api)
+			// 优先精确匹配
+			if api.Path == resp.Path {
+				break
+			}
+		}
+	}
+	resp.Matched = make([]response.PermissionExplainPolicyStruct, 0)
+	for _, rule := range rules {
+		policy := response.PermissionExplainPolicyStruct{
+			Subject: rule.Subject,
+			Keyword: rule.Rule[0],
+			Path:    rule.Rule[2],
+			Method:  rule.Rule[3],
+			Effect:  rule.Effect(),
+			Matcher: rule.Matcher,
+		}
+		for _, api := range apis {
+			if api.Path == policy.Path && api.Method == policy.Method {
+				policy.Api = new(response.ApiListResponseStruct)
+				utils.Struct2StructByJson(api, policy.Api)
+				break
+			}
+		}
+		resp.Matched = append(resp.Matched, policy)
+	}
+	return resp, nil
+}
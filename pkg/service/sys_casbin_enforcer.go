@@ -0,0 +1,383 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/utils"
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/casbin/casbin/v2/util"
+	gormadapter "github.com/casbin/gorm-adapter/v2"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+	"strings"
+	"sync"
+)
+
+// 进程内共享的casbin策略管理器
+// 首次使用时从数据库加载策略, 之后的增删直接更新内存并写入数据库(增量), 不再每次请求重新加载
+// 开启redis时通过发布订阅通知其他实例重新加载策略
+type CasbinEnforcer struct {
+	// casbin v2.2.2的SyncedEnforcer没有批量增删接口, 这里自行加锁
+	lock sync.RWMutex
+	e    *casbin.Enforcer
+	// 检查结果缓存(casbin每次检查都会遍历全部策略), 策略变更时清空
+	cacheLock sync.Mutex
+	cache     map[string]bool
+}
+
+// 检查结果缓存的最大条数, 超过后清空(路径中可能包含编号, 避免无限增长)
+const casbinCacheSize = 10000
+
+var casbinEnforcer struct {
+	sync.Mutex
+	e *CasbinEnforcer
+}
+
+// 当前实例编号, 用于忽略自己发出的策略变更通知
+var casbinInstanceId = uuid.NewV4().String()
+
+// 获取casbin策略管理器
+func (s *MysqlService) Casbin() (*CasbinEnforcer, error) {
+	casbinEnforcer.Lock()
+	defer casbinEnforcer.Unlock()
+	if casbinEnforcer.e != nil {
+		return casbinEnforcer.e, nil
+	}
+	// 初始化数据库适配器, 添加自定义表前缀, casbin不使用事务管理, 因为他内部使用到事务, 重复用会导致冲突
+	// casbin默认表名casbin_rule, 本项目添加了自定义表前缀以及sys, 因此使用prefix_sys_cabin_rule
+	a, err := gormadapter.NewAdapterByDBUsePrefix(s.db, fmt.Sprintf("%ssys_", global.Conf.Mysql.TablePrefix))
+	if err != nil {
+		return nil, err
+	}
+	err = migrateCasbinRules(s.db)
+	if err != nil {
+		return nil, err
+	}
+	e, err := NewCasbinEnforcer(a)
+	if err != nil {
+		return nil, err
+	}
+	if global.Conf.System.UseRedis {
+		err = e.setWatcher(newCasbinWatcher())
+		if err != nil {
+			return nil, err
+		}
+	}
+	casbinEnforcer.e = e
+	return e, nil
+}
+
+// 升级旧版本的策略, 加载前执行, 否则策略长度与模型不一致
+func migrateCasbinRules(db *gorm.DB) error {
+	rule := &models.SysCasbin{}
+	// 1. 旧版本的策略没有效果字段, 补充为允许
+	err := db.Model(rule).Where("p_type = ? AND (v3 = ? OR v3 IS NULL) AND (v4 = ? OR v4 IS NULL)", "p", "", "").Update("v3", models.SysCasbinEffectAllow).Error
+	if err != nil {
+		return err
+	}
+	// 2. 旧版本的策略没有域字段, 归属默认租户(mysql按从左到右的顺序赋值, 需从后往前移动字段)
+	err = db.Exec(
+		fmt.Sprintf("UPDATE %s SET v4 = v3, v3 = v2, v2 = v1, v1 = ? WHERE p_type = ? AND (v4 = ? OR v4 IS NULL)", rule.TableName()),
+		models.TenantDomain(models.SysTenantDefault), "p", "",
+	).Error
+	if err != nil {
+		return err
+	}
+	// 3. 旧版本的角色继承关系没有域字段, 归属默认租户
+	return db.Model(rule).Where("p_type = ? AND (v2 = ? OR v2 IS NULL)", "g", "").Update("v2", models.TenantDomain(models.SysTenantDefault)).Error
+}
+
+// 创建casbin策略管理器并加载策略
+func NewCasbinEnforcer(a persist.Adapter) (*CasbinEnforcer, error) {
+	m, err := loadCasbinModel()
+	if err != nil {
+		return nil, err
+	}
+	// 创建时会自动加载策略
+	e, err := casbin.NewEnforcer(m, a)
+	if err != nil {
+		return nil, err
+	}
+	return &CasbinEnforcer{
+		e:     e,
+		cache: make(map[string]bool),
+	}, nil
+}
+
+// 读取casbin模型配置
+func loadCasbinModel() (model.Model, error) {
+	config, err := global.ConfBox.Find(global.Conf.Casbin.ModelPath)
+	if err != nil {
+		return nil, err
+	}
+	m := model.NewModel()
+	// 从字符串中加载casbin配置
+	err = m.LoadModelFromText(string(config))
+	return m, err
+}
+
+// 设置策略变更通知, 收到其他实例的通知后重新加载策略
+func (c *CasbinEnforcer) setWatcher(w persist.Watcher) error {
+	err := c.e.SetWatcher(w)
+	if err != nil {
+		return err
+	}
+	// 覆盖casbin默认的回调, 重新加载时需要加锁
+	return w.SetUpdateCallback(func(string) {
+		err := c.LoadPolicy()
+		if err != nil {
+			global.Log.Warn("[CasbinEnforcer]重新加载策略失败: ", err)
+		}
+	})
+}
+
+// 检查策略
+func (c *CasbinEnforcer) Enforce(rvals ...interface{}) (bool, error) {
+	key := casbinCacheKey(rvals)
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	c.cacheLock.Lock()
+	pass, ok := c.cache[key]
+	c.cacheLock.Unlock()
+	if ok {
+		return pass, nil
+	}
+	pass, err := c.e.Enforce(rvals...)
+	if err != nil {
+		return pass, err
+	}
+	c.cacheLock.Lock()
+	if len(c.cache) >= casbinCacheSize {
+		c.cache = make(map[string]bool)
+	}
+	c.cache[key] = pass
+	c.cacheLock.Unlock()
+	return pass, nil
+}
+
+// 检查多个角色(dom为租户域), 任一角色(含继承的角色)允许且所有角色都未拒绝才能通过(拒绝优先)
+func (c *CasbinEnforcer) EnforceAny(subs []string, dom string, obj string, act string) (bool, error) {
+	pass := false
+	for _, sub := range subs {
+		ok, err := c.Enforce(sub, dom, obj, act)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			pass = true
+			break
+		}
+	}
+	if !pass || len(subs) == 1 {
+		return pass, nil
+	}
+	// 单个角色的检查结果已包含该角色的拒绝策略, 多个角色时还需确认其他角色没有拒绝
+	_, rules := c.Explain(subs, dom, obj, act)
+	for _, rule := range rules {
+		if rule.Effect() == models.SysCasbinEffectDeny {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// 匹配到的策略
+type CasbinExplainRule struct {
+	Subject string   // 请求的角色
+	Rule    []string // 策略(角色关键字/租户域/资源名称/请求类型/策略效果), 角色可能是请求角色继承的角色
+	Matcher string   // 资源名称的匹配方式
+}
+
+// 策略效果
+func (r CasbinExplainRule) Effect() string {
+	if len(r.Rule) > 4 && r.Rule[4] == models.SysCasbinEffectDeny {
+		return models.SysCasbinEffectDeny
+	}
+	return models.SysCasbinEffectAllow
+}
+
+// 解释检查结果: 返回参与匹配的全部角色(含继承)以及匹配到的策略
+func (c *CasbinEnforcer) Explain(subs []string, dom string, obj string, act string) ([]string, []CasbinExplainRule) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	roles := make([]string, 0)
+	rules := make([]CasbinExplainRule, 0)
+	for _, sub := range subs {
+		implicit, _ := c.e.GetImplicitRolesForUser(sub, dom)
+		for _, role := range append([]string{sub}, implicit...) {
+			if !utils.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+			for _, rule := range c.e.GetFilteredPolicy(0, role, dom) {
+				if matcher := casbinMatch(obj, act, rule); matcher != "" {
+					rules = append(rules, CasbinExplainRule{
+						Subject: sub,
+						Rule:    rule,
+						Matcher: matcher,
+					})
+				}
+			}
+		}
+	}
+	return roles, rules
+}
+
+// 按rbac_model.conf中的matchers匹配资源名称与请求类型, 返回匹配方式, 不匹配返回空
+func casbinMatch(obj string, act string, rule []string) string {
+	if len(rule) < 4 || (act != rule[3] && rule[3] != "*") {
+		return ""
+	}
+	if util.KeyMatch2(obj, rule[2]) {
+		return "keyMatch2"
+	}
+	if util.KeyMatch(obj, rule[2]) {
+		return "keyMatch"
+	}
+	return ""
+}
+
+// 获取符合条件的策略
+func (c *CasbinEnforcer) GetFilteredPolicy(fieldIndex int, fieldValues ...string) [][]string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.e.GetFilteredPolicy(fieldIndex, fieldValues...)
+}
+
+// 添加一条策略
+func (c *CasbinEnforcer) AddPolicy(params ...interface{}) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer c.clearCache()
+	ok, err := c.e.AddPolicy(params...)
+	return ok, c.reloadOnError(err)
+}
+
+// 批量添加策略
+func (c *CasbinEnforcer) AddPolicies(rules [][]string) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer c.clearCache()
+	ok, err := c.e.AddPolicies(rules)
+	return ok, c.reloadOnError(err)
+}
+
+// 删除一条策略
+func (c *CasbinEnforcer) RemovePolicy(params ...interface{}) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer c.clearCache()
+	ok, err := c.e.RemovePolicy(params...)
+	return ok, c.reloadOnError(err)
+}
+
+// 批量删除策略
+func (c *CasbinEnforcer) RemovePolicies(rules [][]string) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer c.clearCache()
+	ok, err := c.e.RemovePolicies(rules)
+	return ok, c.reloadOnError(err)
+}
+
+// 获取符合条件的角色继承关系(g, 子角色, 父角色, 租户域)
+func (c *CasbinEnforcer) GetFilteredGroupingPolicy(fieldIndex int, fieldValues ...string) [][]string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.e.GetFilteredGroupingPolicy(fieldIndex, fieldValues...)
+}
+
+// 获取角色自身及直接/间接继承的全部角色(去重), dom为租户域
+func (c *CasbinEnforcer) GetImplicitRoles(dom string, names ...string) []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	roles := make([]string, 0)
+	visited := make(map[string]bool)
+	for _, name := range names {
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		roles = append(roles, name)
+		implicit, _ := c.e.GetImplicitRolesForUser(name, dom)
+		for _, role := range implicit {
+			if !visited[role] {
+				visited[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+// 租户域dom中name1是否继承了name2(直接或间接, 自身视为继承)
+func (c *CasbinEnforcer) HasLink(name1 string, name2 string, dom string) (bool, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.e.GetRoleManager().HasLink(name1, name2, dom)
+}
+
+// 批量添加角色继承关系
+func (c *CasbinEnforcer) AddGroupingPolicies(rules [][]string) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer c.clearCache()
+	ok, err := c.e.AddGroupingPolicies(rules)
+	return ok, c.reloadOnError(err)
+}
+
+// 批量删除角色继承关系
+func (c *CasbinEnforcer) RemoveGroupingPolicies(rules [][]string) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer c.clearCache()
+	ok, err := c.e.RemoveGroupingPolicies(rules)
+	return ok, c.reloadOnError(err)
+}
+
+// 删除符合条件的角色继承关系
+func (c *CasbinEnforcer) RemoveFilteredGroupingPolicy(fieldIndex int, fieldValues ...string) (bool, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer c.clearCache()
+	ok, err := c.e.RemoveFilteredGroupingPolicy(fieldIndex, fieldValues...)
+	return ok, c.reloadOnError(err)
+}
+
+// 重新加载全部策略
+func (c *CasbinEnforcer) LoadPolicy() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	defer c.clearCache()
+	return c.e.LoadPolicy()
+}
+
+// 清空检查结果缓存(调用方需持有写锁)
+func (c *CasbinEnforcer) clearCache() {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	c.cache = make(map[string]bool)
+}
+
+// 检查结果缓存键
+func casbinCacheKey(rvals []interface{}) string {
+	items := make([]string, 0)
+	for _, v := range rvals {
+		items = append(items, fmt.Sprint(v))
+	}
+	return strings.Join(items, "\n")
+}
+
+// 写入数据库失败时内存中的策略已经变更, 需重新加载保持一致(调用方需持有写锁)
+func (c *CasbinEnforcer) reloadOnError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if loadErr := c.e.LoadPolicy(); loadErr != nil {
+		return errors.New(err.Error() + ", " + loadErr.Error())
+	}
+	return err
+}
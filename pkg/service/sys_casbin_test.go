@@ -0,0 +1,322 @@
+package service
+
+import (
+	"fmt"
+	"gin-web/pkg/global"
+	"github.com/casbin/casbin/v2"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/gobuffalo/packr"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// 准备casbin模型及策略文件(10个角色, 每个角色50条策略)
+func initCasbinBenchmark(b *testing.B) *fileadapter.Adapter {
+	global.ConfBox = packr.NewBox("../../conf")
+	global.Conf.Casbin.ModelPath = "rbac_model.conf"
+	f, err := ioutil.TempFile("", "casbin_policy_*.csv")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 50; j++ {
+			_, _ = fmt.Fprintf(f, "p, role%d, 0, /v1/resource%d/:id, GET, allow\n", i, j)
+		}
+	}
+	_ = f.Close()
+	b.Cleanup(func() {
+		_ = os.Remove(f.Name())
+	})
+	return fileadapter.NewAdapter(f.Name())
+}
+
+// 原实现: 每次请求重新解析模型并加载策略
+func BenchmarkCasbinRebuild(b *testing.B) {
+	a := initCasbinBenchmark(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m, err := loadCasbinModel()
+		if err != nil {
+			b.Fatal(err)
+		}
+		e, err := casbin.NewEnforcer(m, a)
+		if err != nil {
+			b.Fatal(err)
+		}
+		err = e.LoadPolicy()
+		if err != nil {
+			b.Fatal(err)
+		}
+		pass, _ := e.Enforce("role5", "0", "/v1/resource25/1", "GET")
+		if !pass {
+			b.Fatal("Enforce() = false, want true")
+		}
+	}
+}
+
+// 共享策略管理器: 只检查策略
+func BenchmarkCasbinCached(b *testing.B) {
+	a := initCasbinBenchmark(b)
+	e, err := NewCasbinEnforcer(a)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pass, _ := e.Enforce("role5", "0", "/v1/resource25/1", "GET")
+			if !pass {
+				b.Fatal("Enforce() = false, want true")
+			}
+		}
+	})
+}
+
+func TestCasbinEnforcer(t *testing.T) {
+	global.ConfBox = packr.NewBox("../../conf")
+	global.Conf.Casbin.ModelPath = "rbac_model.conf"
+	f, err := ioutil.TempFile("", "casbin_policy_*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+	// 文件适配器不支持自动保存, 增删只修改内存
+	e, err := NewCasbinEnforcer(fileadapter.NewAdapter(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		op   func() (bool, error)
+		obj  string
+		want bool
+	}{
+		{"case1", func() (bool, error) {
+			return e.AddPolicy("admin", "0", "/v1/user/list", "GET", "allow")
+		}, "/v1/user/list", true},
+		{"case2", func() (bool, error) {
+			return e.AddPolicies([][]string{{"admin", "0", "/v1/role/:id", "GET", "allow"}, {"admin", "0", "/v1/menu/*", "GET", "allow"}})
+		}, "/v1/role/1", true},
+		{"case3", func() (bool, error) {
+			return e.RemovePolicy("admin", "0", "/v1/user/list", "GET", "allow")
+		}, "/v1/user/list", false},
+		{"case4", func() (bool, error) {
+			return e.RemovePolicies([][]string{{"admin", "0", "/v1/role/:id", "GET", "allow"}})
+		}, "/v1/role/1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := tt.op()
+			if !ok || err != nil {
+				t.Errorf("op() = %v, %v", ok, err)
+				return
+			}
+			if got, _ := e.Enforce("admin", "0", tt.obj, "GET"); got != tt.want {
+				t.Errorf("Enforce() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCasbinEnforcer_Grouping(t *testing.T) {
+	global.ConfBox = packr.NewBox("../../conf")
+	global.Conf.Casbin.ModelPath = "rbac_model.conf"
+	f, err := ioutil.TempFile("", "casbin_policy_*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+	e, err := NewCasbinEnforcer(fileadapter.NewAdapter(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = e.AddPolicy("tester", "0", "/v1/user/list", "GET", "allow")
+	tests := []struct {
+		name string
+		op   func() (bool, error)
+		subs []string
+		want bool
+	}{
+		{"case1", func() (bool, error) {
+			return true, nil
+		}, []string{"admin"}, false},
+		{"case2", func() (bool, error) {
+			return e.AddGroupingPolicies([][]string{{"admin", "tester", "0"}})
+		}, []string{"admin"}, true},
+		// 间接继承
+		{"case3", func() (bool, error) {
+			return e.AddGroupingPolicies([][]string{{"guest", "admin", "0"}})
+		}, []string{"guest"}, true},
+		// 多个角色任一通过
+		{"case4", func() (bool, error) {
+			return e.RemoveGroupingPolicies([][]string{{"guest", "admin", "0"}})
+		}, []string{"guest", "admin"}, true},
+		{"case5", func() (bool, error) {
+			return e.RemoveFilteredGroupingPolicy(1, "tester")
+		}, []string{"guest", "admin"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := tt.op()
+			if !ok || err != nil {
+				t.Errorf("op() = %v, %v", ok, err)
+				return
+			}
+			if got, _ := e.EnforceAny(tt.subs, "0", "/v1/user/list", "GET"); got != tt.want {
+				t.Errorf("EnforceAny() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	_, _ = e.AddGroupingPolicies([][]string{{"admin", "tester", "0"}, {"guest", "admin", "0"}})
+	if got := e.GetImplicitRoles("0", "guest", "admin"); !reflect.DeepEqual(got, []string{"guest", "admin", "tester"}) {
+		t.Errorf("GetImplicitRoles() = %v", got)
+	}
+	if loop, _ := e.HasLink("tester", "guest", "0"); loop {
+		t.Errorf("HasLink() = %v, want false", loop)
+	}
+}
+
+func TestCasbinEnforcer_Explain(t *testing.T) {
+	global.ConfBox = packr.NewBox("../../conf")
+	global.Conf.Casbin.ModelPath = "rbac_model.conf"
+	f, err := ioutil.TempFile("", "casbin_policy_*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+	e, err := NewCasbinEnforcer(fileadapter.NewAdapter(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = e.AddPolicies([][]string{
+		{"tester", "0", "/v1/user/update/:userId", "PATCH", "allow"},
+		{"tester", "0", "/v1/menu*", "GET", "allow"},
+		{"admin", "0", "/v1/role/list", "*", "allow"},
+	})
+	_, _ = e.AddGroupingPolicies([][]string{{"admin", "tester", "0"}})
+	tests := []struct {
+		name     string
+		subs     []string
+		obj      string
+		act      string
+		roles    []string
+		matchers []string
+	}{
+		{"case1", []string{"tester"}, "/v1/user/update/1", "PATCH", []string{"tester"}, []string{"keyMatch2"}},
+		{"case2", []string{"tester"}, "/v1/menu/tree", "GET", []string{"tester"}, []string{"keyMatch"}},
+		{"case3", []string{"tester"}, "/v1/user/update/1", "GET", []string{"tester"}, []string{}},
+		{"case4", []string{"admin"}, "/v1/user/update/1", "PATCH", []string{"admin", "tester"}, []string{"keyMatch2"}},
+		{"case5", []string{"guest", "admin"}, "/v1/role/list", "DELETE", []string{"guest", "admin", "tester"}, []string{"keyMatch2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles, rules := e.Explain(tt.subs, "0", tt.obj, tt.act)
+			if !reflect.DeepEqual(roles, tt.roles) {
+				t.Errorf("Explain() roles = %v, want %v", roles, tt.roles)
+			}
+			matchers := make([]string, 0)
+			for _, rule := range rules {
+				matchers = append(matchers, rule.Matcher)
+			}
+			if !reflect.DeepEqual(matchers, tt.matchers) {
+				t.Errorf("Explain() matchers = %v, want %v", matchers, tt.matchers)
+			}
+			// 与检查结果一致
+			if pass, _ := e.EnforceAny(tt.subs, "0", tt.obj, tt.act); pass != (len(rules) > 0) {
+				t.Errorf("EnforceAny() = %v, matched %d rules", pass, len(rules))
+			}
+		})
+	}
+}
+
+func TestCasbinEnforcer_Deny(t *testing.T) {
+	global.ConfBox = packr.NewBox("../../conf")
+	global.Conf.Casbin.ModelPath = "rbac_model.conf"
+	f, err := ioutil.TempFile("", "casbin_policy_*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+	e, err := NewCasbinEnforcer(fileadapter.NewAdapter(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 测试可以访问/workflow下的全部接口, 删除除外
+	_, _ = e.AddPolicies([][]string{
+		{"tester", "0", "/v1/workflow/*", "*", "allow"},
+		{"tester", "0", "/v1/workflow/*", "DELETE", "deny"},
+		{"manager", "0", "/v1/workflow/delete/batch", "DELETE", "allow"},
+	})
+	_, _ = e.AddGroupingPolicies([][]string{{"admin", "tester", "0"}})
+	tests := []struct {
+		name string
+		subs []string
+		act  string
+		want bool
+	}{
+		{"case1", []string{"tester"}, "GET", true},
+		{"case2", []string{"tester"}, "DELETE", false},
+		// 继承的拒绝策略
+		{"case3", []string{"admin"}, "DELETE", false},
+		{"case4", []string{"manager"}, "DELETE", true},
+		// 多个角色时拒绝优先
+		{"case5", []string{"manager", "tester"}, "DELETE", false},
+		{"case6", []string{"manager", "tester"}, "GET", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := e.EnforceAny(tt.subs, "0", "/v1/workflow/delete/batch", tt.act); got != tt.want {
+				t.Errorf("EnforceAny() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCasbinEnforcer_Domain(t *testing.T) {
+	global.ConfBox = packr.NewBox("../../conf")
+	global.Conf.Casbin.ModelPath = "rbac_model.conf"
+	f, err := ioutil.TempFile("", "casbin_policy_*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+	e, err := NewCasbinEnforcer(fileadapter.NewAdapter(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不同租户的同名角色互不影响
+	_, _ = e.AddPolicies([][]string{
+		{"admin", "0", "/v1/user/list", "GET", "allow"},
+		{"tester", "1", "/v1/role/list", "GET", "allow"},
+	})
+	_, _ = e.AddGroupingPolicies([][]string{{"admin", "tester", "1"}})
+	tests := []struct {
+		name  string
+		dom   string
+		obj   string
+		want  bool
+		roles []string
+	}{
+		{"case1", "0", "/v1/user/list", true, []string{"admin"}},
+		{"case2", "1", "/v1/user/list", false, []string{"admin", "tester"}},
+		{"case3", "0", "/v1/role/list", false, []string{"admin"}},
+		{"case4", "1", "/v1/role/list", true, []string{"admin", "tester"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := e.EnforceAny([]string{"admin"}, tt.dom, tt.obj, "GET"); got != tt.want {
+				t.Errorf("EnforceAny() = %v, want %v", got, tt.want)
+			}
+			if got := e.GetImplicitRoles(tt.dom, "admin"); !reflect.DeepEqual(got, tt.roles) {
+				t.Errorf("GetImplicitRoles() = %v, want %v", got, tt.roles)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"gin-web/pkg/global"
+	"sync"
+)
+
+// 基于redis发布订阅的casbin策略变更通知(实现persist.Watcher)
+// 策略变更后发布当前实例编号, 其他实例收到后重新加载策略
+type casbinWatcher struct {
+	lock     sync.RWMutex
+	callback func(string)
+	channel  string
+}
+
+func newCasbinWatcher() *casbinWatcher {
+	w := &casbinWatcher{
+		channel: fmt.Sprintf("%s_casbin_policy_changed", global.Conf.Mysql.Database),
+	}
+	go w.subscribe()
+	return w
+}
+
+// 设置收到通知后的回调
+func (w *casbinWatcher) SetUpdateCallback(callback func(string)) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.callback = callback
+	return nil
+}
+
+// 发布策略变更通知
+func (w *casbinWatcher) Update() error {
+	err := global.Redis.Publish(w.channel, casbinInstanceId).Err()
+	if err != nil {
+		// 策略已写入数据库, 通知失败不影响当前请求, 其他实例需重启或等待下次通知
+		global.Log.Warn("[casbinWatcher]发布策略变更通知失败: ", err)
+	}
+	return nil
+}
+
+// 订阅其他实例的策略变更通知, 断线后go-redis会自动重连
+func (w *casbinWatcher) subscribe() {
+	pubsub := global.Redis.Subscribe(w.channel)
+	for msg := range pubsub.Channel() {
+		if msg.Payload == casbinInstanceId {
+			// 忽略自己发出的通知
+			continue
+		}
+		w.lock.RLock()
+		callback := w.callback
+		w.lock.RUnlock()
+		if callback != nil {
+			callback(msg.Payload)
+		}
+	}
+}
+
+func (w *casbinWatcher) Close() {
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"github.com/jinzhu/gorm"
+)
+
+// 获取用户的数据范围(合并全部角色及继承的角色)
+func (s *MysqlService) GetDataScope(user models.SysUser) (models.SysDataScope, error) {
+	roles, err := s.GetImplicitRoles(user.RoleIds())
+	if err != nil {
+		return models.SysDataScope{}, err
+	}
+	roleIds := make([]uint, 0)
+	for _, role := range roles {
+		roleIds = append(roleIds, role.Id)
+	}
+	if len(roleIds) > 0 {
+		// 加载自定义数据范围的部门
+		err = s.tx.Preload("Depts").Where("id IN (?)", roleIds).Find(&roles).Error
+		if err != nil {
+			return models.SysDataScope{}, err
+		}
+	}
+	depts := make([]models.SysDept, 0)
+	err = s.tx.Find(&depts).Error
+	return models.NewDataScope(user, roles, depts), err
+}
+
+// 按数据范围过滤查询, column为数据所属用户编号字段, scope为空时不限制
+func dataScopeQuery(scope *models.SysDataScope, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil || scope.All {
+			return db
+		}
+		if len(scope.DeptIds) == 0 {
+			return db.Where(fmt.Sprintf("%s = ?", column), scope.UserId)
+		}
+		// 本人数据或可见部门内用户的数据(基于当前db, 保留租户等设置)
+		users := db.New().
+			Table(new(models.SysUser).TableName()).
+			Select("id").
+			Where("dept_id IN (?) AND deleted_at IS NULL", scope.DeptIds)
+		// 子查询不会执行租户回调, 需要手动按租户过滤
+		if tenantId, ok := db.Get(global.TenantIdKey); ok {
+			users = users.Where("tenant_id = ?", tenantId)
+		}
+		return db.Where(fmt.Sprintf("%s = ? OR %s IN (?)", column, column), scope.UserId, users.SubQuery())
+	}
+}
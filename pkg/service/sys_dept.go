@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/request"
+	"gin-web/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// 获取部门树
+func (s *MysqlService) GetDepts() ([]models.SysDept, error) {
+	depts := make([]models.SysDept, 0)
+	// 查询所有部门
+	err := s.tx.Order("sort").Find(&depts).Error
+	// 生成部门树
+	return GenDeptTree(nil, depts), err
+}
+
+// 生成部门树
+func GenDeptTree(parent *models.SysDept, depts []models.SysDept) []models.SysDept {
+	tree := make([]models.SysDept, 0)
+	// parentId默认为0, 表示根部门
+	var parentId uint
+	if parent != nil {
+		parentId = parent.Id
+	}
+
+	for _, dept := range depts {
+		// 父部门编号一致
+		if dept.ParentId == parentId {
+			// 递归获取子部门
+			dept.Children = GenDeptTree(&dept, depts)
+			// 加入部门树
+			tree = append(tree, dept)
+		}
+	}
+	return tree
+}
+
+// 创建部门
+func (s *MysqlService) CreateDept(req *request.CreateDeptRequestStruct) (err error) {
+	var dept models.SysDept
+	utils.Struct2StructByJson(req, &dept)
+	// 创建数据
+	err = s.tx.Create(&dept).Error
+	return
+}
+
+// 更新部门
+func (s *MysqlService) UpdateDeptById(id uint, req gin.H) (err error) {
+	var oldDept models.SysDept
+	query := s.tx.Table(oldDept.TableName()).Where("id = ?", id).First(&oldDept)
+	if query.RecordNotFound() {
+		return errors.New("记录不存在")
+	}
+
+	// 比对增量字段
+	m := make(gin.H, 0)
+	utils.CompareDifferenceStructByJson(oldDept, req, &m)
+	if _, ok := m["parentId"]; ok {
+		var parentId uint
+		utils.Struct2StructByJson(m["parentId"], &parentId)
+		// 上级部门不能是自身或下级部门
+		depts := make([]models.SysDept, 0)
+		err = s.tx.Find(&depts).Error
+		if err != nil {
+			return
+		}
+		if utils.ContainsUint(models.GetDeptAndChildrenIds(id, depts), parentId) {
+			return errors.New("上级部门不能是当前部门或其下级部门")
+		}
+	}
+
+	// 更新指定列
+	err = query.Updates(m).Error
+	return
+}
+
+// 批量删除部门
+func (s *MysqlService) DeleteDeptByIds(ids []uint) (err error) {
+	var depts []models.SysDept
+	err = s.tx.Where("id IN (?)", ids).Find(&depts).Error
+	if err != nil {
+		return
+	}
+	for _, dept := range depts {
+		var count int
+		// 仍有下级部门(同时删除的除外)
+		err = s.tx.Model(&models.SysDept{}).Where("parent_id = ?", dept.Id).Where("id NOT IN (?)", ids).Count(&count).Error
+		if err != nil {
+			return
+		}
+		if count > 0 {
+			return errors.New(fmt.Sprintf("部门[%s]仍有%d个下级部门, 请先删除下级部门", dept.Name, count))
+		}
+		err = s.tx.Model(&models.SysUser{}).Where("dept_id = ?", dept.Id).Count(&count).Error
+		if err != nil {
+			return
+		}
+		if count > 0 {
+			return errors.New(fmt.Sprintf("部门[%s]仍有%d位关联用户, 请先调整用户部门", dept.Name, count))
+		}
+	}
+	// 删除角色自定义数据范围中的部门
+	err = s.tx.Where("sys_dept_id IN (?)", ids).Delete(models.RelationRoleDept{}).Error
+	if err != nil {
+		return
+	}
+	// 执行删除
+	return s.tx.Where("id IN (?)", ids).Delete(models.SysDept{}).Error
+}
@@ -10,11 +10,15 @@ import (
 	"strings"
 )
 
-// 获取所有请假(当前用户)
+// 获取所有请假(当前用户数据范围内)
 func (s *MysqlService) GetLeaves(req *request.LeaveListRequestStruct) ([]models.SysLeave, error) {
 	var err error
 	list := make([]models.SysLeave, 0)
-	query := s.tx.Where("user_id = ?", req.UserId)
+	// 按数据范围过滤
+	query := s.tx.Scopes(dataScopeQuery(req.DataScope, "user_id"))
+	if req.UserId > 0 {
+		query = query.Where("user_id = ?", req.UserId)
+	}
 	desc := strings.TrimSpace(req.Desc)
 	if req.Status != nil {
 		query = query.Where("status = ?", *req.Status)
@@ -95,6 +99,27 @@ func (s *MysqlService) UpdateLeaveById(id uint, req gin.H) (err error) {
 	m := make(gin.H, 0)
 	utils.CompareDifferenceStructByJson(leave, req, &m)
 
+	if _, ok := m["days"]; ok {
+		// 天数会影响审批流转条件, 审批中不允许修改
+		var flow models.SysWorkflow
+		flow, err = s.GetWorkflowByTargetCategory(models.SysWorkflowTargetCategoryLeave)
+		if err != nil {
+			return
+		}
+		var count int
+		err = s.tx.
+			Model(&models.SysWorkflowLog{}).
+			Where("flow_id = ? AND target_id = ?", flow.Id, leave.Id).
+			Where("status = ?", models.SysWorkflowLogStateSubmit).
+			Count(&count).Error
+		if err != nil {
+			return
+		}
+		if count > 0 {
+			return errors.New("请假正在审批中, 不能修改天数")
+		}
+	}
+
 	// 更新指定列
 	err = query.Updates(m).Error
 	return
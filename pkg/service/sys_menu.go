@@ -9,20 +9,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// 获取权限菜单树
-func (s *MysqlService) GetMenuTree(roleId uint) ([]models.SysMenu, error) {
+// 获取权限菜单树, 合并用户全部角色(含继承的角色)的菜单
+func (s *MysqlService) GetMenuTree(roleIds []uint) ([]models.SysMenu, error) {
 	tree := make([]models.SysMenu, 0)
-	query := s.tx.First(&models.SysRole{
-		Model: models.Model{
-			Id: roleId,
-		},
-	})
-	if query.RecordNotFound() {
+	roles, err := s.GetImplicitRoles(roleIds)
+	if err != nil {
+		return tree, err
+	}
+	if len(roles) == 0 {
 		return tree, errors.New("菜单为空")
 	}
-	menus := make([]models.SysMenu, 0)
-	// 查询当前role关联的所有菜单
-	query.Where("status = ?", 1).Order("sort").Association("menus").Find(&menus)
+	menus, err := s.getImplicitRoleMenus(roles)
+	if err != nil {
+		return tree, err
+	}
 	// 生成菜单树
 	tree = GenMenuTree(nil, menus)
 	return tree, nil
@@ -120,6 +120,68 @@ func (s *MysqlService) getRoleMenus(roleId uint) []models.SysMenu {
 	return role.Menus
 }
 
+// 获取角色关联的所有正常菜单(去重), 非菜单树
+func (s *MysqlService) getImplicitRoleMenus(roles []models.SysRole) ([]models.SysMenu, error) {
+	ids := make([]uint, 0)
+	for _, role := range roles {
+		ids = append(ids, role.Id)
+	}
+	menus := make([]models.SysMenu, 0)
+	err := s.tx.
+		Where("id IN (?)", s.tx.Table(new(models.RelationRoleMenu).TableName()).Select("sys_menu_id").Where("sys_role_id IN (?)", ids).SubQuery()).
+		Where("status = ?", 1).
+		Order("sort").
+		Find(&menus).Error
+	return menus, err
+}
+
+// 获取菜单(按钮)依赖的接口编号
+func (s *MysqlService) GetMenuApiIds(menuId uint) ([]uint, error) {
+	apiIds := make([]uint, 0)
+	var menu models.SysMenu
+	err := s.tx.Preload("Apis").Where("id = ?", menuId).First(&menu).Error
+	if err != nil {
+		return apiIds, err
+	}
+	for _, api := range menu.Apis {
+		apiIds = append(apiIds, api.Id)
+	}
+	return apiIds, nil
+}
+
+// 更新菜单(按钮)依赖的接口, 新增的接口同时授权给已拥有该菜单的角色
+func (s *MysqlService) UpdateMenuApisById(menuId uint, req request.UpdateIncrementalIdsRequestStruct) (err error) {
+	var menu models.SysMenu
+	query := s.tx.Preload("Apis").Preload("Roles").Where("id = ?", menuId).First(&menu)
+	if query.RecordNotFound() {
+		return errors.New("记录不存在")
+	}
+	apiIds := make([]uint, 0)
+	for _, api := range menu.Apis {
+		if !utils.ContainsUint(req.Delete, api.Id) {
+			apiIds = append(apiIds, api.Id)
+		}
+	}
+	apiIds = append(apiIds, req.Create...)
+	apis := make([]models.SysApi, 0)
+	err = s.tx.Where("id IN (?)", apiIds).Find(&apis).Error
+	if err != nil {
+		return
+	}
+	// 替换接口
+	err = s.tx.Model(&menu).Association("Apis").Replace(&apis).Error
+	if err != nil || len(req.Create) == 0 {
+		return
+	}
+	for _, role := range menu.Roles {
+		err = s.updateRoleApis(role.Keyword, req.Create, nil, models.SysCasbinEffectAllow)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 // 获取全部菜单, 非菜单树
 func (s *MysqlService) getAllMenu() []models.SysMenu {
 	menus := make([]models.SysMenu, 0)
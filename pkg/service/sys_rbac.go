@@ -0,0 +1,288 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+)
+
+// 导出全部角色/角色菜单/角色继承/casbin策略
+func (s *MysqlService) ExportRbac() (models.SysRbacBundle, error) {
+	bundle := models.SysRbacBundle{
+		Version:  models.SysRbacBundleVersion,
+		Roles:    make([]models.SysRbacBundleRole, 0),
+		Menus:    make([]models.SysRbacBundleMenu, 0),
+		Parents:  make([]models.SysRbacBundleParent, 0),
+		Policies: make([]models.SysRoleCasbin, 0),
+	}
+	roles := make([]models.SysRole, 0)
+	err := s.tx.Preload("Menus").Find(&roles).Error
+	if err != nil {
+		return bundle, err
+	}
+	e, err := s.Casbin()
+	if err != nil {
+		return bundle, err
+	}
+	for _, role := range roles {
+		bundle.Roles = append(bundle.Roles, role.BundleRole())
+		for _, menu := range role.Menus {
+			bundle.Menus = append(bundle.Menus, models.SysRbacBundleMenu{
+				Keyword: role.Keyword,
+				Menu:    menu.BundleKey(),
+			})
+		}
+		for _, rule := range e.GetFilteredGroupingPolicy(0, role.Keyword, "", s.domain()) {
+			bundle.Parents = append(bundle.Parents, models.SysRbacBundleParent{
+				Keyword: role.Keyword,
+				Parent:  rule[1],
+			})
+		}
+		bundle.Policies = append(bundle.Policies, s.GetRoleCasbins(models.SysRoleCasbin{
+			Keyword: role.Keyword,
+		})...)
+	}
+	bundle.Sort()
+	return bundle, nil
+}
+
+// 对比权限配置包与数据库
+func (s *MysqlService) DiffRbac(bundle models.SysRbacBundle) (models.SysRbacBundleDiff, error) {
+	var diff models.SysRbacBundleDiff
+	err := bundle.Validate()
+	if err != nil {
+		return diff, err
+	}
+	live, err := s.ExportRbac()
+	if err != nil {
+		return diff, err
+	}
+	return bundle.Diff(live), nil
+}
+
+// 应用权限配置包, 只处理配置包中出现的角色, 返回执行的变更
+// 角色与菜单在当前事务中更新, 出错时返回错误回滚事务; casbin策略不使用事务(参见Casbin), 出错时恢复到应用前的策略
+func (s *MysqlService) ApplyRbac(bundle models.SysRbacBundle) (models.SysRbacBundleDiff, error) {
+	var diff models.SysRbacBundleDiff
+	err := bundle.Validate()
+	if err != nil {
+		return diff, err
+	}
+	before, err := s.ExportRbac()
+	if err != nil {
+		return diff, err
+	}
+	diff = bundle.Diff(before)
+	if diff.Empty() {
+		return diff, nil
+	}
+	err = s.applyRbac(bundle, diff)
+	if err != nil {
+		restoreErr := s.restoreRbacCasbin(before)
+		if restoreErr != nil {
+			global.Log.Error(fmt.Sprintf("应用权限配置包失败, 恢复casbin策略失败: %v", restoreErr))
+		}
+		return diff, err
+	}
+	return diff, nil
+}
+
+// 按对比结果应用权限配置包
+func (s *MysqlService) applyRbac(bundle models.SysRbacBundle, diff models.SysRbacBundleDiff) (err error) {
+	// 菜单标识转换为编号
+	menuIds := make(map[string]uint, 0)
+	for _, menu := range s.getAllMenu() {
+		menuIds[menu.BundleKey()] = menu.Id
+	}
+	for _, menu := range bundle.Menus {
+		if _, ok := menuIds[menu.Menu]; !ok {
+			return errors.New(fmt.Sprintf("菜单不存在: %s", menu.Menu))
+		}
+	}
+
+	// 1. 角色
+	for _, item := range diff.CreateRoles {
+		status := item.Status
+		totpRequired := item.TotpRequired
+		role := models.SysRole{
+			Name:         item.Name,
+			Keyword:      item.Keyword,
+			Desc:         item.Desc,
+			Status:       &status,
+			TotpRequired: &totpRequired,
+			DataScope:    item.DataScope,
+			Creator:      "权限配置包导入",
+		}
+		err = s.tx.Create(&role).Error
+		if err != nil {
+			return err
+		}
+	}
+	for _, item := range diff.UpdateRoles {
+		err = s.tx.Model(&models.SysRole{}).Where("keyword = ?", item.Keyword).Updates(map[string]interface{}{
+			"name":          item.Name,
+			"desc":          item.Desc,
+			"status":        item.Status,
+			"totp_required": item.TotpRequired,
+			"data_scope":    item.DataScope,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	// 删除的继承关系中父角色可能不在配置包中, 这里查询全部角色
+	roles := make([]models.SysRole, 0)
+	err = s.tx.Preload("Menus").Find(&roles).Error
+	if err != nil {
+		return err
+	}
+	roleIds := make(map[string]uint, 0)
+	for _, role := range roles {
+		roleIds[role.Keyword] = role.Id
+	}
+
+	// 2. 角色菜单, 与页面勾选菜单一致, 提交角色的全部目标菜单
+	menuChanged := make(map[string]bool, 0)
+	for _, menu := range append(diff.CreateMenus, diff.DeleteMenus...) {
+		menuChanged[menu.Keyword] = true
+	}
+	for _, role := range roles {
+		if !menuChanged[role.Keyword] {
+			continue
+		}
+		req := request.UpdateIncrementalIdsRequestStruct{
+			Create: make([]uint, 0),
+			Delete: make([]uint, 0),
+		}
+		for _, menu := range role.Menus {
+			req.Delete = append(req.Delete, menu.Id)
+		}
+		for _, menu := range bundle.Menus {
+			if menu.Keyword == role.Keyword {
+				req.Create = append(req.Create, menuIds[menu.Menu])
+			}
+		}
+		err = s.UpdateRoleMenusById(role.Id, req)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 3. 角色继承, 先删除后新增, 避免中间状态误判为循环继承
+	for _, parent := range diff.DeleteParents {
+		err = s.UpdateRoleParentsById(roleIds[parent.Keyword], request.UpdateIncrementalIdsRequestStruct{
+			Delete: []uint{roleIds[parent.Parent]},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, parent := range diff.CreateParents {
+		err = s.UpdateRoleParentsById(roleIds[parent.Keyword], request.UpdateIncrementalIdsRequestStruct{
+			Create: []uint{roleIds[parent.Parent]},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// 4. casbin策略, 角色菜单会同时授权菜单依赖的接口, 需要重新对比策略
+	current, err := s.DiffRbac(bundle)
+	if err != nil {
+		return
+	}
+	// casbin批量操作任一规则已存在/不存在时全部不执行并返回false, 视为失败
+	if len(current.DeletePolicies) > 0 {
+		ok, err := s.BatchDeleteRoleCasbins(current.DeletePolicies)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("删除casbin策略失败, 策略可能已被修改")
+		}
+	}
+	if len(current.CreatePolicies) > 0 {
+		ok, err := s.BatchCreateRoleCasbins(current.CreatePolicies)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("新增casbin策略失败, 策略可能已被修改")
+		}
+	}
+	// 确认策略与配置包一致
+	current, err = s.DiffRbac(bundle)
+	if err != nil {
+		return err
+	}
+	if len(current.CreatePolicies) > 0 || len(current.DeletePolicies) > 0 {
+		return errors.New("应用权限配置包后casbin策略与配置包不一致")
+	}
+	return nil
+}
+
+// 恢复casbin策略及角色继承到应用权限配置包之前的状态
+func (s *MysqlService) restoreRbacCasbin(before models.SysRbacBundle) error {
+	live, err := s.ExportRbac()
+	if err != nil {
+		return err
+	}
+	e, err := s.Casbin()
+	if err != nil {
+		return err
+	}
+	// 逐条处理, 避免批量操作因部分规则已存在/不存在而整体失败
+	for _, policy := range live.Policies {
+		if !containsRoleCasbin(before.Policies, policy) {
+			_, err = s.DeleteRoleCasbin(policy)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, policy := range before.Policies {
+		if !containsRoleCasbin(live.Policies, policy) {
+			_, err = s.CreateRoleCasbin(policy)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, parent := range live.Parents {
+		if !containsRbacParent(before.Parents, parent) {
+			_, err = e.RemoveGroupingPolicies([][]string{{parent.Keyword, parent.Parent, s.domain()}})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, parent := range before.Parents {
+		if !containsRbacParent(live.Parents, parent) {
+			_, err = e.AddGroupingPolicies([][]string{{parent.Keyword, parent.Parent, s.domain()}})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func containsRoleCasbin(list []models.SysRoleCasbin, item models.SysRoleCasbin) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRbacParent(list []models.SysRbacBundleParent, item models.SysRbacBundleParent) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/utils"
+	uuid "github.com/satori/go.uuid"
+	"time"
+)
+
+// 签发刷新令牌, familyId为空时表示新登录, 将创建新的令牌族
+func (s *MysqlService) CreateRefreshToken(userId uint, device string, jti string, familyId string) (string, time.Time, error) {
+	var err error
+	now := time.Now()
+	expires := now.Add(time.Hour * time.Duration(global.Conf.Jwt.RefreshTimeout))
+	if familyId == "" {
+		familyId = uuid.NewV4().String()
+		// 每个设备只保留一个有效令牌族, 同设备重新登录时旧令牌族失效
+		err = s.tx.Model(&models.SysRefreshToken{}).
+			Where("user_id = ? AND device = ?", userId, device).
+			Update("revoked", true).Error
+		if err != nil {
+			return "", expires, err
+		}
+	}
+	// 明文只返回给客户端, 数据库仅保存摘要
+	token := utils.GenRandomToken(32)
+	refreshToken := models.SysRefreshToken{
+		Token:     utils.Sha256(token),
+		FamilyId:  familyId,
+		UserId:    userId,
+		Device:    device,
+		Jti:       jti,
+		ExpiresAt: expires.Unix(),
+	}
+	err = s.tx.Create(&refreshToken).Error
+	return token, expires, err
+}
+
+// 使用刷新令牌, 校验通过后标记为已使用, 调用方需签发同一族的新令牌
+func (s *MysqlService) UseRefreshToken(token string) (models.SysRefreshToken, error) {
+	var refreshToken models.SysRefreshToken
+	if token == "" {
+		return refreshToken, errors.New("刷新令牌不能为空")
+	}
+	notFound := s.tx.Where("token = ?", utils.Sha256(token)).First(&refreshToken).RecordNotFound()
+	if notFound {
+		return refreshToken, errors.New("刷新令牌不存在")
+	}
+	if *refreshToken.Revoked {
+		return refreshToken, errors.New("刷新令牌已吊销")
+	}
+	now := time.Now()
+	if refreshToken.UsedAt > 0 {
+		// 已使用的令牌再次出现, 可能已被盗用, 吊销整个令牌族
+		// 当前请求会以失败结束(事务回滚), 因此这里使用无事务实例
+		s.revokeRefreshTokenFamily(refreshToken.FamilyId, "刷新令牌被重复使用")
+		return refreshToken, errors.New("刷新令牌被重复使用, 已吊销该令牌族")
+	}
+	if refreshToken.ExpiresAt < now.Unix() {
+		return refreshToken, errors.New("刷新令牌已过期")
+	}
+	// 标记为已使用, 带上used_at条件, 避免并发请求重复使用同一令牌
+	query := s.tx.Model(&refreshToken).Where("used_at = ?", 0).Update("used_at", now.Unix())
+	if query.Error != nil {
+		return refreshToken, query.Error
+	}
+	if query.RowsAffected == 0 {
+		return refreshToken, errors.New("刷新令牌已被使用")
+	}
+	return refreshToken, nil
+}
+
+// 吊销用户全部刷新令牌
+func (s *MysqlService) RevokeUserRefreshTokens(userId uint) error {
+	return s.tx.Model(&models.SysRefreshToken{}).
+		Where("user_id = ?", userId).
+		Update("revoked", true).Error
+}
+
+// 吊销访问令牌对应的刷新令牌族(如用户登出)
+func (s *MysqlService) RevokeRefreshTokensByJti(jti string) (err error) {
+	if jti == "" {
+		return
+	}
+	var refreshToken models.SysRefreshToken
+	notFound := s.tx.Where("jti = ?", jti).First(&refreshToken).RecordNotFound()
+	if notFound {
+		return
+	}
+	return s.tx.Model(&models.SysRefreshToken{}).
+		Where("family_id = ?", refreshToken.FamilyId).
+		Update("revoked", true).Error
+}
+
+// 吊销整个令牌族, 同时吊销该族签发过的访问令牌
+func (s *MysqlService) revokeRefreshTokenFamily(familyId string, reason string) {
+	tokens := make([]models.SysRefreshToken, 0)
+	err := s.db.Where("family_id = ?", familyId).Find(&tokens).Error
+	if err != nil {
+		global.Log.Warn("[revokeRefreshTokenFamily]", err)
+		return
+	}
+	err = s.db.Model(&models.SysRefreshToken{}).
+		Where("family_id = ?", familyId).
+		Update("revoked", true).Error
+	if err != nil {
+		global.Log.Warn("[revokeRefreshTokenFamily]", err)
+		return
+	}
+	for _, token := range tokens {
+		err = s.revokeToken(s.db, token.Jti, token.UserId, reason)
+		if err != nil {
+			global.Log.Warn("[revokeRefreshTokenFamily]", err)
+		}
+	}
+}
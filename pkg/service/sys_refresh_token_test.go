@@ -0,0 +1,119 @@
+package service
+
+import (
+	"gin-web/pkg/utils"
+	"gin-web/tests"
+	uuid "github.com/satori/go.uuid"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// 刷新令牌轮换: 使用后旧令牌失效, 新令牌属于同一族
+func TestMysqlService_UseRefreshToken(t *testing.T) {
+	tests.InitTestEnv()
+	s := New(nil)
+	userId := uint(rand.New(rand.NewSource(time.Now().UnixNano())).Intn(1000000) + 1000000)
+	device := uuid.NewV4().String()
+
+	token1, _, err := s.CreateRefreshToken(userId, device, uuid.NewV4().String(), "")
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	used, err := s.UseRefreshToken(token1)
+	if err != nil {
+		t.Fatalf("UseRefreshToken() error = %v", err)
+	}
+	token2, _, err := s.CreateRefreshToken(userId, device, uuid.NewV4().String(), used.FamilyId)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	rotated, err := s.UseRefreshToken(token2)
+	if err != nil {
+		t.Fatalf("UseRefreshToken() error = %v", err)
+	}
+	if rotated.FamilyId != used.FamilyId {
+		t.Errorf("FamilyId = %s, want %s", rotated.FamilyId, used.FamilyId)
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"case1", "", true},
+		{"case2", utils.GenRandomToken(32), true},
+		// 轮换后的令牌不能再使用
+		{"case3", token2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.UseRefreshToken(tt.token); (err != nil) != tt.wantErr {
+				t.Errorf("UseRefreshToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// 重复使用已轮换的刷新令牌: 吊销整个令牌族以及该族签发的访问令牌
+func TestMysqlService_UseRefreshTokenReuse(t *testing.T) {
+	tests.InitTestEnv()
+	s := New(nil)
+	userId := uint(rand.New(rand.NewSource(time.Now().UnixNano())).Intn(1000000) + 2000000)
+	device := uuid.NewV4().String()
+	jti1 := uuid.NewV4().String()
+	jti2 := uuid.NewV4().String()
+
+	token1, _, err := s.CreateRefreshToken(userId, device, jti1, "")
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	used, err := s.UseRefreshToken(token1)
+	if err != nil {
+		t.Fatalf("UseRefreshToken() error = %v", err)
+	}
+	token2, _, err := s.CreateRefreshToken(userId, device, jti2, used.FamilyId)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	// 旧令牌再次出现
+	if _, err = s.UseRefreshToken(token1); err == nil {
+		t.Fatalf("UseRefreshToken() reuse error = nil, want error")
+	}
+	// 同一族未使用的新令牌同样失效
+	if _, err = s.UseRefreshToken(token2); err == nil {
+		t.Errorf("UseRefreshToken() after reuse error = nil, want error")
+	}
+	now := utils.UnixMilli(time.Now())
+	for _, jti := range []string{jti1, jti2} {
+		if !s.IsTokenRevoked(jti, userId, now) {
+			t.Errorf("IsTokenRevoked(%s) = false, want true", jti)
+		}
+	}
+}
+
+// 同一设备重新登录: 旧令牌族失效, 其他设备不受影响
+func TestMysqlService_CreateRefreshTokenSameDevice(t *testing.T) {
+	tests.InitTestEnv()
+	s := New(nil)
+	userId := uint(rand.New(rand.NewSource(time.Now().UnixNano())).Intn(1000000) + 3000000)
+	device := uuid.NewV4().String()
+
+	token1, _, err := s.CreateRefreshToken(userId, device, uuid.NewV4().String(), "")
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	other, _, err := s.CreateRefreshToken(userId, uuid.NewV4().String(), uuid.NewV4().String(), "")
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	_, _, err = s.CreateRefreshToken(userId, device, uuid.NewV4().String(), "")
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	if _, err = s.UseRefreshToken(token1); err == nil {
+		t.Errorf("UseRefreshToken() same device error = nil, want error")
+	}
+	if _, err = s.UseRefreshToken(other); err != nil {
+		t.Errorf("UseRefreshToken() other device error = %v", err)
+	}
+}
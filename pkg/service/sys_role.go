@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"gin-web/models"
-	"gin-web/pkg/global"
 	"gin-web/pkg/request"
 	"gin-web/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -15,7 +14,7 @@ import (
 func (s *MysqlService) GetRoles(req *request.RoleListRequestStruct) ([]models.SysRole, error) {
 	var err error
 	list := make([]models.SysRole, 0)
-	db := global.Mysql
+	db := s.tx.Preload("Depts")
 	name := strings.TrimSpace(req.Name)
 	if name != "" {
 		db = db.Where("name LIKE ?", fmt.Sprintf("%%%s%%", name))
@@ -56,6 +55,11 @@ func (s *MysqlService) CreateRole(req *request.CreateRoleRequestStruct) (err err
 	utils.Struct2StructByJson(req, &role)
 	// 创建数据
 	err = s.tx.Create(&role).Error
+	if err != nil {
+		return
+	}
+	// 自定义数据范围的部门
+	err = s.updateRoleDepts(role.Id, req.DeptIds)
 	return
 }
 
@@ -73,9 +77,30 @@ func (s *MysqlService) UpdateRoleById(id uint, req gin.H) (err error) {
 
 	// 更新指定列
 	err = query.Updates(m).Error
+	if err != nil {
+		return
+	}
+	// 提交了自定义数据范围的部门
+	if _, ok := req["deptIds"]; ok {
+		var deptIds []uint
+		utils.Struct2StructByJson(req["deptIds"], &deptIds)
+		err = s.updateRoleDepts(id, deptIds)
+	}
 	return
 }
 
+// 替换角色自定义数据范围的部门
+func (s *MysqlService) updateRoleDepts(id uint, deptIds []uint) error {
+	depts := make([]models.SysDept, 0)
+	if len(deptIds) > 0 {
+		err := s.tx.Where("id IN (?)", deptIds).Find(&depts).Error
+		if err != nil {
+			return err
+		}
+	}
+	return s.tx.Model(&models.SysRole{Model: models.Model{Id: id}}).Association("Depts").Replace(depts).Error
+}
+
 // 更新角色的权限菜单
 func (s *MysqlService) UpdateRoleMenusById(id uint, req request.UpdateIncrementalIdsRequestStruct) (err error) {
 	// 查询全部菜单
@@ -96,59 +121,247 @@ func (s *MysqlService) UpdateRoleMenusById(id uint, req request.UpdateIncrementa
 		return
 	}
 	// 替换菜单
-	err = s.tx.Where("id = ?", id).First(&models.SysRole{}).Association("Menus").Replace(&incrementalMenus).Error
+	var role models.SysRole
+	err = s.tx.Where("id = ?", id).First(&role).Association("Menus").Replace(&incrementalMenus).Error
+	if err != nil {
+		return
+	}
+	// 菜单(按钮)依赖的接口随菜单授权
+	err = s.updateRoleMenuApis(role.Keyword, roleMenus, incrementalMenus)
 	return
 }
 
+// 根据菜单变化更新角色的接口权限: 新菜单依赖的接口授权给角色
+// 移除菜单时不取消接口授权, 无法区分接口是随菜单授权还是单独授权(UpdateRoleApisById), 需要时请单独取消
+func (s *MysqlService) updateRoleMenuApis(keyword string, oldMenus []models.SysMenu, newMenus []models.SysMenu) error {
+	oldApiIds, err := s.getMenusApiIds(oldMenus)
+	if err != nil {
+		return err
+	}
+	newApiIds, err := s.getMenusApiIds(newMenus)
+	if err != nil {
+		return err
+	}
+	createIds := make([]uint, 0)
+	for _, id := range newApiIds {
+		if !utils.ContainsUint(oldApiIds, id) {
+			createIds = append(createIds, id)
+		}
+	}
+	return s.updateRoleApis(keyword, createIds, nil, models.SysCasbinEffectAllow)
+}
+
+// 获取菜单依赖的全部接口编号(去重)
+func (s *MysqlService) getMenusApiIds(menus []models.SysMenu) ([]uint, error) {
+	apiIds := make([]uint, 0)
+	menuIds := make([]uint, 0)
+	for _, menu := range menus {
+		menuIds = append(menuIds, menu.Id)
+	}
+	if len(menuIds) == 0 {
+		return apiIds, nil
+	}
+	relations := make([]models.RelationMenuApi, 0)
+	err := s.tx.Where("sys_menu_id IN (?)", menuIds).Find(&relations).Error
+	if err != nil {
+		return apiIds, err
+	}
+	for _, relation := range relations {
+		if !utils.ContainsUint(apiIds, relation.SysApiId) {
+			apiIds = append(apiIds, relation.SysApiId)
+		}
+	}
+	return apiIds, nil
+}
+
 // 更新角色的权限接口
-func (s *MysqlService) UpdateRoleApisById(id uint, req request.UpdateIncrementalIdsRequestStruct) (err error) {
+func (s *MysqlService) UpdateRoleApisById(id uint, req request.UpdateRoleApisRequestStruct) (err error) {
 	var oldRole models.SysRole
 	query := s.tx.Model(&oldRole).Where("id = ?", id).First(&oldRole)
 	if query.RecordNotFound() {
 		return errors.New("记录不存在")
 	}
-	if len(req.Delete) > 0 {
+	// 允许访问的接口
+	err = s.updateRoleApis(oldRole.Keyword, req.Create, req.Delete, models.SysCasbinEffectAllow)
+	if err != nil {
+		return
+	}
+	// 拒绝访问的接口
+	err = s.updateRoleApis(oldRole.Keyword, req.DenyCreate, req.DenyDelete, models.SysCasbinEffectDeny)
+	return
+}
+
+// 增量更新角色的casbin规则
+func (s *MysqlService) updateRoleApis(keyword string, createIds []uint, deleteIds []uint, effect string) (err error) {
+	if len(deleteIds) > 0 {
 		// 查询需要删除的api
 		deleteApis := make([]models.SysApi, 0)
-		err = s.tx.Where("id IN (?)", req.Delete).Find(&deleteApis).Error
+		err = s.tx.Where("id IN (?)", deleteIds).Find(&deleteApis).Error
 		if err != nil {
 			return
 		}
 		// 构建casbin规则
 		cs := make([]models.SysRoleCasbin, 0)
 		for _, api := range deleteApis {
-			cs = append(cs, models.SysRoleCasbin{
-				Keyword: oldRole.Keyword,
+			c := models.SysRoleCasbin{
+				Keyword: keyword,
 				Path:    api.Path,
 				Method:  api.Method,
-			})
+				Effect:  effect,
+			}
+			// casbin批量删除时任一规则不存在则全部失败, 这里只保留已存在的规则
+			if len(s.GetRoleCasbins(c)) > 0 {
+				cs = append(cs, c)
+			}
+		}
+		if len(cs) > 0 {
+			// 批量删除
+			_, err = s.BatchDeleteRoleCasbins(cs)
+			if err != nil {
+				return
+			}
 		}
-		// 批量删除
-		_, err = s.BatchDeleteRoleCasbins(cs)
 	}
-	if len(req.Create) > 0 {
+	if len(createIds) > 0 {
 		// 查询需要新增的api
 		createApis := make([]models.SysApi, 0)
-		err = s.tx.Where("id IN (?)", req.Create).Find(&createApis).Error
+		err = s.tx.Where("id IN (?)", createIds).Find(&createApis).Error
 		if err != nil {
 			return
 		}
 		// 构建casbin规则
 		cs := make([]models.SysRoleCasbin, 0)
 		for _, api := range createApis {
-			cs = append(cs, models.SysRoleCasbin{
-				Keyword: oldRole.Keyword,
+			c := models.SysRoleCasbin{
+				Keyword: keyword,
 				Path:    api.Path,
 				Method:  api.Method,
-			})
+				Effect:  effect,
+			}
+			// casbin批量新增时任一规则已存在则全部失败, 这里只保留新规则
+			if len(s.GetRoleCasbins(c)) == 0 {
+				cs = append(cs, c)
+			}
+		}
+		if len(cs) > 0 {
+			// 批量创建
+			_, err = s.BatchCreateRoleCasbins(cs)
 		}
-		// 批量创建
-		_, err = s.BatchCreateRoleCasbins(cs)
+	}
+	return
+}
+
+// 获取角色直接继承的父角色编号
+func (s *MysqlService) GetRoleParentIds(roleId uint) ([]uint, error) {
+	parentIds := make([]uint, 0)
+	var role models.SysRole
+	err := s.tx.Where("id = ?", roleId).First(&role).Error
+	if err != nil {
+		return parentIds, err
+	}
+	e, err := s.Casbin()
+	if err != nil {
+		return parentIds, err
+	}
+	// 查询符合字段v0=role.Keyword的所有继承关系(g, 子角色, 父角色, 租户域)
+	keywords := make([]string, 0)
+	for _, v := range e.GetFilteredGroupingPolicy(0, role.Keyword, "", s.domain()) {
+		keywords = append(keywords, v[1])
+	}
+	if len(keywords) == 0 {
+		return parentIds, nil
+	}
+	parents := make([]models.SysRole, 0)
+	err = s.tx.Where("keyword IN (?)", keywords).Find(&parents).Error
+	for _, parent := range parents {
+		parentIds = append(parentIds, parent.Id)
+	}
+	return parentIds, err
+}
 
+// 更新角色继承的父角色(子角色拥有父角色的全部接口权限与菜单)
+func (s *MysqlService) UpdateRoleParentsById(id uint, req request.UpdateIncrementalIdsRequestStruct) (err error) {
+	var role models.SysRole
+	if s.tx.Where("id = ?", id).First(&role).RecordNotFound() {
+		return errors.New("记录不存在")
+	}
+	e, err := s.Casbin()
+	if err != nil {
+		return
+	}
+	if len(req.Delete) > 0 {
+		// 查询需要删除的父角色
+		deleteRoles := make([]models.SysRole, 0)
+		err = s.tx.Where("id IN (?)", req.Delete).Find(&deleteRoles).Error
+		if err != nil {
+			return
+		}
+		rules := make([][]string, 0)
+		for _, parent := range deleteRoles {
+			// casbin批量删除时任一规则不存在则全部失败, 这里只保留已存在的规则
+			if len(e.GetFilteredGroupingPolicy(0, role.Keyword, parent.Keyword, s.domain())) > 0 {
+				rules = append(rules, []string{role.Keyword, parent.Keyword, s.domain()})
+			}
+		}
+		if len(rules) > 0 {
+			_, err = e.RemoveGroupingPolicies(rules)
+			if err != nil {
+				return
+			}
+		}
+	}
+	if len(req.Create) > 0 {
+		// 查询需要新增的父角色
+		createRoles := make([]models.SysRole, 0)
+		err = s.tx.Where("id IN (?)", req.Create).Find(&createRoles).Error
+		if err != nil {
+			return
+		}
+		rules := make([][]string, 0)
+		for _, parent := range createRoles {
+			if parent.Id == role.Id {
+				return errors.New("角色不能继承自身")
+			}
+			// 父角色已直接或间接继承当前角色, 再继承会形成循环
+			loop, _ := e.HasLink(parent.Keyword, role.Keyword, s.domain())
+			if loop {
+				return errors.New(fmt.Sprintf("角色[%s]已继承角色[%s], 不能循环继承", parent.Name, role.Name))
+			}
+			// casbin批量新增时任一规则已存在则全部失败, 这里只保留新规则
+			if len(e.GetFilteredGroupingPolicy(0, role.Keyword, parent.Keyword, s.domain())) == 0 {
+				rules = append(rules, []string{role.Keyword, parent.Keyword, s.domain()})
+			}
+		}
+		if len(rules) > 0 {
+			_, err = e.AddGroupingPolicies(rules)
+		}
 	}
 	return
 }
 
+// 获取角色自身及继承的全部角色(去重)
+func (s *MysqlService) GetImplicitRoles(roleIds []uint) ([]models.SysRole, error) {
+	roles := make([]models.SysRole, 0)
+	if len(roleIds) == 0 {
+		return roles, nil
+	}
+	err := s.tx.Where("id IN (?)", roleIds).Find(&roles).Error
+	if err != nil {
+		return roles, err
+	}
+	e, err := s.Casbin()
+	if err != nil {
+		return roles, err
+	}
+	keywords := make([]string, 0)
+	for _, role := range roles {
+		keywords = append(keywords, role.Keyword)
+	}
+	// 继承的角色同样需要是有效角色
+	err = s.tx.Where("keyword IN (?)", e.GetImplicitRoles(s.domain(), keywords...)).Where("status = ?", 1).Find(&roles).Error
+	return roles, err
+}
+
 // 批量删除角色
 func (s *MysqlService) DeleteRoleByIds(ids []uint) (err error) {
 	var roles []models.SysRole
@@ -157,12 +370,25 @@ func (s *MysqlService) DeleteRoleByIds(ids []uint) (err error) {
 	if err != nil {
 		return
 	}
+	e, err := s.Casbin()
+	if err != nil {
+		return
+	}
 	newIds := make([]uint, 0)
 	oldCasbins := make([]models.SysRoleCasbin, 0)
 	for _, v := range roles {
 		if len(v.Users) > 0 {
 			return errors.New(fmt.Sprintf("角色[%s]仍有%d位关联用户, 请先删除用户再删除角色", v.Name, len(v.Users)))
 		}
+		// 作为附加角色的用户
+		var count int
+		err = s.tx.Table(new(models.RelationUserRole).TableName()).Where("sys_role_id = ?", v.Id).Count(&count).Error
+		if err != nil {
+			return
+		}
+		if count > 0 {
+			return errors.New(fmt.Sprintf("角色[%s]仍有%d位关联用户, 请先删除用户再删除角色", v.Name, count))
+		}
 		oldCasbins = append(oldCasbins, s.GetRoleCasbins(models.SysRoleCasbin{
 			Keyword: v.Keyword,
 		})...)
@@ -172,7 +398,17 @@ func (s *MysqlService) DeleteRoleByIds(ids []uint) (err error) {
 		// 删除关联的casbin
 		s.BatchDeleteRoleCasbins(oldCasbins)
 	}
+	for _, v := range roles {
+		// 删除关联的继承关系(作为子角色或父角色)
+		e.RemoveFilteredGroupingPolicy(0, v.Keyword, "", s.domain())
+		e.RemoveFilteredGroupingPolicy(1, v.Keyword, s.domain())
+	}
 	if len(newIds) > 0 {
+		// 删除自定义数据范围的部门
+		err = s.tx.Where("sys_role_id IN (?)", newIds).Delete(models.RelationRoleDept{}).Error
+		if err != nil {
+			return
+		}
 		// 执行删除
 		err = s.tx.Where("id IN (?)", newIds).Delete(models.SysRole{}).Error
 	}
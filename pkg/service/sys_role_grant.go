@@ -0,0 +1,245 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"strings"
+	"time"
+)
+
+// 获取临时授权列表
+func (s *MysqlService) GetRoleGrants(req *request.RoleGrantListRequestStruct) ([]models.SysRoleGrant, error) {
+	var err error
+	list := make([]models.SysRoleGrant, 0)
+	query := s.tx.Model(&models.SysRoleGrant{}).Preload("User").Preload("Role")
+	if req.UserId > 0 {
+		query = query.Where("user_id = ?", req.UserId)
+	}
+	if req.RoleId > 0 {
+		query = query.Where("role_id = ?", req.RoleId)
+	}
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
+	}
+	if req.Active != nil {
+		now := time.Now().Unix()
+		if *req.Active {
+			query = query.Where("status = ? AND start_at <= ? AND end_at > ?", models.SysRoleGrantStatusApproved, now, now)
+		} else {
+			query = query.Where("NOT (status = ? AND start_at <= ? AND end_at > ?)", models.SysRoleGrantStatusApproved, now, now)
+		}
+	}
+	// 按id逆序
+	query = query.Order("id DESC")
+	// 查询条数
+	err = query.Count(&req.PageInfo.Total).Error
+	if err == nil {
+		if req.PageInfo.NoPagination {
+			// 不使用分页
+			err = query.Find(&list).Error
+		} else {
+			// 获取分页参数
+			limit, offset := req.GetLimit()
+			err = query.Limit(limit).Offset(offset).Find(&list).Error
+		}
+	}
+	return list, err
+}
+
+// 获取临时授权审计日志
+func (s *MysqlService) GetRoleGrantLogs(req *request.RoleGrantLogListRequestStruct) ([]models.SysRoleGrantLog, error) {
+	var err error
+	list := make([]models.SysRoleGrantLog, 0)
+	query := s.tx.Model(&models.SysRoleGrantLog{})
+	if req.GrantId > 0 {
+		query = query.Where("grant_id = ?", req.GrantId)
+	}
+	if req.UserId > 0 {
+		query = query.Where("user_id = ?", req.UserId)
+	}
+	if req.RoleId > 0 {
+		query = query.Where("role_id = ?", req.RoleId)
+	}
+	action := strings.TrimSpace(req.Action)
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	// 按id逆序
+	query = query.Order("id DESC")
+	// 查询条数
+	err = query.Count(&req.PageInfo.Total).Error
+	if err == nil {
+		if req.PageInfo.NoPagination {
+			// 不使用分页
+			err = query.Find(&list).Error
+		} else {
+			// 获取分页参数
+			limit, offset := req.GetLimit()
+			err = query.Limit(limit).Offset(offset).Find(&list).Error
+		}
+	}
+	return list, err
+}
+
+// 获取用户当前生效的临时授权角色
+func (s *MysqlService) getUserGrantRoles(userId uint) ([]models.SysRole, error) {
+	roles := make([]models.SysRole, 0)
+	grants := make([]models.SysRoleGrant, 0)
+	now := time.Now().Unix()
+	err := s.tx.
+		Preload("Role").
+		Where("user_id = ? AND status = ? AND start_at <= ? AND end_at > ?", userId, models.SysRoleGrantStatusApproved, now, now).
+		Find(&grants).Error
+	if err != nil {
+		return roles, err
+	}
+	for _, grant := range grants {
+		if grant.Role.Id > 0 {
+			roles = append(roles, grant.Role)
+		}
+	}
+	return roles, nil
+}
+
+// 创建临时授权: 配置了临时授权流程时提交审批, 否则直接生效
+func (s *MysqlService) CreateRoleGrant(req *request.CreateRoleGrantRequestStruct, operator models.SysUser) (err error) {
+	now := time.Now().Unix()
+	startAt := req.StartAt
+	if startAt <= 0 {
+		startAt = now
+	}
+	if req.EndAt <= startAt || req.EndAt <= now {
+		return errors.New("失效时间必须晚于生效时间及当前时间")
+	}
+	maxHours := global.Conf.RoleGrant.MaxHours
+	if maxHours > 0 && req.EndAt-startAt > int64(maxHours)*3600 {
+		return fmt.Errorf("临时授权时长不能超过%d小时", maxHours)
+	}
+	var user models.SysUser
+	if s.tx.Where("id = ?", req.UserId).First(&user).RecordNotFound() {
+		return errors.New("被授权用户不存在")
+	}
+	var role models.SysRole
+	if s.tx.Where("id = ?", req.RoleId).First(&role).RecordNotFound() {
+		return errors.New("授权角色不存在")
+	}
+	if user.RoleId == role.Id {
+		return errors.New("用户已拥有该角色")
+	}
+	grant := models.SysRoleGrant{
+		UserId:  user.Id,
+		RoleId:  role.Id,
+		StartAt: startAt,
+		EndAt:   req.EndAt,
+		Reason:  req.Reason,
+		Status:  models.SysRoleGrantStatusPending,
+		Creator: req.Creator,
+	}
+	err = s.tx.Create(&grant).Error
+	if err != nil {
+		return
+	}
+	detail := fmt.Sprintf(
+		"临时授权[用户: %s(%s), 角色: %s, 有效期: %s ~ %s, 原因: %s]",
+		user.Nickname,
+		user.Username,
+		role.Name,
+		time.Unix(startAt, 0).Format(models.TimeFormat),
+		time.Unix(req.EndAt, 0).Format(models.TimeFormat),
+		grant.Reason,
+	)
+	err = s.createRoleGrantLog(grant, models.SysRoleGrantActionCreate, operator.Username, detail)
+	if err != nil {
+		return
+	}
+	// 获取临时授权对应的工作流
+	var flow models.SysWorkflow
+	if s.tx.Where("target_category = ?", models.SysWorkflowTargetCategoryRoleGrant).First(&flow).RecordNotFound() {
+		// 未配置审批流程, 直接生效
+		err = s.tx.Model(&grant).Update("status", models.SysRoleGrantStatusApproved).Error
+		if err != nil {
+			return
+		}
+		return s.createRoleGrantLog(grant, models.SysRoleGrantActionApprove, operator.Username, "未配置临时授权流程, 无需审批")
+	}
+	// 提交审批, 审批结果由工作流回写
+	return s.WorkflowTransition(&request.WorkflowTransitionRequestStruct{
+		FlowId:         flow.Id,
+		TargetCategory: models.SysWorkflowTargetCategoryRoleGrant, // 临时授权
+		TargetId:       grant.Id,                                  // 临时授权编号
+		SubmitUserId:   operator.Id,                               // 提交人编号
+		SubmitDetail:   detail,                                    // 提交明细
+	})
+}
+
+// 批量撤销临时授权(待审批/已批准的授权)
+func (s *MysqlService) RevokeRoleGrantByIds(ids []uint, operator models.SysUser) (err error) {
+	grants := make([]models.SysRoleGrant, 0)
+	err = s.tx.
+		Where("id IN (?)", ids).
+		Where("status IN (?)", []uint{models.SysRoleGrantStatusPending, models.SysRoleGrantStatusApproved}).
+		Find(&grants).Error
+	if err != nil {
+		return
+	}
+	for _, grant := range grants {
+		err = s.tx.Model(&grant).Update("status", models.SysRoleGrantStatusRevoked).Error
+		if err != nil {
+			return
+		}
+		err = s.createRoleGrantLog(grant, models.SysRoleGrantActionRevoke, operator.Username, "手动撤销")
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 将已到期的临时授权标记为过期, 由定时任务调用(不区分租户)
+// 按条件逐条更新, 多实例同时执行时只有一个实例会记录日志
+func (s *MysqlService) ExpireRoleGrants() (int, error) {
+	grants := make([]models.SysRoleGrant, 0)
+	now := time.Now().Unix()
+	err := s.tx.
+		Where("status = ? AND end_at <= ?", models.SysRoleGrantStatusApproved, now).
+		Find(&grants).Error
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, grant := range grants {
+		query := s.tx.
+			Model(&models.SysRoleGrant{}).
+			Where("id = ? AND status = ?", grant.Id, models.SysRoleGrantStatusApproved).
+			Update("status", models.SysRoleGrantStatusExpired)
+		if query.Error != nil {
+			return count, query.Error
+		}
+		if query.RowsAffected == 0 {
+			continue
+		}
+		// 日志归属授权所在租户
+		ts := s.WithTenant(grant.TenantId)
+		err = ts.createRoleGrantLog(grant, models.SysRoleGrantActionExpire, "system", "授权到期")
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// 记录临时授权审计日志
+func (s *MysqlService) createRoleGrantLog(grant models.SysRoleGrant, action string, operator string, detail string) error {
+	return s.tx.Create(&models.SysRoleGrantLog{
+		GrantId:  grant.Id,
+		UserId:   grant.UserId,
+		RoleId:   grant.RoleId,
+		Action:   action,
+		Operator: operator,
+		Detail:   detail,
+	}).Error
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 会话最近访问时间的更新间隔(秒), 避免每次请求都写入
+const SessionTouchInterval = 60
+
+// 创建登录会话
+func (s *MysqlService) CreateSession(session models.SysSession) error {
+	// 清理已过期的会话
+	err := s.tx.Unscoped().Where("expires_at < ?", time.Now().Unix()).Delete(models.SysSession{}).Error
+	if err != nil {
+		global.Log.Warn("[CreateSession]", err)
+	}
+	return s.tx.Create(&session).Error
+}
+
+// 刷新令牌后更新会话(保留登录时间), 会话不存在时重新创建
+func (s *MysqlService) RefreshSession(oldJti string, session models.SysSession) error {
+	var oldSession models.SysSession
+	notFound := s.tx.Where("jti = ?", oldJti).First(&oldSession).RecordNotFound()
+	if notFound {
+		return s.CreateSession(session)
+	}
+	return s.tx.Model(&oldSession).Updates(map[string]interface{}{
+		"jti":          session.Jti,
+		"ip":           session.Ip,
+		"user_agent":   session.UserAgent,
+		"issued_at":    session.IssuedAt,
+		"last_seen_at": session.LastSeenAt,
+		"expires_at":   session.ExpiresAt,
+	}).Error
+}
+
+// 更新会话最近访问时间
+func (s *MysqlService) TouchSession(jti string) {
+	now := time.Now().Unix()
+	// 请求失败时也需要记录, 因此这里使用无事务实例
+	err := s.db.Model(&models.SysSession{}).
+		Where("jti = ? AND last_seen_at < ?", jti, now-SessionTouchInterval).
+		Update("last_seen_at", now).Error
+	if err != nil {
+		global.Log.Warn("[TouchSession]", err)
+	}
+}
+
+// 删除会话(如用户登出)
+func (s *MysqlService) DeleteSession(jti string) error {
+	return s.tx.Unscoped().Where("jti = ?", jti).Delete(models.SysSession{}).Error
+}
+
+// 获取有效会话, req.UserId不为0时只查询该用户
+func (s *MysqlService) GetSessions(req *request.SessionListRequestStruct) ([]models.SysSession, error) {
+	sessions := make([]models.SysSession, 0)
+	db := s.tx.Where("expires_at >= ?", time.Now().Unix())
+	if req.UserId > 0 {
+		db = db.Where("user_id = ?", req.UserId)
+	}
+	username := strings.TrimSpace(req.Username)
+	if username != "" {
+		db = db.Where("username LIKE ?", fmt.Sprintf("%%%s%%", username))
+	}
+	err := db.Find(&sessions).Error
+	if err != nil || len(sessions) == 0 {
+		return sessions, err
+	}
+	// 查询相关的吊销记录, 过滤已被吊销的会话
+	jtis := make([]string, 0)
+	userIds := make([]uint, 0)
+	for _, session := range sessions {
+		jtis = append(jtis, session.Jti)
+		userIds = append(userIds, session.UserId)
+	}
+	revocations := make([]models.SysTokenRevocation, 0)
+	err = s.tx.Where("jti IN (?) OR (jti = ? AND user_id IN (?))", jtis, "", userIds).Find(&revocations).Error
+	if err != nil {
+		return sessions, err
+	}
+	return PageSessions(models.ActiveSessions(sessions, revocations), req), nil
+}
+
+// 强制下线, userId不为0时只能操作该用户自己的会话
+func (s *MysqlService) KillSessions(userId uint, jtis []string, reason string) (err error) {
+	sessions := make([]models.SysSession, 0)
+	db := s.tx.Where("jti IN (?)", jtis)
+	if userId > 0 {
+		db = db.Where("user_id = ?", userId)
+	}
+	err = db.Find(&sessions).Error
+	if err != nil {
+		return
+	}
+	killed := make([]string, 0)
+	for _, session := range sessions {
+		err = s.RevokeSession(session, reason)
+		if err != nil {
+			return
+		}
+		killed = append(killed, session.Jti)
+	}
+	if len(killed) == 0 {
+		return
+	}
+	return s.tx.Unscoped().Where("jti IN (?)", killed).Delete(models.SysSession{}).Error
+}
+
+// 吊销会话的访问令牌及刷新令牌族
+func (s *MysqlService) RevokeSession(session models.SysSession, reason string) error {
+	err := s.RevokeToken(session.Jti, session.UserId, reason)
+	if err != nil {
+		return err
+	}
+	return s.RevokeRefreshTokensByJti(session.Jti)
+}
+
+// 会话按最近访问时间倒序, 并按分页参数截取
+func PageSessions(sessions []models.SysSession, req *request.SessionListRequestStruct) []models.SysSession {
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt > sessions[j].LastSeenAt
+	})
+	req.PageInfo.Total = uint(len(sessions))
+	if req.PageInfo.NoPagination {
+		return sessions
+	}
+	limit, offset := req.GetLimit()
+	start := int(offset)
+	if start >= len(sessions) {
+		return []models.SysSession{}
+	}
+	end := start + int(limit)
+	if end > len(sessions) {
+		end = len(sessions)
+	}
+	return sessions[start:end]
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/request"
+	"gin-web/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// 租户管理员角色关键字
+const tenantAdminKeyword = models.SysRoleSuperAdminKeyword
+
+// 根据编号获取租户(不区分当前租户), 用于校验请求/令牌中的租户
+func (s *MysqlService) GetTenantById(id uint) (models.SysTenant, error) {
+	var tenant models.SysTenant
+	err := global.Mysql.Where("id = ?", id).First(&tenant).Error
+	return tenant, err
+}
+
+// 租户只能在默认租户下管理
+func (s *MysqlService) checkDefaultTenant() error {
+	if tenantId, _ := s.TenantId(); tenantId != models.SysTenantDefault {
+		return errors.New("只有默认租户可以管理租户")
+	}
+	return nil
+}
+
+// 获取租户列表
+func (s *MysqlService) GetTenants(req *request.TenantListRequestStruct) ([]models.SysTenant, error) {
+	list := make([]models.SysTenant, 0)
+	err := s.checkDefaultTenant()
+	if err != nil {
+		return list, err
+	}
+	db := s.tx.Model(&models.SysTenant{})
+	name := strings.TrimSpace(req.Name)
+	if name != "" {
+		db = db.Where("name LIKE ?", fmt.Sprintf("%%%s%%", name))
+	}
+	creator := strings.TrimSpace(req.Creator)
+	if creator != "" {
+		db = db.Where("creator LIKE ?", fmt.Sprintf("%%%s%%", creator))
+	}
+	if req.Status != nil {
+		if *req.Status {
+			db = db.Where("status = ?", 1)
+		} else {
+			db = db.Where("status = ?", 0)
+		}
+	}
+	// 查询条数
+	err = db.Count(&req.PageInfo.Total).Error
+	if err == nil {
+		if req.PageInfo.NoPagination {
+			// 不使用分页
+			err = db.Find(&list).Error
+		} else {
+			// 获取分页参数
+			limit, offset := req.GetLimit()
+			err = db.Limit(limit).Offset(offset).Find(&list).Error
+		}
+	}
+	return list, err
+}
+
+// 创建租户
+func (s *MysqlService) CreateTenant(req *request.CreateTenantRequestStruct) (err error) {
+	err = s.checkDefaultTenant()
+	if err != nil {
+		return
+	}
+	var tenant models.SysTenant
+	utils.Struct2StructByJson(req, &tenant)
+	// 创建数据
+	err = s.tx.Create(&tenant).Error
+	if err != nil || strings.TrimSpace(req.AdminUsername) == "" {
+		return
+	}
+	// 初始化租户管理员
+	return s.createTenantAdmin(tenant, req)
+}
+
+// 在租户中创建管理员角色(拥有全部菜单以及除租户管理外的全部接口)与管理员用户
+func (s *MysqlService) createTenantAdmin(tenant models.SysTenant, req *request.CreateTenantRequestStruct) (err error) {
+	ts := s.WithTenant(tenant.Id)
+	status := true
+	role := models.SysRole{
+		Name:      "管理员",
+		Keyword:   tenantAdminKeyword,
+		Desc:      fmt.Sprintf("租户[%s]管理员", tenant.Name),
+		Status:    &status,
+		Creator:   req.Creator,
+		DataScope: models.SysRoleDataScopeAll,
+	}
+	err = ts.tx.Create(&role).Error
+	if err != nil {
+		return
+	}
+	// 全部菜单(同时授权菜单绑定的接口)
+	menuIds := make([]uint, 0)
+	for _, menu := range ts.getAllMenu() {
+		menuIds = append(menuIds, menu.Id)
+	}
+	err = ts.UpdateRoleMenusById(role.Id, request.UpdateIncrementalIdsRequestStruct{
+		Create: menuIds,
+	})
+	if err != nil {
+		return
+	}
+	// 全部接口
+	apis := make([]models.SysApi, 0)
+	err = ts.tx.Where("stale = ?", false).Find(&apis).Error
+	if err != nil {
+		return
+	}
+	apiIds := make([]uint, 0)
+	for _, api := range apis {
+		if !strings.HasPrefix(api.Path, "/v1/tenant/") {
+			apiIds = append(apiIds, api.Id)
+		}
+	}
+	err = ts.updateRoleApis(role.Keyword, apiIds, nil, models.SysCasbinEffectAllow)
+	if err != nil {
+		return
+	}
+	return ts.CreateUser(&request.CreateUserRequestStruct{
+		Username:     req.AdminUsername,
+		InitPassword: req.AdminPassword,
+		Mobile:       req.AdminMobile,
+		Nickname:     "管理员",
+		Status:       &status,
+		RoleId:       role.Id,
+		Creator:      req.Creator,
+	})
+}
+
+// 更新租户
+func (s *MysqlService) UpdateTenantById(id uint, req gin.H) (err error) {
+	err = s.checkDefaultTenant()
+	if err != nil {
+		return
+	}
+	var oldTenant models.SysTenant
+	query := s.tx.Table(oldTenant.TableName()).Where("id = ?", id).First(&oldTenant)
+	if query.RecordNotFound() {
+		return errors.New("记录不存在")
+	}
+
+	// 比对增量字段
+	m := make(gin.H, 0)
+	utils.CompareDifferenceStructByJson(oldTenant, req, &m)
+
+	// 更新指定列
+	return query.Updates(m).Error
+}
+
+// 批量删除租户, 租户仍有用户时不能删除
+func (s *MysqlService) DeleteTenantByIds(ids []uint) (err error) {
+	err = s.checkDefaultTenant()
+	if err != nil {
+		return
+	}
+	tenants := make([]models.SysTenant, 0)
+	err = s.tx.Where("id IN (?)", ids).Find(&tenants).Error
+	if err != nil {
+		return
+	}
+	for _, tenant := range tenants {
+		var count int
+		// 查询其他租户的数据, 不使用租户过滤
+		err = global.Mysql.Model(&models.SysUser{}).Where("tenant_id = ?", tenant.Id).Count(&count).Error
+		if err != nil {
+			return
+		}
+		if count > 0 {
+			return errors.New(fmt.Sprintf("租户[%s]仍有%d位用户, 请先删除用户再删除租户", tenant.Name, count))
+		}
+	}
+	// 执行删除
+	return s.tx.Where("id IN (?)", ids).Delete(models.SysTenant{}).Error
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/utils"
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+// 吊销单个令牌(按jti)
+func (s *MysqlService) RevokeToken(jti string, userId uint, reason string) error {
+	return s.revokeToken(s.tx, jti, userId, reason)
+}
+
+// 吊销单个令牌, 可指定是否使用事务(请求失败时也需要生效的场景使用无事务实例)
+func (s *MysqlService) revokeToken(db *gorm.DB, jti string, userId uint, reason string) (err error) {
+	if jti == "" {
+		return
+	}
+	now := time.Now()
+	// 清理已过期的吊销记录
+	s.clearExpiredTokenRevocations(db, now)
+	// 访问令牌只能通过刷新令牌重新签发(新jti), 因此携带该jti的令牌最多存活一个Timeout周期
+	revocation := models.SysTokenRevocation{
+		Jti:       jti,
+		UserId:    userId,
+		RevokedAt: utils.UnixMilli(now),
+		ExpiresAt: now.Add(time.Hour * time.Duration(global.Conf.Jwt.Timeout)).Unix(),
+		Reason:    reason,
+	}
+	err = db.Create(&revocation).Error
+	return
+}
+
+// 吊销用户全部令牌(该时间点之前签发的令牌全部失效)
+func (s *MysqlService) RevokeUserTokens(userId uint, reason string) (err error) {
+	if userId == 0 {
+		return
+	}
+	now := time.Now()
+	// 清理已过期的吊销记录
+	s.clearExpiredTokenRevocations(s.tx, now)
+	// 每个用户只需保留最新一条记录, 旧记录直接物理删除
+	err = s.tx.Unscoped().Where("user_id = ? AND jti = ?", userId, "").Delete(models.SysTokenRevocation{}).Error
+	if err != nil {
+		return
+	}
+	// 令牌刷新不会重置签发时间iat, 因此该记录不设置过期时间
+	revocation := models.SysTokenRevocation{
+		UserId:    userId,
+		RevokedAt: utils.UnixMilli(now),
+		Reason:    reason,
+	}
+	err = s.tx.Create(&revocation).Error
+	if err != nil {
+		return
+	}
+	// 刷新令牌同样失效
+	err = s.RevokeUserRefreshTokens(userId)
+	return
+}
+
+// 判断令牌是否已被吊销
+func (s *MysqlService) IsTokenRevoked(jti string, userId uint, iat int64) bool {
+	revocations := make([]models.SysTokenRevocation, 0)
+	err := s.tx.Where("jti = ? OR (user_id = ? AND jti = ?)", jti, userId, "").Find(&revocations).Error
+	if err != nil {
+		global.Log.Warn("[IsTokenRevoked]", err)
+		return false
+	}
+	return models.TokenRevoked(revocations, jti, userId, iat)
+}
+
+// 清理已过期的吊销记录
+func (s *MysqlService) clearExpiredTokenRevocations(db *gorm.DB, now time.Time) {
+	err := db.Unscoped().Where("expires_at > 0 AND expires_at < ?", now.Unix()).Delete(models.SysTokenRevocation{}).Error
+	if err != nil {
+		global.Log.Warn("[clearExpiredTokenRevocations]", err)
+	}
+}
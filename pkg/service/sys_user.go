@@ -4,34 +4,37 @@ import (
 	"errors"
 	"fmt"
 	"gin-web/models"
-	"gin-web/pkg/global"
 	"gin-web/pkg/request"
 	"gin-web/pkg/response"
 	"gin-web/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"strings"
+	"time"
 )
 
 // 登录校验
 func (s *MysqlService) LoginCheck(user *models.SysUser) (*models.SysUser, error) {
 	var u models.SysUser
 	// 查询用户及其角色
-	err := s.tx.Preload("Role").Where("username = ?", user.Username).First(&u).Error
+	err := s.tx.Preload("Role").Preload("Roles").Where("username = ?", user.Username).First(&u).Error
 	if err != nil {
-		return nil, err
+		// 本地用户不存在, 可能是首次登录的LDAP用户
+		return s.AuthenticateByOrder(user.Username, user.Password, nil, err)
 	}
-	// 校验密码
-	if ok := utils.ComparePwd(user.Password, u.Password); !ok {
-		return nil, errors.New(response.LoginCheckErrorMsg)
+	// 登录失败次数过多, 账号已锁定
+	if u.IsLocked() {
+		return nil, errors.New(response.LoginLockedMsg)
 	}
-	return &u, err
+	// 按认证顺序校验密码(本地密码/LDAP)
+	return s.AuthenticateByOrder(user.Username, user.Password, &u, nil)
 }
 
 // 获取用户
 func (s *MysqlService) GetUsers(req *request.UserListRequestStruct) ([]models.SysUser, error) {
 	var err error
 	list := make([]models.SysUser, 0)
-	db := global.Mysql
+	// 按数据范围过滤
+	db := s.tx.Preload("Roles").Scopes(dataScopeQuery(req.DataScope, "id"))
 	username := strings.TrimSpace(req.Username)
 	if username != "" {
 		db = db.Where("username LIKE ?", fmt.Sprintf("%%%s%%", username))
@@ -55,6 +58,9 @@ func (s *MysqlService) GetUsers(req *request.UserListRequestStruct) ([]models.Sy
 			db = db.Where("status = ?", 0)
 		}
 	}
+	if req.DeptId > 0 {
+		db = db.Where("dept_id = ?", req.DeptId)
+	}
 	// 查询条数
 	err = db.Find(&list).Count(&req.PageInfo.Total).Error
 	if err == nil {
@@ -74,7 +80,12 @@ func (s *MysqlService) GetUsers(req *request.UserListRequestStruct) ([]models.Sy
 func (s *MysqlService) GetUserById(id uint) (models.SysUser, error) {
 	var user models.SysUser
 	var err error
-	err = s.tx.Preload("Role").Where("id = ?", id).First(&user).Error
+	err = s.tx.Preload("Role").Preload("Roles").Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return user, err
+	}
+	// 当前生效的临时授权角色
+	user.GrantRoles, err = s.getUserGrantRoles(user.Id)
 	return user, err
 }
 
@@ -82,7 +93,7 @@ func (s *MysqlService) GetUserById(id uint) (models.SysUser, error) {
 func (s *MysqlService) GetUsersByIds(ids []uint) ([]models.SysUser, error) {
 	var users []models.SysUser
 	var err error
-	err = s.tx.Preload("Role").Where("id IN (?)", ids).Find(&users).Error
+	err = s.tx.Preload("Role").Preload("Roles").Where("id IN (?)", ids).Find(&users).Error
 	return users, err
 }
 
@@ -90,10 +101,28 @@ func (s *MysqlService) GetUsersByIds(ids []uint) ([]models.SysUser, error) {
 func (s *MysqlService) CreateUser(req *request.CreateUserRequestStruct) (err error) {
 	var user models.SysUser
 	utils.Struct2StructByJson(req, &user)
+	// 校验初始密码
+	err = s.checkNewPwd(user, req.InitPassword)
+	if err != nil {
+		return
+	}
 	// 将初始密码转为密文
 	user.Password = utils.GenPwd(req.InitPassword)
+	// 初始密码由管理员设置, 首次登录必须修改
+	mustChangePwd := true
+	user.MustChangePwd = &mustChangePwd
+	user.PwdChangedAt = time.Now().Unix()
 	// 创建数据
 	err = s.tx.Create(&user).Error
+	if err != nil {
+		return
+	}
+	// 附加角色
+	err = s.updateUserRoles(user, req.RoleIds)
+	if err != nil {
+		return
+	}
+	err = s.createPwdHistory(user.Id, user.Password)
 	return
 }
 
@@ -108,23 +137,139 @@ func (s *MysqlService) UpdateUserById(id uint, newPassword string, req gin.H) (e
 	password := ""
 	// 填写了新密码
 	if strings.TrimSpace(newPassword) != "" {
+		err = s.checkNewPwd(oldUser, newPassword)
+		if err != nil {
+			return
+		}
 		password = utils.GenPwd(newPassword)
 	}
 	// 比对增量字段
 	m := make(gin.H, 0)
 	utils.CompareDifferenceStructByJson(oldUser, req, &m)
+	// 管理员只能关闭二次验证(如用户丢失设备), 开启需用户自行绑定
+	if enabled, ok := m["totpEnabled"].(bool); ok {
+		delete(m, "totpEnabled")
+		if !enabled {
+			err = s.resetTotp(id)
+			if err != nil {
+				return
+			}
+		}
+	}
 
 	if password != "" {
-		// 更新密码以及其他指定列
-		err = query.Update("password", password).Updates(m).Error
-	} else {
-		// 更新指定列
-		err = query.Updates(m).Error
+		// 管理员重置的密码, 下次登录必须修改
+		err = s.savePwd(id, password, true)
+		if err != nil {
+			return
+		}
+	}
+	// 更新指定列
+	err = query.Updates(m).Error
+	if err != nil {
+		return
+	}
+	// 提交了附加角色
+	if _, ok := req["roleIds"]; ok {
+		var roleIds []uint
+		utils.Struct2StructByJson(req["roleIds"], &roleIds)
+		if _, ok := m["roleId"]; ok {
+			// 主角色有变更
+			utils.Struct2StructByJson(m["roleId"], &oldUser.RoleId)
+		}
+		err = s.updateUserRoles(oldUser, roleIds)
+		if err != nil {
+			return
+		}
+	}
+	if password != "" {
+		// 修改密码后, 吊销该用户全部令牌
+		err = s.RevokeUserTokens(id, "管理员修改密码")
+	} else if status, ok := m["status"].(bool); ok && !status {
+		// 禁用用户后, 吊销该用户全部令牌
+		err = s.RevokeUserTokens(id, "用户被禁用")
 	}
 	return
 }
 
+// 替换用户的附加角色(不包含主角色)
+func (s *MysqlService) updateUserRoles(user models.SysUser, roleIds []uint) error {
+	ids := make([]uint, 0)
+	for _, id := range roleIds {
+		if id != user.RoleId && !utils.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	roles := make([]models.SysRole, 0)
+	if len(ids) > 0 {
+		err := s.tx.Where("id IN (?)", ids).Find(&roles).Error
+		if err != nil {
+			return err
+		}
+		if len(roles) != len(ids) {
+			return errors.New("附加角色不存在")
+		}
+	}
+	return s.tx.Model(&models.SysUser{Model: models.Model{Id: user.Id}}).Association("Roles").Replace(roles).Error
+}
+
 // 批量删除用户
 func (s *MysqlService) DeleteUserByIds(ids []uint) (err error) {
 	return s.tx.Where("id IN (?)", ids).Delete(models.SysUser{}).Error
 }
+
+// 锁定用户(登录失败次数过多)
+func (s *MysqlService) LockUserByUsername(username string, until int64) error {
+	// 登录失败时请求会以失败结束(事务回滚), 因此这里使用无事务实例
+	return s.db.Model(&models.SysUser{}).Where("username = ?", username).Update("locked_until", until).Error
+}
+
+// 解锁用户
+func (s *MysqlService) UnlockUserById(id uint) (models.SysUser, error) {
+	var user models.SysUser
+	query := s.tx.Where("id = ?", id).First(&user)
+	if query.RecordNotFound() {
+		return user, errors.New("记录不存在")
+	}
+	err := query.Update("locked_until", 0).Error
+	return user, err
+}
+
+// 获取用户权限标识, 包括角色(含继承的角色)菜单的权限标识, 以及有权访问的接口标识
+func (s *MysqlService) GetUserPermissions(user models.SysUser) ([]string, error) {
+	roles, err := s.GetImplicitRoles(user.RoleIds())
+	if err != nil || len(roles) == 0 {
+		return make([]string, 0), err
+	}
+	menus, err := s.getImplicitRoleMenus(roles)
+	if err != nil {
+		return make([]string, 0), err
+	}
+	apis := make([]models.SysApi, 0)
+	err = s.tx.Where("stale = ?", false).Find(&apis).Error
+	if err != nil {
+		return make([]string, 0), err
+	}
+	e, err := s.Casbin()
+	if err != nil {
+		return make([]string, 0), err
+	}
+	return GenPermissions(e, user.RoleKeywords(), s.domain(), menus, apis), nil
+}
+
+// 生成权限标识(去重): 菜单的权限标识, 以及角色在租户域dom中有权访问的接口标识(参见SysApi.PermissionKey)
+func GenPermissions(e *CasbinEnforcer, keywords []string, dom string, menus []models.SysMenu, apis []models.SysApi) []string {
+	permissions := make([]string, 0)
+	for _, menu := range menus {
+		if menu.Permission != "" && !utils.Contains(permissions, menu.Permission) {
+			permissions = append(permissions, menu.Permission)
+		}
+	}
+	for _, api := range apis {
+		allowed, _ := e.EnforceAny(keywords, dom, api.Path, api.Method)
+		if allowed && !utils.Contains(permissions, api.PermissionKey()) {
+			permissions = append(permissions, api.PermissionKey())
+		}
+	}
+	return permissions
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/ldap"
+	"gin-web/pkg/response"
+	"gin-web/pkg/utils"
+	"time"
+)
+
+// 登录认证方式
+const (
+	AuthLocal = "local" // 本地密码
+	AuthLdap  = "ldap"  // LDAP绑定
+)
+
+// 获取登录认证顺序, 未开启LDAP时只使用本地密码
+func LoginAuthOrder() []string {
+	conf := global.Conf.Ldap
+	if !conf.Enable {
+		return []string{AuthLocal}
+	}
+	if len(conf.AuthOrder) == 0 {
+		return []string{AuthLocal, AuthLdap}
+	}
+	return conf.AuthOrder
+}
+
+// 按认证顺序校验密码, u为本地查询到的用户(不存在时为nil, notFoundErr为查询错误)
+func (s *MysqlService) AuthenticateByOrder(username string, password string, u *models.SysUser, notFoundErr error) (*models.SysUser, error) {
+	// 本地用户不存在且其他方式均未通过时, 与原逻辑一样返回查询错误
+	err := notFoundErr
+	if u != nil {
+		err = errors.New(response.LoginCheckErrorMsg)
+	}
+	for _, backend := range LoginAuthOrder() {
+		switch backend {
+		case AuthLocal:
+			if u != nil && utils.ComparePwd(password, u.Password) {
+				return u, nil
+			}
+		case AuthLdap:
+			user, ldapErr := s.LdapLoginCheck(username, password)
+			if ldapErr == nil {
+				return user, nil
+			}
+			if ldapErr.Error() != response.LoginCheckErrorMsg {
+				global.Log.Warn("[AuthenticateByOrder]", ldapErr)
+			}
+			err = errors.New(response.LoginCheckErrorMsg)
+		}
+	}
+	return nil, err
+}
+
+// LDAP登录校验, 通过后同步用户信息(首次登录时自动创建)
+func (s *MysqlService) LdapLoginCheck(username string, password string) (*models.SysUser, error) {
+	conf := global.Conf.Ldap
+	entry, err := ldap.Authenticate(ldap.Config{
+		Url:          conf.Url,
+		BindDn:       conf.BindDn,
+		BindPassword: conf.BindPassword,
+		BaseDn:       conf.BaseDn,
+		UserFilter:   conf.UserFilter,
+		GroupBaseDn:  conf.GroupBaseDn,
+		GroupFilter:  conf.GroupFilter,
+		GroupAttr:    conf.GroupAttr,
+		NicknameAttr: conf.NicknameAttr,
+		MobileAttr:   conf.MobileAttr,
+		Timeout:      time.Duration(conf.Timeout) * time.Second,
+	}, username, password)
+	if err == ldap.ErrInvalidCredentials {
+		return nil, errors.New(response.LoginCheckErrorMsg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.syncLdapUser(username, entry)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// 同步LDAP用户信息(昵称/手机/角色)
+func (s *MysqlService) syncLdapUser(username string, entry *ldap.Entry) (models.SysUser, error) {
+	conf := global.Conf.Ldap
+	roleId, err := s.getLdapRoleId(entry.Groups)
+	if err != nil {
+		return models.SysUser{}, err
+	}
+	var user models.SysUser
+	notFound := s.tx.Where("username = ?", username).First(&user).RecordNotFound()
+	if notFound {
+		if roleId == 0 {
+			roleId = conf.DefaultRoleId
+		}
+		if roleId == 0 {
+			return user, errors.New("未配置LDAP用户的默认角色")
+		}
+		user = models.SysUser{
+			Username: username,
+			// LDAP用户不使用本地密码登录, 设置随机密码
+			Password: utils.GenPwd(utils.GenRandomToken(32)),
+			Mobile:   entry.Mobile,
+			Nickname: entry.Nickname,
+			LdapDn:   entry.Dn,
+			RoleId:   roleId,
+			Creator:  "LDAP",
+		}
+		err = s.tx.Create(&user).Error
+	} else {
+		// 不自动关联同名的本地用户, 避免LDAP中的同名账号接管本地账号
+		if user.LdapDn != entry.Dn {
+			return user, errors.New("用户名已存在, 请联系管理员关联LDAP账号")
+		}
+		m := make(map[string]interface{})
+		if entry.Nickname != "" {
+			m["nickname"] = entry.Nickname
+		}
+		if entry.Mobile != "" {
+			m["mobile"] = entry.Mobile
+		}
+		if roleId > 0 {
+			m["role_id"] = roleId
+		}
+		if len(m) > 0 {
+			err = s.tx.Model(&user).Updates(m).Error
+		}
+	}
+	if err != nil {
+		return user, err
+	}
+	err = s.tx.Preload("Role").Where("id = ?", user.Id).First(&user).Error
+	return user, err
+}
+
+// 按组映射角色, 未匹配时返回0
+func (s *MysqlService) getLdapRoleId(groups []string) (uint, error) {
+	for _, mapping := range global.Conf.Ldap.GroupMappings {
+		if !utils.Contains(groups, mapping.Group) {
+			continue
+		}
+		var role models.SysRole
+		notFound := s.tx.Where("keyword = ?", mapping.Role).First(&role).RecordNotFound()
+		if notFound {
+			return 0, errors.New("LDAP组映射配置错误, 角色不存在: " + mapping.Role)
+		}
+		return role.Id, nil
+	}
+	return 0, nil
+}
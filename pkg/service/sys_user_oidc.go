@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/oidc"
+	"gin-web/pkg/utils"
+)
+
+// 获取SSO登录用户, 首次登录时自动创建(JIT), 每次登录按声明同步角色
+func (s *MysqlService) GetOidcUser(claims oidc.Claims) (models.SysUser, error) {
+	conf := global.Conf.Oidc
+	sub := claims.String("sub")
+	roleId, err := s.getOidcRoleId(claims)
+	if err != nil {
+		return models.SysUser{}, err
+	}
+	var user models.SysUser
+	notFound := s.tx.Where("oidc_subject = ?", sub).First(&user).RecordNotFound()
+	if notFound {
+		username := claims.String(conf.UsernameClaim)
+		if username == "" {
+			username = sub
+		}
+		// 不自动关联同名的本地用户, 避免IdP中的同名账号接管本地账号
+		var count int
+		err = s.tx.Model(&models.SysUser{}).Where("username = ?", username).Count(&count).Error
+		if err != nil {
+			return user, err
+		}
+		if count > 0 {
+			return user, errors.New("用户名已存在, 请联系管理员关联SSO账号")
+		}
+		if roleId == 0 {
+			roleId = conf.DefaultRoleId
+		}
+		if roleId == 0 {
+			return user, errors.New("未配置SSO用户的默认角色")
+		}
+		user = models.SysUser{
+			Username: username,
+			// SSO用户不使用本地密码登录, 设置随机密码
+			Password:    utils.GenPwd(utils.GenRandomToken(32)),
+			Mobile:      claims.String("phone_number"),
+			Avatar:      claims.String("picture"),
+			Nickname:    claims.String("name"),
+			OidcSubject: sub,
+			RoleId:      roleId,
+			Creator:     "SSO",
+		}
+		err = s.tx.Create(&user).Error
+	} else if roleId > 0 && roleId != user.RoleId {
+		// 同步IdP中的角色
+		err = s.tx.Model(&user).Update("role_id", roleId).Error
+	}
+	if err != nil {
+		return user, err
+	}
+	err = s.tx.Preload("Role").Where("id = ?", user.Id).First(&user).Error
+	return user, err
+}
+
+// 按声明映射角色, 未匹配时返回0
+func (s *MysqlService) getOidcRoleId(claims oidc.Claims) (uint, error) {
+	conf := global.Conf.Oidc
+	values := claims.Strings(conf.RoleClaim)
+	for _, mapping := range conf.RoleMappings {
+		if !utils.Contains(values, mapping.Claim) {
+			continue
+		}
+		var role models.SysRole
+		notFound := s.tx.Where("keyword = ?", mapping.Role).First(&role).RecordNotFound()
+		if notFound {
+			return 0, errors.New("SSO角色映射配置错误, 角色不存在: " + mapping.Role)
+		}
+		return role.Id, nil
+	}
+	return 0, nil
+}
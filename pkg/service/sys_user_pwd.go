@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/pwdpolicy"
+	"gin-web/pkg/utils"
+	"time"
+)
+
+// 用户修改密码
+func (s *MysqlService) ChangePwd(userId uint, oldPwd string, newPwd string) error {
+	var user models.SysUser
+	notFound := s.tx.Where("id = ?", userId).First(&user).RecordNotFound()
+	if notFound {
+		return errors.New("记录不存在")
+	}
+	// 校验密码
+	if ok := utils.ComparePwd(oldPwd, user.Password); !ok {
+		return errors.New("原密码错误")
+	}
+	err := s.checkNewPwd(user, newPwd)
+	if err != nil {
+		return err
+	}
+	return s.savePwd(user.Id, utils.GenPwd(newPwd), false)
+}
+
+// 校验新密码: 密码策略以及历史密码
+func (s *MysqlService) checkNewPwd(user models.SysUser, newPwd string) error {
+	policy := global.Conf.PwdPolicy
+	err := pwdpolicy.Check(policy, user.Username, newPwd)
+	if err != nil {
+		return err
+	}
+	if policy.History <= 0 || user.Id == 0 {
+		return nil
+	}
+	reused := fmt.Errorf("新密码不能与最近%d次使用过的密码相同", policy.History)
+	// 历史用户可能没有历史密码记录, 当前密码单独比较
+	if user.Password != "" && utils.ComparePwd(newPwd, user.Password) {
+		return reused
+	}
+	histories := make([]models.SysPwdHistory, 0)
+	err = s.tx.Where("user_id = ?", user.Id).Order("id DESC").Limit(policy.History).Find(&histories).Error
+	if err != nil {
+		return err
+	}
+	for _, history := range histories {
+		if utils.ComparePwd(newPwd, history.Password) {
+			return reused
+		}
+	}
+	return nil
+}
+
+// 保存新密码并记录历史密码, mustChange表示下次登录必须修改(管理员设置的初始密码)
+func (s *MysqlService) savePwd(userId uint, pwd string, mustChange bool) error {
+	err := s.tx.Model(&models.SysUser{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"password":        pwd,
+		"must_change_pwd": mustChange,
+		"pwd_changed_at":  time.Now().Unix(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	return s.createPwdHistory(userId, pwd)
+}
+
+// 记录历史密码, 只保留策略要求的条数
+func (s *MysqlService) createPwdHistory(userId uint, pwd string) error {
+	history := global.Conf.PwdPolicy.History
+	if history <= 0 {
+		return nil
+	}
+	err := s.tx.Create(&models.SysPwdHistory{
+		UserId:   userId,
+		Password: pwd,
+	}).Error
+	if err != nil {
+		return err
+	}
+	// 清理更早的记录
+	var ids []uint
+	err = s.tx.Model(&models.SysPwdHistory{}).Where("user_id = ?", userId).Order("id DESC").Limit(history).Pluck("id", &ids).Error
+	if err != nil || len(ids) < history {
+		return err
+	}
+	return s.tx.Unscoped().Where("user_id = ? AND id NOT IN (?)", userId, ids).Delete(models.SysPwdHistory{}).Error
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/mail"
+	"gin-web/pkg/utils"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 找回密码令牌格式: 用户编号.过期时间.签名
+// 签名包含当前密码密文, 重置(或以其他方式修改)密码后令牌自动失效, 因此只能使用一次
+
+// 发送找回密码邮件, 用户不存在/未设置邮箱时同样返回成功, 避免泄露用户信息
+func (s *MysqlService) SendPwdResetMail(username string) error {
+	conf := global.Conf.PwdReset
+	if !conf.Enable {
+		return errors.New("未开启找回密码")
+	}
+	var user models.SysUser
+	notFound := s.tx.Where("username = ?", username).First(&user).RecordNotFound()
+	// 禁用用户及LDAP/SSO用户(密码不由本系统管理)不能找回密码
+	if notFound || user.Email == "" || (user.Status != nil && !*user.Status) || user.LdapDn != "" || user.OidcSubject != "" {
+		return nil
+	}
+	expiresAt := time.Now().Add(time.Duration(conf.Timeout) * time.Minute).Unix()
+	token := genPwdResetToken(user, expiresAt)
+	sep := "?"
+	if strings.Contains(conf.Url, "?") {
+		sep = "&"
+	}
+	link := conf.Url + sep + "token=" + url.QueryEscape(token)
+	err := global.Mailer.Send(mail.Message{
+		To:      []string{user.Email},
+		Subject: "找回密码",
+		Body:    fmt.Sprintf("%s你好:\n\n请在%d分钟内点击以下链接重置密码, 链接只能使用一次:\n%s\n\n如果不是你本人操作, 请忽略此邮件.", user.Username, conf.Timeout, link),
+	})
+	if err != nil {
+		// 不返回发送结果, 避免泄露用户信息
+		global.Log.Warn("[SendPwdResetMail]", err)
+	}
+	return nil
+}
+
+// 通过找回密码令牌重置密码, 返回用户编号
+func (s *MysqlService) ResetPwd(token string, newPwd string) (uint, error) {
+	invalid := errors.New("重置链接无效或已过期")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, invalid
+	}
+	userId := utils.Str2Uint(parts[0])
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if userId == 0 || err != nil || expiresAt < time.Now().Unix() {
+		return 0, invalid
+	}
+	var user models.SysUser
+	notFound := s.tx.Where("id = ?", userId).First(&user).RecordNotFound()
+	if notFound || !hmac.Equal([]byte(token), []byte(genPwdResetToken(user, expiresAt))) {
+		return 0, invalid
+	}
+	err = s.checkNewPwd(user, newPwd)
+	if err != nil {
+		return 0, err
+	}
+	err = s.savePwd(user.Id, utils.GenPwd(newPwd), false)
+	if err != nil {
+		return 0, err
+	}
+	// 已通过邮箱验证身份, 同时解除登录锁定
+	err = s.tx.Model(&models.SysUser{}).Where("id = ?", user.Id).Update("locked_until", 0).Error
+	if err != nil {
+		return 0, err
+	}
+	// 吊销该用户全部令牌, 需重新登录
+	err = s.RevokeUserTokens(user.Id, "用户找回密码")
+	return user.Id, err
+}
+
+// 生成找回密码令牌
+func genPwdResetToken(user models.SysUser, expiresAt int64) string {
+	payload := fmt.Sprintf("%d.%d", user.Id, expiresAt)
+	mac := hmac.New(sha256.New, []byte(global.Conf.Jwt.Key))
+	mac.Write([]byte(payload + "." + user.Password))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"gin-web/models"
+	"gin-web/pkg/global"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/gobuffalo/packr"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGenPermissions(t *testing.T) {
+	global.ConfBox = packr.NewBox("../../conf")
+	global.Conf.Casbin.ModelPath = "rbac_model.conf"
+	f, err := ioutil.TempFile("", "casbin_policy_*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+	e, err := NewCasbinEnforcer(fileadapter.NewAdapter(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = e.AddPolicies([][]string{
+		{"guest", "0", "/v1/user/info", "GET", "allow"},
+		{"tester", "0", "/v1/user/*", "*", "allow"},
+		{"tester", "0", "/v1/user/delete/batch", "DELETE", "deny"},
+	})
+	_, _ = e.AddGroupingPolicies([][]string{{"leader", "tester", "0"}})
+	menus := []models.SysMenu{
+		{Name: "user"},
+		{Name: "userCreate", Permission: "user:create"},
+		{Name: "userCreate2", Permission: "user:create"},
+	}
+	apis := []models.SysApi{
+		{Method: "GET", Path: "/v1/user/info"},
+		{Method: "POST", Path: "/v1/user/create"},
+		{Method: "DELETE", Path: "/v1/user/delete/batch"},
+		{Method: "GET", Path: "/v1/role/list"},
+	}
+	tests := []struct {
+		name     string
+		keywords []string
+		menus    []models.SysMenu
+		want     []string
+	}{
+		{"case1", []string{"guest"}, nil, []string{"GET:/v1/user/info"}},
+		// 菜单权限标识去重
+		{"case2", []string{"guest"}, menus, []string{"user:create", "GET:/v1/user/info"}},
+		// 继承的角色, 拒绝的接口不生成标识
+		{"case3", []string{"leader"}, nil, []string{"GET:/v1/user/info", "POST:/v1/user/create"}},
+		{"case4", []string{"unknown"}, nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GenPermissions(e, tt.keywords, "0", tt.menus, apis); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GenPermissions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"errors"
+	"gin-web/models"
+	"gin-web/pkg/global"
+	"gin-web/pkg/response"
+	"gin-web/pkg/totp"
+	"gin-web/pkg/utils"
+	"github.com/jinzhu/gorm"
+	"strings"
+	"time"
+)
+
+// 开始绑定二次验证, 生成新密钥(需调用EnableTotp校验验证码后才会生效)
+func (s *MysqlService) EnrollTotp(userId uint) (secret string, url string, err error) {
+	var user models.SysUser
+	query := s.tx.Where("id = ?", userId).First(&user)
+	if query.RecordNotFound() {
+		err = errors.New("记录不存在")
+		return
+	}
+	if user.TotpOn() {
+		err = errors.New("二次验证已开启, 如需更换请先关闭")
+		return
+	}
+	secret = totp.GenSecret()
+	err = query.Updates(map[string]interface{}{
+		"totp_secret":       secret,
+		"totp_last_counter": 0,
+	}).Error
+	url = totp.Url(global.Conf.Totp.Issuer, user.Username, secret)
+	return
+}
+
+// 校验验证码并开启二次验证, 返回恢复码明文(仅返回一次, 数据库保存摘要)
+func (s *MysqlService) EnableTotp(userId uint, code string) ([]string, error) {
+	var user models.SysUser
+	query := s.tx.Where("id = ?", userId).First(&user)
+	if query.RecordNotFound() {
+		return nil, errors.New("记录不存在")
+	}
+	if user.TotpOn() {
+		return nil, errors.New("二次验证已开启")
+	}
+	if user.TotpSecret == "" {
+		return nil, errors.New("请先获取二次验证密钥")
+	}
+	counter, ok := totp.Match(user.TotpSecret, code, time.Now())
+	if !ok {
+		return nil, errors.New("验证码错误")
+	}
+	codes, hashes := genTotpRecoveryCodes(global.Conf.Totp.RecoveryCodes)
+	err := query.Updates(map[string]interface{}{
+		"totp_enabled":        true,
+		"totp_recovery_codes": strings.Join(hashes, ","),
+		"totp_last_counter":   counter,
+	}).Error
+	return codes, err
+}
+
+// 关闭二次验证, 需提供验证码或恢复码
+func (s *MysqlService) DisableTotp(userId uint, code string) error {
+	var user models.SysUser
+	query := s.tx.Where("id = ?", userId).First(&user)
+	if query.RecordNotFound() {
+		return errors.New("记录不存在")
+	}
+	if !user.TotpOn() {
+		return errors.New("二次验证未开启")
+	}
+	err := s.checkTotpCode(s.tx, user, code)
+	if err != nil {
+		return err
+	}
+	return s.resetTotp(userId)
+}
+
+// 重置二次验证(清空密钥与恢复码)
+func (s *MysqlService) resetTotp(userId uint) error {
+	return s.tx.Model(&models.SysUser{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"totp_enabled":        false,
+		"totp_secret":         "",
+		"totp_recovery_codes": "",
+		"totp_last_counter":   0,
+	}).Error
+}
+
+// 登录二次验证, 校验通过返回用户及其角色
+func (s *MysqlService) TotpCheck(userId uint, code string) (models.SysUser, error) {
+	var user models.SysUser
+	err := s.tx.Preload("Role").Where("id = ?", userId).First(&user).Error
+	if err != nil {
+		return user, err
+	}
+	// 验证码错误次数过多, 账号已锁定
+	if user.IsLocked() {
+		return user, errors.New(response.LoginLockedMsg)
+	}
+	if !user.TotpOn() {
+		return user, errors.New("二次验证未开启")
+	}
+	// 恢复码/时间步需立即失效, 即使本次登录最终失败也不能再次使用, 因此这里使用无事务实例
+	err = s.checkTotpCode(s.db, user, code)
+	return user, err
+}
+
+// 校验验证码或恢复码, 通过后记录时间步或作废恢复码, 防止重复使用
+func (s *MysqlService) checkTotpCode(db *gorm.DB, user models.SysUser, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return errors.New("验证码不能为空")
+	}
+	query := db.Model(&models.SysUser{}).Where("id = ?", user.Id)
+	if len(code) == totp.Digits {
+		counter, ok := totp.Match(user.TotpSecret, code, time.Now())
+		if !ok {
+			return errors.New("验证码错误")
+		}
+		// 带上时间步条件, 并发请求中只有一个能够成功
+		res := query.Where("totp_last_counter < ?", counter).Update("totp_last_counter", counter)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("验证码已使用, 请等待下一个验证码")
+		}
+		return nil
+	}
+	// 恢复码
+	hashes := strings.Split(user.TotpRecoveryCodes, ",")
+	hash := utils.Sha256(normalizeTotpRecoveryCode(code))
+	remains := make([]string, 0)
+	found := false
+	for _, item := range hashes {
+		if item == "" {
+			continue
+		}
+		if item == hash && !found {
+			found = true
+			continue
+		}
+		remains = append(remains, item)
+	}
+	if !found {
+		return errors.New("验证码错误")
+	}
+	res := query.Where("totp_recovery_codes = ?", user.TotpRecoveryCodes).Update("totp_recovery_codes", strings.Join(remains, ","))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errors.New("恢复码已使用")
+	}
+	return nil
+}
+
+// 生成恢复码, 返回明文与摘要
+func genTotpRecoveryCodes(n int) (codes []string, hashes []string) {
+	for i := 0; i < n; i++ {
+		token := utils.GenRandomToken(5)
+		// 格式如: 1a2b3-c4d5e, 方便用户抄写
+		code := token[:5] + "-" + token[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, utils.Sha256(normalizeTotpRecoveryCode(code)))
+	}
+	return
+}
+
+// 恢复码忽略大小写与分隔符
+func normalizeTotpRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -1,9 +1,12 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"gin-web/models"
 	"gin-web/pkg/global"
+	"gin-web/pkg/mail"
 	"gin-web/pkg/request"
 	"gin-web/pkg/service/strategy"
 	"gin-web/pkg/utils"
@@ -12,8 +15,12 @@ import (
 	uuid "github.com/satori/go.uuid"
 	"github.com/thedevsaddam/gojsonq/v2"
 	"strings"
+	"time"
 )
 
+// 审批日志状态已被其他操作修改(如定时任务与审批人同时处理)
+var errWorkflowLogChanged = errors.New("审批日志状态已变化, 请刷新后重试")
+
 // 获取工作流(指定审批单类型)
 func (s *MysqlService) GetWorkflowByTargetCategory(targetCategory uint) (models.SysWorkflow, error) {
 	var flow models.SysWorkflow
@@ -106,18 +113,22 @@ func (s *MysqlService) GetWorkflowApprovings(req *request.WorkflowApprovingListR
 		Preload("CurrentLine.Users").
 		Preload("CurrentLine.Role").
 		Preload("CurrentLine.Role.Users").
+		Preload("EscalateRole.Users").
 		Where("status = ?", models.SysWorkflowLogStateSubmit). // 状态已提交
 		Find(&logs).Error
 	if err != nil {
 		return list, err
 	}
 
+	// 委托给当前审批人的审批
+	delegates := s.getActiveDelegates(approval.Id)
+	now := time.Now().Unix()
 	for _, log := range logs {
 		// 获取当前待审批人
 		userIds := s.getApprovingUsers(log)
 		log.ApprovingUserIds = userIds
-		// 包含当前审批人
-		if utils.ContainsUint(userIds, approval.Id) {
+		// 包含当前审批人(或委托人)
+		if _, ok := models.WorkflowActingUser(userIds, approval.Id, log.FlowId, delegates, now); ok {
 			list = append(list, log)
 		}
 	}
@@ -136,41 +147,118 @@ func (s *MysqlService) GetWorkflowApprovings(req *request.WorkflowApprovingListR
 
 // 查询下一审批人(指定目标)
 func (s *MysqlService) GetWorkflowNextApprovingUsers(flowId uint, targetId uint) ([]models.SysUser, error) {
-	// 查询需要审核的日志
-	var log models.SysWorkflowLog
+	// 查询需要审核的日志(并行分支可能有多条)
 	users := make([]models.SysUser, 0)
-	err := s.tx.
-		Preload("CurrentLine").
-		Preload("CurrentLine.Users").
-		Preload("CurrentLine.Role").
-		Preload("CurrentLine.Role.Users").
-		Where(&models.SysWorkflowLog{
-			FlowId:   flowId,   // 流程号一致
-			TargetId: targetId, // 目标一致
-		}).Where(
-		"status = ?", models.SysWorkflowLogStateSubmit, // 状态已提交
-	).First(&log).Error
+	logs, err := s.getPendingLogs(flowId, targetId)
 	if err != nil {
 		return users, err
 	}
+	if len(logs) == 0 {
+		return users, gorm.ErrRecordNotFound
+	}
 
 	// 获取当前待审批人
-	userIds := s.getApprovingUsers(log)
+	userIds := make([]uint, 0)
+	for _, log := range logs {
+		for _, userId := range s.getApprovingUsers(log) {
+			if !utils.ContainsUint(userIds, userId) {
+				userIds = append(userIds, userId)
+			}
+		}
+	}
 	err = s.tx.Where("id IN (?)", userIds).Find(&users).Error
 	return users, err
 }
 
+// 查询待审批日志(指定目标), 并行分支会同时存在多条
+func (s *MysqlService) getPendingLogs(flowId uint, targetId uint) ([]models.SysWorkflowLog, error) {
+	logs := make([]models.SysWorkflowLog, 0)
+	err := s.tx.
+		Preload("CurrentLine").
+		Preload("CurrentLine.Users").
+		Preload("CurrentLine.Role").
+		Preload("CurrentLine.Role.Users").
+		Preload("EscalateRole.Users").
+		Preload("Flow").
+		Where("flow_id = ? AND target_id = ?", flowId, targetId).
+		Where("status = ?", models.SysWorkflowLogStateSubmit). // 状态已提交
+		Order("id").
+		Find(&logs).Error
+	return logs, err
+}
+
 // 查询审批日志(指定目标)
 func (s *MysqlService) GetWorkflowLogs(flowId uint, targetId uint) ([]models.SysWorkflowLog, error) {
 	// 查询已审核的日志
 	logs := make([]models.SysWorkflowLog, 0)
-	err := s.tx.Preload("ApprovalUser").Preload("SubmitUser").Preload("Flow").Where(&models.SysWorkflowLog{
+	err := s.tx.Preload("ApprovalUser").Preload("OnBehalfOfUser").Preload("AssigneeUser").Preload("SubmitUser").Preload("Flow").Where(&models.SysWorkflowLog{
 		FlowId:   flowId,   // 流程号一致
 		TargetId: targetId, // 目标一致
 	}).Find(&logs).Error
 	return logs, err
 }
 
+// 查询流程图(流水线+连线)
+func (s *MysqlService) GetWorkflowGraph(flowId uint) (models.SysWorkflowGraph, error) {
+	lines := make([]models.SysWorkflowLine, 0)
+	edges := make([]models.SysWorkflowEdge, 0)
+	err := s.tx.Where("flow_id = ?", flowId).Find(&lines).Error
+	if err != nil {
+		return models.SysWorkflowGraph{}, err
+	}
+	err = s.tx.Where("flow_id = ?", flowId).Find(&edges).Error
+	if err != nil {
+		return models.SysWorkflowGraph{}, err
+	}
+	return models.NewWorkflowGraph(lines, edges), nil
+}
+
+// 查询流程连线
+func (s *MysqlService) GetWorkflowEdges(flowId uint) ([]models.SysWorkflowEdge, error) {
+	edges := make([]models.SysWorkflowEdge, 0)
+	err := s.tx.Where("flow_id = ?", flowId).Find(&edges).Error
+	return edges, err
+}
+
+// 更新流程连线(全量替换), 连线为空时按流水线排序依次流转
+func (s *MysqlService) UpdateWorkflowEdges(req *request.UpdateWorkflowEdgeRequestStruct) (err error) {
+	var flow models.SysWorkflow
+	if s.tx.Where("id = ?", req.FlowId).First(&flow).RecordNotFound() {
+		return fmt.Errorf("流程不存在")
+	}
+	lines := make([]models.SysWorkflowLine, 0)
+	err = s.tx.Where("flow_id = ?", flow.Id).Find(&lines).Error
+	if err != nil {
+		return
+	}
+	edges := make([]models.SysWorkflowEdge, 0)
+	for _, item := range req.Edges {
+		edges = append(edges, models.SysWorkflowEdge{
+			FlowId:     flow.Id,
+			FromLineId: item.FromLineId,
+			ToLineId:   item.ToLineId,
+			Condition:  strings.TrimSpace(item.Condition),
+			IsDefault:  item.IsDefault,
+		})
+	}
+	// 校验流程图
+	err = models.NewWorkflowGraph(lines, edges).Validate()
+	if err != nil {
+		return
+	}
+	err = s.tx.Where("flow_id = ?", flow.Id).Delete(models.SysWorkflowEdge{}).Error
+	if err != nil {
+		return
+	}
+	for _, edge := range edges {
+		err = s.tx.Create(&edge).Error
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 // 查询下一流水线
 func (s *MysqlService) GetNextWorkflowLine(flowId uint, currentSort uint) (models.SysWorkflowLine, error) {
 	return s.GetWorkflowLineBySort(flowId, currentSort+1)
@@ -241,6 +329,12 @@ func (s *MysqlService) UpdateWorkflowLineByIncremental(req *request.UpdateWorkfl
 	if err != nil {
 		return
 	}
+	// 超时升级必须指定角色
+	for _, item := range append(req.Create, req.Update...) {
+		if item.TimeoutAction == models.SysWorkflowLineTimeoutEscalate && item.EscalateRoleId == 0 {
+			return fmt.Errorf("流水线[%s]超时升级需要指定角色", item.Name)
+		}
+	}
 	// 查询增改的所有用户/流水线
 	userIds := make([]uint, 0)
 	for _, item := range req.Create {
@@ -271,6 +365,11 @@ func (s *MysqlService) UpdateWorkflowLineByIncremental(req *request.UpdateWorkfl
 		if err != nil {
 			return
 		}
+		// 删除流水线相关的连线
+		err = s.tx.Where("from_line_id = ? OR to_line_id = ?", item.Id, item.Id).Delete(models.SysWorkflowEdge{}).Error
+		if err != nil {
+			return
+		}
 	}
 	// 2. 更新流水线
 	for _, item := range req.Update {
@@ -293,6 +392,13 @@ func (s *MysqlService) UpdateWorkflowLineByIncremental(req *request.UpdateWorkfl
 			// 需要强制更新roleId
 			query = query.Update("role_id", item.RoleId)
 		}
+		// 审批时限可以设置为0(不处理), 需要强制更新
+		query = query.Updates(map[string]interface{}{
+			"remind_hours":     item.RemindHours,
+			"timeout_hours":    item.TimeoutHours,
+			"timeout_action":   item.TimeoutAction,
+			"escalate_role_id": item.EscalateRoleId,
+		})
 		// 更新数据, 替换users
 		err = query.Update(&line).Association("Users").Replace(&us).Error
 		if err != nil {
@@ -347,7 +453,12 @@ func (s *MysqlService) UpdateWorkflowLineByIncremental(req *request.UpdateWorkfl
 		}
 		sort += 1
 	}
-	return
+	// 校验流程图, 从开始出发的每条路径都必须能到达结束
+	graph, err := s.GetWorkflowGraph(req.FlowId)
+	if err != nil {
+		return
+	}
+	return graph.Validate()
 }
 
 // 工作流流转(从一个状态转移到另一个状态)
@@ -358,14 +469,19 @@ func (s *MysqlService) WorkflowTransition(req *request.WorkflowTransitionRequest
 	if req.TargetId == 0 {
 		return fmt.Errorf("目标表编号不存在, flowId=%d", req.TargetId)
 	}
+	// 锁定目标的审批日志, 并行分支同时审批时依次执行, 避免都认为其他分支未完成导致流程无法继续
+	err := s.lockWorkflowLogs(req.FlowId, req.TargetId)
+	if err != nil {
+		return err
+	}
 	// 查询最后一条审批日志, 判断是否存在
 	var lastLog models.SysWorkflowLog
-	var err error
 	notFound := s.tx.
 		Preload("CurrentLine").
 		Preload("CurrentLine.Users").
 		Preload("CurrentLine.Role").
 		Preload("CurrentLine.Role.Users").
+		Preload("EscalateRole.Users").
 		Preload("Flow").
 		Where(&models.SysWorkflowLog{
 			TargetId: req.TargetId,
@@ -378,6 +494,11 @@ func (s *MysqlService) WorkflowTransition(req *request.WorkflowTransitionRequest
 			return err
 		}
 	} else {
+		// 并行分支存在多条待审批日志, 找到本次审批的日志
+		lastLog, err = s.getTransitionLog(req, lastLog)
+		if err != nil {
+			return err
+		}
 		// 走审批逻辑
 		err = s.next(req, lastLog)
 		if err != nil {
@@ -385,13 +506,8 @@ func (s *MysqlService) WorkflowTransition(req *request.WorkflowTransitionRequest
 		}
 	}
 
-	// 查询最后一条审批日志
-	var newLastLog models.SysWorkflowLog
-	err = s.tx.
-		Where(&models.SysWorkflowLog{
-			TargetId: req.TargetId,
-			FlowId:   req.FlowId,
-		}).Last(&newLastLog).Error
+	// 查询目标当前状态对应的日志
+	newLastLog, err := s.getStateLog(req.FlowId, req.TargetId)
 	if err != nil {
 		return err
 	}
@@ -405,6 +521,56 @@ func (s *MysqlService) WorkflowTransition(req *request.WorkflowTransitionRequest
 	return ctx.Strategy.UpdateTarget()
 }
 
+// 锁定目标的全部审批日志(SELECT ... FOR UPDATE), 直到事务结束
+func (s *MysqlService) lockWorkflowLogs(flowId uint, targetId uint) error {
+	ids := make([]uint, 0)
+	return s.lockQuery().
+		Model(&models.SysWorkflowLog{}).
+		Where("flow_id = ? AND target_id = ?", flowId, targetId).
+		Pluck("id", &ids).Error
+}
+
+// 加锁查询(SELECT ... FOR UPDATE), 读取最新提交的数据而不是事务快照, 用于判断并行分支状态
+// 不能用于包含Preload的查询, 否则关联表也会被锁定
+func (s *MysqlService) lockQuery() *gorm.DB {
+	return s.tx.Set("gorm:query_option", "FOR UPDATE")
+}
+
+// 获取本次审批对应的日志: 优先使用指定流水线的待审批日志, 其次为审批人有权限的待审批日志, 没有待审批日志时为最后一条日志
+func (s *MysqlService) getTransitionLog(req *request.WorkflowTransitionRequestStruct, lastLog models.SysWorkflowLog) (models.SysWorkflowLog, error) {
+	logs, err := s.getPendingLogs(req.FlowId, req.TargetId)
+	if err != nil || len(logs) == 0 {
+		return lastLog, err
+	}
+	if req.LineId > 0 {
+		for _, log := range logs {
+			if log.CurrentLineId == req.LineId {
+				return log, nil
+			}
+		}
+		return lastLog, fmt.Errorf("流水线不在审批中, lineId=%d", req.LineId)
+	}
+	for _, log := range logs {
+		if s.checkPermission(req.ApprovalUserId, log) {
+			return log, nil
+		}
+	}
+	// 无权限审批(如提交人取消), 使用最后一条待审批日志
+	return logs[len(logs)-1], nil
+}
+
+// 获取目标当前状态对应的日志: 存在待审批日志时为最后一条待审批日志, 否则为最后一条日志
+func (s *MysqlService) getStateLog(flowId uint, targetId uint) (models.SysWorkflowLog, error) {
+	var log models.SysWorkflowLog
+	query := s.tx.Where("flow_id = ? AND target_id = ?", flowId, targetId)
+	notFound := query.Where("status = ?", models.SysWorkflowLogStateSubmit).Last(&log).RecordNotFound()
+	if !notFound {
+		return log, nil
+	}
+	err := query.Last(&log).Error
+	return log, err
+}
+
 // 初次提交流程工单
 func (s *MysqlService) first(req *request.WorkflowTransitionRequestStruct) error {
 	if req.SubmitUserId == 0 {
@@ -434,14 +600,26 @@ func (s *MysqlService) first(req *request.WorkflowTransitionRequestStruct) error
 	firstLog.ApprovalOpinion = approvalOpinion
 	// 创建首条日志
 	s.tx.Create(&firstLog)
-	// 获取下一流水线
-	nextLine, err := s.GetNextWorkflowLine(req.FlowId, 0)
+	// 获取开始后的流水线(多条时并行审批)
+	graph, err := s.GetWorkflowGraph(req.FlowId)
 	if err != nil {
 		return err
 	}
-	// 状态为提交, 当前流水线指向下一流水线, 创建新日志
-	err = s.newLog(models.SysWorkflowLogStateSubmit, nextLine.Id, firstLog)
-	return err
+	if len(graph.StartLines()) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	startLineIds, err := s.getRouteLines(req.TargetCategory, graph, 0, firstLog)
+	if err != nil {
+		return err
+	}
+	for _, lineId := range startLineIds {
+		// 状态为提交, 当前流水线指向下一流水线, 创建新日志
+		err = s.newLog(models.SysWorkflowLogStateSubmit, lineId, firstLog)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // 第二次提交流程工单
@@ -449,6 +627,10 @@ func (s *MysqlService) next(req *request.WorkflowTransitionRequestStruct, lastLo
 	if *lastLog.Status == models.SysWorkflowLogStateEnd {
 		return fmt.Errorf("流程已结束")
 	}
+	if req.System {
+		// 系统自动审批
+		return s.system(req, lastLog)
+	}
 	if req.ApprovalUserId == 0 {
 		return fmt.Errorf("审批人不存在, approvalUserId=%d", req.ApprovalUserId)
 	}
@@ -466,6 +648,20 @@ func (s *MysqlService) next(req *request.WorkflowTransitionRequestStruct, lastLo
 	}
 }
 
+// 系统自动审批(超时), 不校验审批人权限, 通过时直接流转到后续流水线
+func (s *MysqlService) system(req *request.WorkflowTransitionRequestStruct, lastLog models.SysWorkflowLog) error {
+	if *lastLog.Status != models.SysWorkflowLogStateSubmit || lastLog.CurrentLineId == 0 {
+		return fmt.Errorf("流程不在审批中, 无法自动审批")
+	}
+	approval := workflowSystemUser()
+	if *req.ApprovalStatus == models.SysWorkflowLogStateApproval {
+		// 通过
+		return s.approval(req, approval, lastLog)
+	}
+	// 回退到上一流水线
+	return s.deny(req, approval, lastLog)
+}
+
 // 开始自我审批
 func (s *MysqlService) selfStart(req *request.WorkflowTransitionRequestStruct, approval models.SysUser, lastLog models.SysWorkflowLog) error {
 	var err error
@@ -479,7 +675,11 @@ func (s *MysqlService) selfStart(req *request.WorkflowTransitionRequestStruct, a
 			approvalOpinion = "提交人主动取消"
 		}
 		err := s.updateLog(models.SysWorkflowLogStateCancel, approvalOpinion, approval, lastLog)
-		return err
+		if err != nil {
+			return err
+		}
+		// 同时取消其他并行分支
+		return s.closePendingLogs(models.SysWorkflowLogStateCancel, approvalOpinion, approval, lastLog)
 	} else if *lastLog.Status == models.SysWorkflowLogStateCancel {
 		// 提交人再次重启
 		if *req.ApprovalStatus == models.SysWorkflowLogStateRestart {
@@ -491,19 +691,28 @@ func (s *MysqlService) selfStart(req *request.WorkflowTransitionRequestStruct, a
 			return s.first(req)
 		}
 	}
-	var nextLine models.SysWorkflowLine
 	// 获取下一流水线
-	if lastLog.CurrentLineId > 0 && !*lastLog.CurrentLine.End {
-		nextLine, err = s.GetNextWorkflowLine(req.FlowId, lastLog.CurrentLine.Sort)
+	graph, err := s.GetWorkflowGraph(req.FlowId)
+	if err != nil {
+		return err
+	}
+	nextLineIds := make([]uint, 0)
+	if lastLog.CurrentLineId > 0 {
+		nextLineIds, err = s.getRouteLines(req.TargetCategory, graph, lastLog.CurrentLineId, lastLog)
 		if err != nil {
 			return err
 		}
 	}
 	// 1. 未结束 且 开启自我审批 且 有权限审批
 	if !*lastLog.End && *lastLog.Flow.Self && s.checkPermission(approval.Id, lastLog) {
+		if isWorkflowAssignStatus(*req.ApprovalStatus) {
+			// 转交/加签
+			return s.assign(req, approval, lastLog)
+		}
 		if *req.ApprovalStatus == models.SysWorkflowLogStateApproval {
-			if nextLine.Id == 0 {
-				return s.end(false, req.ApprovalOpinion, approval, lastLog)
+			if len(nextLineIds) == 0 && lastLog.AssignStatus != models.SysWorkflowLogStateAddBefore {
+				// 当前分支到达结束
+				return s.forward(req, approval, lastLog, graph, nextLineIds)
 			}
 			// 通过
 			return s.approval(req, approval, lastLog)
@@ -515,7 +724,7 @@ func (s *MysqlService) selfStart(req *request.WorkflowTransitionRequestStruct, a
 	// 2.开启提交人确认
 	if *lastLog.Flow.SubmitUserConfirm {
 		// 下一流水线为空
-		if nextLine.Id == 0 {
+		if len(nextLineIds) == 0 {
 			var log models.SysWorkflowLog
 			err = s.tx.
 				Where(&models.SysWorkflowLog{
@@ -544,6 +753,9 @@ func (s *MysqlService) start(req *request.WorkflowTransitionRequestStruct, appro
 		if *req.ApprovalStatus == models.SysWorkflowLogStateApproval {
 			// 通过
 			return s.approval(req, approval, lastLog)
+		} else if isWorkflowAssignStatus(*req.ApprovalStatus) {
+			// 转交/加签
+			return s.assign(req, approval, lastLog)
 		} else {
 			// 回退到上一流水线
 			return s.deny(req, approval, lastLog)
@@ -554,52 +766,208 @@ func (s *MysqlService) start(req *request.WorkflowTransitionRequestStruct, appro
 
 // 通过审批, 流转到下一流水线
 func (s *MysqlService) approval(req *request.WorkflowTransitionRequestStruct, approval models.SysUser, lastLog models.SysWorkflowLog) error {
-	// 默认流水线不变
-	lineId := lastLog.CurrentLineId
-	if s.checkNextLineSort(approval.Id, lastLog) {
+	if lastLog.AssignStatus == models.SysWorkflowLogStateAddBefore {
+		// 前加签的审批人审批完成, 回到当前流水线
+		return s.assignBack(req, approval, lastLog)
+	}
+	if req.System || s.checkNextLineSort(approval.Id, lastLog) {
 		// 流转到下一流水线
-		var err error
-		var nextLine models.SysWorkflowLine
-		if !*lastLog.CurrentLine.End {
-			// 获取下一流水线
-			nextLine, err = s.GetNextWorkflowLine(req.FlowId, lastLog.CurrentLine.Sort)
-			if err != nil {
-				return err
-			}
-		}
-		// 下一流水线为空, 直接结束
-		if nextLine.Id == 0 {
-			return s.end(false, req.ApprovalOpinion, approval, lastLog)
-		}
-		// 更新日志
-		err = s.updateLog(models.SysWorkflowLogStateApproval, req.ApprovalOpinion, approval, lastLog)
+		graph, err := s.GetWorkflowGraph(req.FlowId)
 		if err != nil {
 			return err
 		}
-		// 当前流水线指向下一流水线
-		lineId = nextLine.Id
-	} else {
-		// 保留当前流水线, 还需其他人继续审批
-		err := s.updateLog(models.SysWorkflowLogStateApproval, req.ApprovalOpinion, approval, lastLog)
+		nextLineIds, err := s.getRouteLines(req.TargetCategory, graph, lastLog.CurrentLineId, lastLog)
 		if err != nil {
 			return err
 		}
+		return s.forward(req, approval, lastLog, graph, nextLineIds)
+	}
+	// 保留当前流水线, 还需其他人继续审批
+	err := s.updateLog(models.SysWorkflowLogStateApproval, req.ApprovalOpinion, approval, lastLog)
+	if err != nil {
+		return err
 	}
 	// 状态为提交, 创建新日志
-	err := s.newLog(models.SysWorkflowLogStateSubmit, lineId, lastLog)
-	return err
+	return s.newLog(models.SysWorkflowLogStateSubmit, lastLog.CurrentLineId, lastLog)
+}
+
+// 是否为转交/加签操作
+func isWorkflowAssignStatus(status uint) bool {
+	return status == models.SysWorkflowLogStateTransfer ||
+		status == models.SysWorkflowLogStateAddBefore ||
+		status == models.SysWorkflowLogStateAddAfter
+}
+
+// 转交/加签: 记录当前审批人的操作, 在同一流水线创建只有指定用户可以审批的日志
+// 转交: 指定用户代替当前审批人审批; 前加签: 指定用户审批后回到当前流水线; 后加签: 当前审批人通过, 指定用户审批后再继续流转
+func (s *MysqlService) assign(req *request.WorkflowTransitionRequestStruct, approval models.SysUser, lastLog models.SysWorkflowLog) error {
+	status := *req.ApprovalStatus
+	if req.AssigneeUserId == 0 {
+		return fmt.Errorf("请指定%s的用户", models.SysWorkflowLogStateConst[status])
+	}
+	if lastLog.CurrentLineId == 0 {
+		return fmt.Errorf("提交人确认不能%s", models.SysWorkflowLogStateConst[status])
+	}
+	if lastLog.Countersign() {
+		return fmt.Errorf("加签的审批人不能再转交或加签")
+	}
+	// 发起人: 当前审批人(或委托人/转交前的审批人)
+	fromUserId, _ := s.getActingUser(approval.Id, lastLog)
+	if req.AssigneeUserId == approval.Id || req.AssigneeUserId == fromUserId {
+		return fmt.Errorf("不能%s给自己", models.SysWorkflowLogStateConst[status])
+	}
+	var assignee models.SysUser
+	if s.tx.Where("id = ?", req.AssigneeUserId).First(&assignee).RecordNotFound() {
+		return fmt.Errorf("%s的用户不存在", models.SysWorkflowLogStateConst[status])
+	}
+	approvalOpinion := req.ApprovalOpinion
+	if strings.TrimSpace(approvalOpinion) == "" {
+		approvalOpinion = fmt.Sprintf("%s给%s", models.SysWorkflowLogStateConst[status], assignee.Nickname)
+	}
+	err := s.updateLog(status, approvalOpinion, approval, lastLog)
+	if err != nil {
+		return err
+	}
+	// 状态为提交, 创建指定审批人的新日志
+	var newLog models.SysWorkflowLog
+	submitStatus := models.SysWorkflowLogStateSubmit
+	newLog.FlowId = lastLog.FlowId
+	newLog.TargetId = lastLog.TargetId
+	newLog.CurrentLineId = lastLog.CurrentLineId
+	newLog.Status = &submitStatus
+	newLog.End = lastLog.End
+	newLog.SubmitUserId = lastLog.SubmitUserId
+	newLog.SubmitDetail = lastLog.SubmitDetail
+	newLog.PrevLogId = lastLog.Id
+	newLog.EscalateRoleId = lastLog.EscalateRoleId
+	newLog.AssigneeUserId = assignee.Id
+	newLog.AssignFromUserId = fromUserId
+	newLog.AssignStatus = status
+	return s.tx.Create(&newLog).Error
+}
+
+// 前加签的审批人审批完成(通过或拒绝仅作为参考意见), 回到当前流水线继续审批
+func (s *MysqlService) assignBack(req *request.WorkflowTransitionRequestStruct, approval models.SysUser, lastLog models.SysWorkflowLog) error {
+	err := s.updateLog(*req.ApprovalStatus, req.ApprovalOpinion, approval, lastLog)
+	if err != nil {
+		return err
+	}
+	// 状态为提交, 创建新日志
+	return s.newLog(models.SysWorkflowLogStateSubmit, lastLog.CurrentLineId, lastLog)
+}
+
+// 当前流水线审批完成, 流转到后续流水线, 没有需要继续审批的流水线时结束
+func (s *MysqlService) forward(req *request.WorkflowTransitionRequestStruct, approval models.SysUser, lastLog models.SysWorkflowLog, graph models.SysWorkflowGraph, nextLineIds []uint) error {
+	lineIds, err := s.getActivateLines(req.TargetCategory, graph, nextLineIds, lastLog)
+	if err != nil {
+		return err
+	}
+	// 没有后续流水线 且 没有其他并行分支, 直接结束
+	if len(lineIds) == 0 && s.countOtherPendingLogs(lastLog) == 0 {
+		return s.end(false, req.ApprovalOpinion, approval, lastLog)
+	}
+	// 更新日志
+	err = s.updateLog(models.SysWorkflowLogStateApproval, req.ApprovalOpinion, approval, lastLog)
+	if err != nil {
+		return err
+	}
+	for _, lineId := range lineIds {
+		// 状态为提交, 当前流水线指向下一流水线, 创建新日志
+		err = s.newPendingLog(lineId, lastLog)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 获取需要开始审批的流水线: 后续流水线中的普通流水线及已就绪的汇合流水线
+// 以及其他分支已到达并在等待的汇合流水线(当前分支未到达该汇合流水线, 如条件不满足改走其他路径)
+func (s *MysqlService) getActivateLines(targetCategory uint, graph models.SysWorkflowGraph, nextLineIds []uint, lastLog models.SysWorkflowLog) ([]uint, error) {
+	lineIds := make([]uint, 0)
+	for _, lineId := range nextLineIds {
+		// 汇合流水线需要等待全部前置分支完成
+		if !graph.IsJoin(lineId) || s.checkJoinReady(graph, lineId, lastLog) {
+			lineIds = append(lineIds, lineId)
+		}
+	}
+	for _, lineId := range graph.Joins() {
+		if utils.ContainsUint(nextLineIds, lineId) {
+			continue
+		}
+		reached, err := s.checkJoinReached(targetCategory, graph, lineId, lastLog)
+		if err != nil {
+			return lineIds, err
+		}
+		if reached && s.checkJoinReady(graph, lineId, lastLog) {
+			lineIds = append(lineIds, lineId)
+		}
+	}
+	return lineIds, nil
+}
+
+// 获取后续流水线(根据流转条件), 为空表示当前分支到达结束
+func (s *MysqlService) getRouteLines(targetCategory uint, graph models.SysWorkflowGraph, lineId uint, log models.SysWorkflowLog) ([]uint, error) {
+	env, err := s.getWorkflowConditionEnv(targetCategory, graph, log)
+	if err != nil {
+		return nil, err
+	}
+	return graph.Route(lineId, env)
+}
+
+// 获取流转条件变量: 审批目标的字段, 提交明细(submitDetail, 为json对象时同时展开其字段), 以及提交人(user)
+// 未配置流转条件时无需查询
+func (s *MysqlService) getWorkflowConditionEnv(targetCategory uint, graph models.SysWorkflowGraph, log models.SysWorkflowLog) (map[string]interface{}, error) {
+	env := make(map[string]interface{})
+	if !graph.HasConditions() {
+		return env, nil
+	}
+	detail := make(map[string]interface{})
+	if json.Unmarshal([]byte(log.SubmitDetail), &detail) == nil {
+		for key, value := range detail {
+			env[key] = value
+		}
+	}
+	target, err := strategy.NewConditionTarget(s.tx, targetCategory, log.TargetId)
+	if err != nil {
+		return env, err
+	}
+	for key, value := range target {
+		env[key] = value
+	}
+	env["submitDetail"] = log.SubmitDetail
+	submitUser, err := s.GetUserById(log.SubmitUserId)
+	if err != nil {
+		return env, err
+	}
+	roles := make([]interface{}, 0)
+	for _, keyword := range submitUser.RoleKeywords() {
+		roles = append(roles, keyword)
+	}
+	env["user"] = map[string]interface{}{
+		"id":       float64(submitUser.Id),
+		"username": submitUser.Username,
+		"nickname": submitUser.Nickname,
+		"deptId":   float64(submitUser.DeptId),
+		"role":     submitUser.Role.Keyword,
+		"roles":    roles,
+	}
+	return env, nil
 }
 
 // 拒绝审批, 回退到上一流水线
 func (s *MysqlService) deny(req *request.WorkflowTransitionRequestStruct, approval models.SysUser, lastLog models.SysWorkflowLog) error {
+	if lastLog.AssignStatus == models.SysWorkflowLogStateAddBefore {
+		// 前加签的审批人审批完成, 回到当前流水线
+		return s.assignBack(req, approval, lastLog)
+	}
 	// 流转到上一流水线
-	// 获取上一流水线
-	if lastLog.CurrentLine.Sort <= 1 {
-		// 上一流水线不存在, 说明拒绝到最初提交状态
-		err := s.updateLog(models.SysWorkflowLogStateDeny, req.ApprovalOpinion, approval, lastLog)
+	// 获取上一流水线(汇合流水线有多个)
+	graph, err := s.GetWorkflowGraph(req.FlowId)
+	if err != nil {
 		return err
 	}
-	prevLine, err := s.GetPrevWorkflowLine(req.FlowId, lastLog.CurrentLine.Sort)
+	prevLineIds, err := s.getDenyLines(req.TargetCategory, graph, lastLog)
 	if err != nil {
 		return err
 	}
@@ -608,9 +976,139 @@ func (s *MysqlService) deny(req *request.WorkflowTransitionRequestStruct, approv
 	if err != nil {
 		return err
 	}
-	// 状态为提交,当前流水线指向上一流水线, 创建新日志
-	err = s.newLog(models.SysWorkflowLogStateSubmit, prevLine.Id, lastLog)
-	return err
+	if len(prevLineIds) == 0 {
+		// 上一流水线不存在, 说明拒绝到最初提交状态, 其他并行分支同时结束
+		return s.closePendingLogs(models.SysWorkflowLogStateDeny, "并行分支已被拒绝", approval, lastLog)
+	}
+	for _, lineId := range prevLineIds {
+		// 状态为提交,当前流水线指向上一流水线, 创建新日志
+		err = s.newPendingLog(lineId, lastLog)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 获取拒绝后回退的流水线: 满足流转条件且本次流程经过的前置流水线
+func (s *MysqlService) getDenyLines(targetCategory uint, graph models.SysWorkflowGraph, lastLog models.SysWorkflowLog) ([]uint, error) {
+	lineIds := make([]uint, 0)
+	prevLineIds := graph.PrevLines(lastLog.CurrentLineId)
+	if len(prevLineIds) == 0 {
+		return lineIds, nil
+	}
+	env, err := s.getWorkflowConditionEnv(targetCategory, graph, lastLog)
+	if err != nil {
+		return lineIds, err
+	}
+	for _, lineId := range prevLineIds {
+		ok, err := graph.Match(lineId, lastLog.CurrentLineId, env)
+		if err != nil {
+			return lineIds, err
+		}
+		if ok && s.getLastLineLog(lastLog, lineId).Id > 0 {
+			lineIds = append(lineIds, lineId)
+		}
+	}
+	return lineIds, nil
+}
+
+// 检查汇合流水线是否可以开始审批: 全部上游流水线(当前流水线除外)均没有待审批日志, 即不再有分支会到达
+func (s *MysqlService) checkJoinReady(graph models.SysWorkflowGraph, lineId uint, lastLog models.SysWorkflowLog) bool {
+	var count int
+	err := s.lockQuery().
+		Model(&models.SysWorkflowLog{}).
+		Where("flow_id = ? AND target_id = ? AND id <> ?", lastLog.FlowId, lastLog.TargetId, lastLog.Id).
+		Where("current_line_id IN (?)", graph.Ancestors(lineId)).
+		Where("status = ?", models.SysWorkflowLogStateSubmit).
+		Count(&count).Error
+	if err != nil {
+		global.Log.Warn("[checkJoinReady]", err)
+		return false
+	}
+	return count == 0
+}
+
+// 检查是否有其他分支已到达汇合流水线: 前置流水线在汇合流水线上次审批之后已通过, 且满足流转条件
+func (s *MysqlService) checkJoinReached(targetCategory uint, graph models.SysWorkflowGraph, lineId uint, lastLog models.SysWorkflowLog) (bool, error) {
+	joinLog := s.getLastLineLog(lastLog, lineId)
+	for _, prevLineId := range graph.PrevLines(lineId) {
+		if prevLineId == lastLog.CurrentLineId {
+			continue
+		}
+		prevLog := s.getLastLineLog(lastLog, prevLineId)
+		if prevLog.Id == 0 || prevLog.Id < joinLog.Id || *prevLog.Status != models.SysWorkflowLogStateApproval {
+			continue
+		}
+		env, err := s.getWorkflowConditionEnv(targetCategory, graph, lastLog)
+		if err != nil {
+			return false, err
+		}
+		ok, err := graph.Match(prevLineId, lineId, env)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// 查询指定流水线的最后一条日志(同一目标), 不存在时编号为0
+func (s *MysqlService) getLastLineLog(lastLog models.SysWorkflowLog, lineId uint) models.SysWorkflowLog {
+	var log models.SysWorkflowLog
+	s.lockQuery().
+		Where("flow_id = ? AND target_id = ? AND current_line_id = ?", lastLog.FlowId, lastLog.TargetId, lineId).
+		Last(&log)
+	return log
+}
+
+// 创建指定流水线的待审批日志, 已存在时不重复创建
+func (s *MysqlService) newPendingLog(lineId uint, lastLog models.SysWorkflowLog) error {
+	var count int
+	err := s.lockQuery().
+		Model(&models.SysWorkflowLog{}).
+		Where("flow_id = ? AND target_id = ? AND current_line_id = ?", lastLog.FlowId, lastLog.TargetId, lineId).
+		Where("status = ?", models.SysWorkflowLogStateSubmit).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+	return s.newLog(models.SysWorkflowLogStateSubmit, lineId, lastLog)
+}
+
+// 统计其他并行分支的待审批日志数
+func (s *MysqlService) countOtherPendingLogs(lastLog models.SysWorkflowLog) int {
+	var count int
+	err := s.lockQuery().
+		Model(&models.SysWorkflowLog{}).
+		Where("flow_id = ? AND target_id = ? AND id <> ?", lastLog.FlowId, lastLog.TargetId, lastLog.Id).
+		Where("status = ?", models.SysWorkflowLogStateSubmit).
+		Count(&count).Error
+	if err != nil {
+		global.Log.Warn("[countOtherPendingLogs]", err)
+	}
+	return count
+}
+
+// 结束其他并行分支的待审批日志
+func (s *MysqlService) closePendingLogs(status uint, approvalOpinion string, approval models.SysUser, lastLog models.SysWorkflowLog) error {
+	logs, err := s.getPendingLogs(lastLog.FlowId, lastLog.TargetId)
+	if err != nil {
+		return err
+	}
+	for _, log := range logs {
+		if log.Id == lastLog.Id {
+			continue
+		}
+		err = s.updateLog(status, approvalOpinion, approval, log)
+		if err == errWorkflowLogChanged {
+			// 已被其他操作关闭
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // 结束审批, 末尾流水线
@@ -659,8 +1157,26 @@ func (s *MysqlService) updateLog(status uint, approvalOpinion string, approval m
 	// 审批人以及意见
 	updateLog.ApprovalUserId = approval.Id
 	updateLog.ApprovalOpinion = approvalOpinion
-	err := s.tx.Table(updateLog.TableName()).Where("id = ?", lastLog.Id).Update(&updateLog).Error
-	return err
+	if approval.Id == 0 {
+		// 系统操作
+		updateLog.Actor = models.SysWorkflowActorSystem
+	} else if userId, ok := s.getActingUser(approval.Id, lastLog); ok && userId != approval.Id {
+		// 被委托人代为审批
+		updateLog.OnBehalfOfUserId = userId
+	}
+	query := s.tx.Table(updateLog.TableName()).Where("id = ?", lastLog.Id)
+	if lastLog.Status != nil {
+		// 条件更新, 状态与读取时不一致说明已被其他操作处理
+		query = query.Where("status = ?", *lastLog.Status)
+	}
+	query = query.Update(&updateLog)
+	if query.Error != nil {
+		return query.Error
+	}
+	if query.RowsAffected != 1 {
+		return errWorkflowLogChanged
+	}
+	return nil
 }
 
 // 创建新的日志
@@ -679,34 +1195,71 @@ func (s *MysqlService) newLog(status uint, lineId uint, lastLog models.SysWorkfl
 	newLog.SubmitUserId = lastLog.SubmitUserId
 	// 提交人
 	newLog.SubmitDetail = lastLog.SubmitDetail
+	if lineId > 0 && lineId == lastLog.CurrentLineId {
+		// 同一流水线还需其他人继续审批, 关联上一条日志
+		newLog.PrevLogId = lastLog.Id
+		// 已升级的角色继续有审批权限
+		newLog.EscalateRoleId = lastLog.EscalateRoleId
+	}
 	// 创建数据
 	err := s.tx.Create(&newLog).Error
 	return err
 }
 
-// 检查当前审批人是否有权限
+// 检查当前审批人是否有权限(本人或被委托人)
 func (s *MysqlService) checkPermission(approvalUserId uint, lastLog models.SysWorkflowLog) bool {
+	_, ok := s.getActingUser(approvalUserId, lastLog)
+	return ok
+}
+
+// 获取审批人代表的待审批人: 审批人本身或委托给审批人的待审批人
+func (s *MysqlService) getActingUser(approvalUserId uint, lastLog models.SysWorkflowLog) (uint, bool) {
 	// 获取当前待审批人
 	userIds := s.getApprovingUsers(lastLog)
-	return utils.ContainsUint(userIds, approvalUserId)
+	userId, ok := approvalUserId, utils.ContainsUint(userIds, approvalUserId)
+	if !ok {
+		userId, ok = models.WorkflowActingUser(userIds, approvalUserId, lastLog.FlowId, s.getActiveDelegates(approvalUserId), time.Now().Unix())
+	}
+	if ok && lastLog.AssignStatus == models.SysWorkflowLogStateTransfer {
+		// 转交的审批人代替发起转交的审批人
+		userId = lastLog.AssignFromUserId
+	}
+	return userId, ok
+}
+
+// 获取委托给指定用户且正在生效的审批委托
+func (s *MysqlService) getActiveDelegates(delegateUserId uint) []models.SysWorkflowDelegate {
+	delegates := make([]models.SysWorkflowDelegate, 0)
+	now := time.Now().Unix()
+	err := s.tx.
+		Where("delegate_user_id = ? AND start_at <= ? AND end_at > ?", delegateUserId, now, now).
+		Find(&delegates).Error
+	if err != nil {
+		global.Log.Warn("[getActiveDelegates]", err)
+	}
+	return delegates
 }
 
 // 检查是否可以切换流水线到下一个(通过审批会使用)
 func (s *MysqlService) checkNextLineSort(approvalUserId uint, lastLog models.SysWorkflowLog) bool {
-	// 获取当前待审批人
-	userIds := s.getApprovingUsers(lastLog)
+	// 当前审批人(或委托人)在待审批列表中
+	_, ok := s.getActingUser(approvalUserId, lastLog)
 	// 判断流程类别
 	switch lastLog.Flow.Category {
 	case models.SysWorkflowCategoryOnlyOneApproval:
 		// 只需要1人通过: 当前审批人在待审批列表中
-		return utils.ContainsUint(userIds, approvalUserId)
+		return ok
 	case models.SysWorkflowCategoryAllApproval:
 		// 查询全部审批人数
 		allUserIds := s.getAllApprovalUsers(lastLog)
 		// 查询历史审批人数
 		historyUserIds := s.getHistoryApprovalUsers(lastLog)
+		if lastLog.Countersign() {
+			// 加签的审批人不是流水线审批人: 历史审批人刚好等于全部审批人
+			return ok && len(historyUserIds) >= len(allUserIds)
+		}
 		// 需要全部人通过: 当前审批人在待审批列表中 且 历史审批人+当前审批人刚好等于全部审批人
-		return utils.ContainsUint(userIds, approvalUserId) && len(historyUserIds) >= len(allUserIds)-1
+		return ok && len(historyUserIds) >= len(allUserIds)-1
 	}
 	return false
 }
@@ -714,6 +1267,10 @@ func (s *MysqlService) checkNextLineSort(approvalUserId uint, lastLog models.Sys
 // 获取待审批人(当前流水线)
 func (s *MysqlService) getApprovingUsers(log models.SysWorkflowLog) []uint {
 	userIds := make([]uint, 0)
+	if log.AssigneeUserId > 0 {
+		// 转交/加签时只有指定审批人可以审批
+		return append(userIds, log.AssigneeUserId)
+	}
 	allUserIds := s.getAllApprovalUsers(log)
 	historyUserIds := s.getHistoryApprovalUsers(log)
 	for _, allUserId := range allUserIds {
@@ -728,29 +1285,29 @@ func (s *MysqlService) getApprovingUsers(log models.SysWorkflowLog) []uint {
 // 获取历史审批人(最后一个流水线, 主要用于判断是否审批完成)
 func (s *MysqlService) getHistoryApprovalUsers(log models.SysWorkflowLog) []uint {
 	historyUserIds := make([]uint, 0)
-	// 查询已审核的日志
-	logs := make([]models.SysWorkflowLog, 0)
-	err := s.tx.Where(&models.SysWorkflowLog{
-		FlowId:   log.FlowId,   // 流程号一致
-		TargetId: log.TargetId, // 目标一致
-	}).Where(
-		"status > ?", models.SysWorkflowLogStateSubmit, // 状态非提交
-	).Order("id DESC").Find(&logs).Error
-	if err != nil {
-		global.Log.Warn("[getHistoryApprovalUsers]", err)
-	}
-	// 保留连续审核通过记录
-	l := len(logs)
-	for i := 0; i < l; i++ {
-		item := logs[i]
-		// 如果不是通过立即结束, 必须保证连续的通过 或 当前流水线不一致
-		if *item.Status != models.SysWorkflowLogStateApproval || item.CurrentLineId != log.CurrentLineId {
+	// 沿上一条日志查找同一流水线连续审核通过记录(并行分支的日志会交错, 不能按编号顺序查找)
+	prevLogId := log.PrevLogId
+	for prevLogId > 0 {
+		var item models.SysWorkflowLog
+		err := s.tx.Where("id = ?", prevLogId).First(&item).Error
+		if err != nil {
+			global.Log.Warn("[getHistoryApprovalUsers]", err)
+			break
+		}
+		// 当前流水线不一致立即结束
+		if item.CurrentLineId != log.CurrentLineId {
+			break
+		}
+		// 必须保证连续的通过(升级/转交/加签不影响审批进度)
+		userId, next := item.HistoryApprovalUser()
+		if !next {
 			break
 		}
 		// 审批人为配置中的一人
-		if !utils.ContainsUint(historyUserIds, item.ApprovalUserId) {
-			historyUserIds = append(historyUserIds, item.ApprovalUserId)
+		if userId > 0 && !utils.ContainsUint(historyUserIds, userId) {
+			historyUserIds = append(historyUserIds, userId)
 		}
+		prevLogId = item.PrevLogId
 	}
 	return historyUserIds
 }
@@ -774,6 +1331,147 @@ func (s *MysqlService) getAllApprovalUsers(log models.SysWorkflowLog) []uint {
 				}
 			}
 		}
+		if log.EscalateRoleId > 0 {
+			// 超时升级的角色
+			for _, user := range log.EscalateRole.Users {
+				if user.Id > 0 && !utils.ContainsUint(userIds, user.Id) {
+					userIds = append(userIds, user.Id)
+				}
+			}
+		}
 	}
 	return userIds
 }
+
+// 系统操作使用的审批人(编号为0, 日志操作方记为system)
+func workflowSystemUser() models.SysUser {
+	return models.SysUser{
+		Username: models.SysWorkflowActorSystem,
+		Nickname: "系统",
+	}
+}
+
+// 查询到达审批时限的待审批日志(不区分租户), 由定时任务调用
+func (s *MysqlService) GetWorkflowSlaLogs(now time.Time) ([]models.SysWorkflowLog, error) {
+	logs := make([]models.SysWorkflowLog, 0)
+	list := make([]models.SysWorkflowLog, 0)
+	err := s.tx.
+		Preload("CurrentLine").
+		Where("status = ? AND current_line_id > 0", models.SysWorkflowLogStateSubmit).
+		Order("id").
+		Find(&logs).Error
+	if err != nil {
+		return list, err
+	}
+	for _, log := range logs {
+		if _, ok := log.CurrentLine.SlaAction(log, now); ok {
+			list = append(list, log)
+		}
+	}
+	return list, nil
+}
+
+// 处理到达审批时限的待审批日志: 提醒审批人/升级给指定角色/自动通过/自动拒绝
+// 重新查询日志状态, 多实例同时执行时已处理的日志会被跳过
+func (s *MysqlService) HandleWorkflowSla(logId uint, now time.Time) error {
+	var log models.SysWorkflowLog
+	// 先加锁读取, 多个实例同时扫描时只有一个能处理
+	if s.lockQuery().Where("id = ? AND status = ?", logId, models.SysWorkflowLogStateSubmit).First(&log).RecordNotFound() {
+		return nil
+	}
+	notFound := s.tx.
+		Preload("CurrentLine").
+		Preload("CurrentLine.Users").
+		Preload("CurrentLine.Role").
+		Preload("CurrentLine.Role.Users").
+		Preload("EscalateRole.Users").
+		Preload("Flow").
+		Where("id = ? AND status = ?", logId, models.SysWorkflowLogStateSubmit).
+		First(&log).RecordNotFound()
+	if notFound {
+		return nil
+	}
+	action, ok := log.CurrentLine.SlaAction(log, now)
+	if !ok {
+		return nil
+	}
+	var err error
+	switch action {
+	case models.SysWorkflowLineTimeoutEscalate:
+		err = s.escalateLog(log)
+	case models.SysWorkflowLineTimeoutApproval, models.SysWorkflowLineTimeoutDeny:
+		status := models.SysWorkflowLogStateApproval
+		approvalOpinion := fmt.Sprintf("超过%d小时未审批, 系统自动通过", log.CurrentLine.TimeoutHours)
+		if action == models.SysWorkflowLineTimeoutDeny {
+			status = models.SysWorkflowLogStateDeny
+			approvalOpinion = fmt.Sprintf("超过%d小时未审批, 系统自动拒绝", log.CurrentLine.TimeoutHours)
+		}
+		err = s.WorkflowTransition(&request.WorkflowTransitionRequestStruct{
+			FlowId:          log.FlowId,
+			TargetCategory:  log.Flow.TargetCategory,
+			TargetId:        log.TargetId,
+			LineId:          log.CurrentLineId,
+			ApprovalStatus:  &status,
+			ApprovalOpinion: approvalOpinion,
+			System:          true,
+		})
+	default:
+		return s.remindLog(log)
+	}
+	if err == errWorkflowLogChanged {
+		// 日志已被审批人或其他实例处理, 跳过
+		return nil
+	}
+	return err
+}
+
+// 超时升级: 当前日志标记为升级(仅待审批状态可以更新), 在同一流水线创建新的待审批日志, 升级角色的用户也可以审批
+func (s *MysqlService) escalateLog(lastLog models.SysWorkflowLog) error {
+	var role models.SysRole
+	if s.tx.Where("id = ?", lastLog.CurrentLine.EscalateRoleId).First(&role).RecordNotFound() {
+		return fmt.Errorf("超时升级的角色不存在, roleId=%d", lastLog.CurrentLine.EscalateRoleId)
+	}
+	approvalOpinion := fmt.Sprintf("超过%d小时未审批, 升级给角色[%s]", lastLog.CurrentLine.TimeoutHours, role.Name)
+	err := s.updateLog(models.SysWorkflowLogStateEscalate, approvalOpinion, workflowSystemUser(), lastLog)
+	if err != nil {
+		return err
+	}
+	lastLog.EscalateRoleId = role.Id
+	return s.newLog(models.SysWorkflowLogStateSubmit, lastLog.CurrentLineId, lastLog)
+}
+
+// 超时提醒: 标记已提醒后邮件通知待审批人(只提醒一次)
+func (s *MysqlService) remindLog(log models.SysWorkflowLog) error {
+	query := s.tx.
+		Model(&models.SysWorkflowLog{}).
+		Where("id = ? AND reminded_at = 0", log.Id).
+		Update("reminded_at", time.Now().Unix())
+	if query.Error != nil || query.RowsAffected == 0 {
+		return query.Error
+	}
+	users := make([]models.SysUser, 0)
+	err := s.tx.Where("id IN (?)", s.getApprovingUsers(log)).Find(&users).Error
+	if err != nil {
+		return err
+	}
+	to := make([]string, 0)
+	for _, user := range users {
+		if strings.TrimSpace(user.Email) != "" {
+			to = append(to, user.Email)
+		}
+	}
+	if len(to) == 0 {
+		return nil
+	}
+	return global.Mailer.Send(mail.Message{
+		To:      to,
+		Subject: fmt.Sprintf("[%s]审批提醒", log.Flow.Name),
+		Body: fmt.Sprintf(
+			"您有一条待审批工单已超过%d小时未处理, 请尽快审批\n流程: %s\n流水线: %s\n提交明细: %s",
+			log.CurrentLine.RemindHours,
+			log.Flow.Name,
+			log.CurrentLine.Name,
+			log.SubmitDetail,
+		),
+	})
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"gin-web/models"
+	"gin-web/pkg/request"
+	"time"
+)
+
+// 获取审批委托列表(委托人或被委托人为指定用户)
+func (s *MysqlService) GetWorkflowDelegates(req *request.WorkflowDelegateListRequestStruct) ([]models.SysWorkflowDelegate, error) {
+	var err error
+	list := make([]models.SysWorkflowDelegate, 0)
+	query := s.tx.
+		Model(&models.SysWorkflowDelegate{}).
+		Preload("User").
+		Preload("DelegateUser").
+		Preload("Flow").
+		Where("user_id = ? OR delegate_user_id = ?", req.CurrentUserId, req.CurrentUserId)
+	if req.UserId > 0 {
+		query = query.Where("user_id = ?", req.UserId)
+	}
+	if req.DelegateUserId > 0 {
+		query = query.Where("delegate_user_id = ?", req.DelegateUserId)
+	}
+	if req.FlowId > 0 {
+		query = query.Where("flow_id = ?", req.FlowId)
+	}
+	if req.Active != nil {
+		now := time.Now().Unix()
+		if *req.Active {
+			query = query.Where("start_at <= ? AND end_at > ?", now, now)
+		} else {
+			query = query.Where("NOT (start_at <= ? AND end_at > ?)", now, now)
+		}
+	}
+	// 按id逆序
+	query = query.Order("id DESC")
+	// 查询条数
+	err = query.Count(&req.PageInfo.Total).Error
+	if err == nil {
+		if req.PageInfo.NoPagination {
+			// 不使用分页
+			err = query.Find(&list).Error
+		} else {
+			// 获取分页参数
+			limit, offset := req.GetLimit()
+			err = query.Limit(limit).Offset(offset).Find(&list).Error
+		}
+	}
+	return list, err
+}
+
+// 创建审批委托(委托人为当前用户)
+func (s *MysqlService) CreateWorkflowDelegate(req *request.CreateWorkflowDelegateRequestStruct, user models.SysUser) (err error) {
+	now := time.Now().Unix()
+	startAt := req.StartAt
+	if startAt <= 0 {
+		startAt = now
+	}
+	if req.EndAt <= startAt || req.EndAt <= now {
+		return errors.New("失效时间必须晚于生效时间及当前时间")
+	}
+	if req.DelegateUserId == user.Id {
+		return errors.New("不能委托给自己")
+	}
+	var delegateUser models.SysUser
+	if s.tx.Where("id = ?", req.DelegateUserId).First(&delegateUser).RecordNotFound() {
+		return errors.New("被委托人不存在")
+	}
+	if req.FlowId > 0 {
+		var flow models.SysWorkflow
+		if s.tx.Where("id = ?", req.FlowId).First(&flow).RecordNotFound() {
+			return errors.New("流程不存在")
+		}
+	}
+	err = s.tx.Create(&models.SysWorkflowDelegate{
+		UserId:         user.Id,
+		DelegateUserId: delegateUser.Id,
+		FlowId:         req.FlowId,
+		StartAt:        startAt,
+		EndAt:          req.EndAt,
+		Reason:         req.Reason,
+		Creator:        req.Creator,
+	}).Error
+	return
+}
+
+// 批量删除审批委托(只能删除自己的委托)
+func (s *MysqlService) DeleteWorkflowDelegateByIds(ids []uint, user models.SysUser) (err error) {
+	return s.tx.Where("id IN (?) AND user_id = ?", ids, user.Id).Delete(models.SysWorkflowDelegate{}).Error
+}
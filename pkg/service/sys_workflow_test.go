@@ -8,6 +8,7 @@ import (
 	"gin-web/tests"
 	uuid "github.com/satori/go.uuid"
 	"math/rand"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -1971,3 +1972,230 @@ func TestMysqlService_GetWorkflowLineLogs(t *testing.T) {
 	}
 	fmt.Println(logs, res, err)
 }
+
+// 分支/汇合测试流程: 流水线A/B/C/D各1个审批人, 提交人提交请假单
+type testWorkflowGraph struct {
+	flow      models.SysWorkflow
+	lineIds   []uint
+	approvers []models.SysUser
+	submitter models.SysUser
+	leave     models.SysLeave
+}
+
+// 创建分支/汇合测试流程, edges根据流水线A/B/C/D的编号生成连线
+func newTestWorkflowGraph(t *testing.T, s *MysqlService, testName string, days float64, edges func(a, b, c, d uint) []request.WorkflowEdgeRequestStruct) testWorkflowGraph {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var g testWorkflowGraph
+	users := make([]models.SysUser, 5)
+	for i := range users {
+		users[i] = models.SysUser{
+			Username: fmt.Sprintf("%s用户名%d", testName, r.Intn(1000000)),
+			Nickname: fmt.Sprintf("%s用户昵称%d", testName, r.Intn(1000000)),
+			Creator:  "系统",
+		}
+		s.tx.Create(&users[i])
+	}
+	g.approvers = users[:4]
+	g.submitter = users[4]
+	falsePtr := false
+	g.flow = models.SysWorkflow{
+		Uuid:              uuid.NewV4().String(),
+		Category:          models.SysWorkflowCategoryOnlyOneApproval, // 只需要有1个人通过
+		TargetCategory:    models.SysWorkflowTargetCategoryLeave,
+		SubmitUserConfirm: &falsePtr, // 不需要提交人确认
+		Self:              &falsePtr, // 不能自我审批
+		Name:              fmt.Sprintf("%s工作流程%d", testName, r.Intn(1000000)),
+		Creator:           "系统",
+	}
+	s.tx.Create(&g.flow)
+	req := request.UpdateWorkflowLineIncrementalRequestStruct{
+		FlowId: g.flow.Id,
+	}
+	for i, user := range g.approvers {
+		req.Create = append(req.Create, request.UpdateWorkflowLineRequestStruct{
+			FlowId:  g.flow.Id,
+			Name:    fmt.Sprintf("流水线%c", 'A'+i),
+			UserIds: []uint{user.Id},
+		})
+	}
+	err := s.UpdateWorkflowLineByIncremental(&req)
+	if err != nil {
+		t.Fatalf("创建流水线失败: %v", err)
+	}
+	lines := make([]models.SysWorkflowLine, 0)
+	s.tx.Where("flow_id = ?", g.flow.Id).Order("sort").Find(&lines)
+	for _, line := range lines {
+		g.lineIds = append(g.lineIds, line.Id)
+	}
+	if len(g.lineIds) != 4 {
+		t.Fatalf("流水线数量不正确: %v", g.lineIds)
+	}
+	err = s.UpdateWorkflowEdges(&request.UpdateWorkflowEdgeRequestStruct{
+		FlowId: g.flow.Id,
+		Edges:  edges(g.lineIds[0], g.lineIds[1], g.lineIds[2], g.lineIds[3]),
+	})
+	if err != nil {
+		t.Fatalf("创建连线失败: %v", err)
+	}
+	g.leave = models.SysLeave{
+		UserId: g.submitter.Id,
+		Desc:   testName,
+		Days:   days,
+	}
+	s.tx.Create(&g.leave)
+	err = s.WorkflowTransition(&request.WorkflowTransitionRequestStruct{
+		FlowId:         g.flow.Id,
+		TargetCategory: models.SysWorkflowTargetCategoryLeave,
+		TargetId:       g.leave.Id,
+		SubmitUserId:   g.submitter.Id,
+	})
+	if err != nil {
+		t.Fatalf("提交审批失败: %v", err)
+	}
+	return g
+}
+
+// 流水线(0:A 1:B 2:C 3:D)的审批人审批
+func (g testWorkflowGraph) transition(s *MysqlService, line int, status uint) error {
+	return s.WorkflowTransition(&request.WorkflowTransitionRequestStruct{
+		FlowId:          g.flow.Id,
+		TargetCategory:  models.SysWorkflowTargetCategoryLeave,
+		TargetId:        g.leave.Id,
+		ApprovalUserId:  g.approvers[line].Id,
+		ApprovalOpinion: fmt.Sprintf("流水线%c审批", 'A'+line),
+		ApprovalStatus:  &status,
+	})
+}
+
+// 当前待审批的流水线
+func (g testWorkflowGraph) pendingLineIds(s *MysqlService) []uint {
+	ids := make([]uint, 0)
+	logs, _ := s.getPendingLogs(g.flow.Id, g.leave.Id)
+	for _, log := range logs {
+		ids = append(ids, log.CurrentLineId)
+	}
+	return ids
+}
+
+// 最后一条日志的状态
+func (g testWorkflowGraph) lastStatus(s *MysqlService) uint {
+	var log models.SysWorkflowLog
+	s.tx.Where("flow_id = ? AND target_id = ?", g.flow.Id, g.leave.Id).Last(&log)
+	if log.Status == nil {
+		return 0
+	}
+	return *log.Status
+}
+
+// 并行分支汇合: A通过后B/C并行审批, B/C都通过后才到D
+func TestMysqlService_WorkflowTransitionJoin(t *testing.T) {
+	tests.InitTestEnv()
+	s := New(nil)
+	g := newTestWorkflowGraph(t, &s, "并行汇合", 1, func(a, b, c, d uint) []request.WorkflowEdgeRequestStruct {
+		return []request.WorkflowEdgeRequestStruct{
+			{ToLineId: a},
+			{FromLineId: a, ToLineId: b},
+			{FromLineId: a, ToLineId: c},
+			{FromLineId: b, ToLineId: d},
+			{FromLineId: c, ToLineId: d},
+			{FromLineId: d},
+		}
+	})
+	a, b, c, d := g.lineIds[0], g.lineIds[1], g.lineIds[2], g.lineIds[3]
+	steps := []struct {
+		name    string
+		line    int
+		status  uint
+		pending []uint
+	}{
+		{"case1", 0, approval, []uint{b, c}},
+		{"case2", 1, approval, []uint{c}},
+		{"case3", 2, approval, []uint{d}},
+		{"case4", 3, approval, []uint{}},
+	}
+	if got := g.pendingLineIds(&s); !reflect.DeepEqual(got, []uint{a}) {
+		t.Fatalf("提交后待审批流水线 = %v, want %v", got, []uint{a})
+	}
+	for _, tt := range steps {
+		if err := g.transition(&s, tt.line, tt.status); err != nil {
+			t.Fatalf("%s: WorkflowTransition() error = %v", tt.name, err)
+		}
+		if got := g.pendingLineIds(&s); !reflect.DeepEqual(got, tt.pending) {
+			t.Fatalf("%s: 待审批流水线 = %v, want %v", tt.name, got, tt.pending)
+		}
+	}
+	if got := g.lastStatus(&s); got != end {
+		t.Errorf("最后状态 = %d, want %d", got, end)
+	}
+}
+
+// 并行分支拒绝: 开始后B/C并行审批, B拒绝回到提交人, C同时结束
+func TestMysqlService_WorkflowTransitionJoinDeny(t *testing.T) {
+	tests.InitTestEnv()
+	s := New(nil)
+	g := newTestWorkflowGraph(t, &s, "并行拒绝", 1, func(a, b, c, d uint) []request.WorkflowEdgeRequestStruct {
+		return []request.WorkflowEdgeRequestStruct{
+			{ToLineId: b},
+			{ToLineId: c},
+			{FromLineId: b, ToLineId: d},
+			{FromLineId: c, ToLineId: d},
+			{FromLineId: d},
+		}
+	})
+	b, c := g.lineIds[1], g.lineIds[2]
+	if got := g.pendingLineIds(&s); !reflect.DeepEqual(got, []uint{b, c}) {
+		t.Fatalf("提交后待审批流水线 = %v, want %v", got, []uint{b, c})
+	}
+	if err := g.transition(&s, 1, deny); err != nil {
+		t.Fatalf("WorkflowTransition() error = %v", err)
+	}
+	if got := g.pendingLineIds(&s); len(got) != 0 {
+		t.Errorf("拒绝后待审批流水线 = %v, want []", got)
+	}
+	// 另一分支不能再审批
+	if err := g.transition(&s, 2, approval); err == nil {
+		t.Errorf("拒绝后其他分支审批 error = nil, want error")
+	}
+	var log models.SysWorkflowLog
+	s.tx.Where("flow_id = ? AND target_id = ? AND current_line_id = ?", g.flow.Id, g.leave.Id, c).Last(&log)
+	if log.Status == nil || *log.Status != deny {
+		t.Errorf("分支C状态 = %v, want %d", log.Status, deny)
+	}
+}
+
+// 条件分支汇合: A之后超过3天走B, 否则默认走C, B/C汇合到D, 只经过C时也能到达D
+func TestMysqlService_WorkflowTransitionConditionalJoin(t *testing.T) {
+	tests.InitTestEnv()
+	s := New(nil)
+	g := newTestWorkflowGraph(t, &s, "条件汇合", 1, func(a, b, c, d uint) []request.WorkflowEdgeRequestStruct {
+		return []request.WorkflowEdgeRequestStruct{
+			{ToLineId: a},
+			{FromLineId: a, ToLineId: b, Condition: "days > 3"},
+			{FromLineId: a, ToLineId: c, IsDefault: true},
+			{FromLineId: b, ToLineId: d},
+			{FromLineId: c, ToLineId: d},
+			{FromLineId: d},
+		}
+	})
+	c, d := g.lineIds[2], g.lineIds[3]
+	steps := []struct {
+		name    string
+		line    int
+		pending []uint
+	}{
+		{"case1", 0, []uint{c}},
+		{"case2", 2, []uint{d}},
+		{"case3", 3, []uint{}},
+	}
+	for _, tt := range steps {
+		if err := g.transition(&s, tt.line, approval); err != nil {
+			t.Fatalf("%s: WorkflowTransition() error = %v", tt.name, err)
+		}
+		if got := g.pendingLineIds(&s); !reflect.DeepEqual(got, tt.pending) {
+			t.Fatalf("%s: 待审批流水线 = %v, want %v", tt.name, got, tt.pending)
+		}
+	}
+	if got := g.lastStatus(&s); got != end {
+		t.Errorf("最后状态 = %d, want %d", got, end)
+	}
+}
@@ -0,0 +1,61 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B测试向量(SHA1, 8位验证码取后6位)
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{"case1", 59, "287082"},
+		{"case2", 1111111109, "081804"},
+		{"case3", 1111111111, "050471"},
+		{"case4", 1234567890, "005924"},
+		{"case5", 2000000000, "279037"},
+		{"case6", 20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(secret, time.Unix(tt.unix, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Code() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := GenSecret()
+	now := time.Now()
+	code, _ := Code(secret, now)
+	prev, _ := Code(secret, now.Add(-Period*time.Second))
+	old, _ := Code(secret, now.Add(-5*Period*time.Second))
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"case1", code, true},
+		{"case2", prev, true},
+		{"case3", old, old == code || old == prev},
+		{"case4", "", false},
+		{"case5", "12345", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(secret, tt.code, now); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
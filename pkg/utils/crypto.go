@@ -1,6 +1,12 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+)
 
 // 生成密码, 由于使用自适应hash算法, 不可逆
 func GenPwd(str string) string {
@@ -17,3 +23,18 @@ func ComparePwd(str string, pwd string) bool {
 	}
 	return true
 }
+
+// 生成随机令牌(十六进制), n为随机字节数
+func GenRandomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("[GenRandomToken]生成随机数失败: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// 计算sha256摘要(十六进制), 用于保存令牌等无需解密的敏感数据
+func Sha256(str string) string {
+	sum := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(sum[:])
+}
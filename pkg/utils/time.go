@@ -0,0 +1,8 @@
+package utils
+
+import "time"
+
+// 获取unix毫秒时间戳
+func UnixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -9,10 +9,11 @@ import (
 
 // 接口路由
 func InitApiRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
-	router := r.Group("api").Use(authMiddleware.MiddlewareFunc()).Use(middleware.CasbinMiddleware)
+	router := r.Group("api").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
 	{
 		router.GET("/list", v1.GetApis)
 		router.GET("/all/category/:roleId", v1.GetAllApiGroupByCategoryByRoleId)
+		router.GET("/explain", v1.ExplainPermission)
 		router.POST("/create", v1.CreateApi)
 		router.PATCH("/update/:apiId", v1.UpdateApiById)
 		router.DELETE("/delete/batch", v1.BatchDeleteApiByIds)
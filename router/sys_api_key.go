@@ -0,0 +1,19 @@
+package router
+
+import (
+	v1 "gin-web/api/v1"
+	"gin-web/middleware"
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// API密钥路由
+func InitApiKeyRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
+	router := r.Group("apiKey").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
+	{
+		router.GET("/list", v1.GetApiKeys)
+		router.POST("/create", v1.CreateApiKey)
+		router.DELETE("/delete/batch", v1.BatchDeleteApiKeyByIds)
+	}
+	return router
+}
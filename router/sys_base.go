@@ -1,6 +1,7 @@
 package router
 
 import (
+	"gin-web/middleware"
 	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/gin-gonic/gin"
 )
@@ -10,8 +11,13 @@ func InitBaseRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R
 	router := r.Group("base")
 	{
 		router.POST("/login", authMiddleware.LoginHandler)
-		router.POST("/logout", authMiddleware.LogoutHandler)
-		router.POST("/refresh_token", authMiddleware.RefreshHandler)
+		// 登出需要解析当前令牌, 用于服务端吊销
+		router.POST("/logout", authMiddleware.MiddlewareFunc(), authMiddleware.LogoutHandler)
+		// 使用刷新令牌(非访问令牌)换取新令牌
+		router.POST("/refresh_token", middleware.RefreshTokenHandler(authMiddleware))
+		// SSO登录
+		router.GET("/oidc/login", middleware.OidcLoginHandler)
+		router.POST("/oidc/callback", middleware.OidcCallbackHandler(authMiddleware))
 	}
 	return router
 }
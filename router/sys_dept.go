@@ -0,0 +1,20 @@
+package router
+
+import (
+	v1 "gin-web/api/v1"
+	"gin-web/middleware"
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// 部门路由
+func InitDeptRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
+	router := r.Group("dept").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
+	{
+		router.GET("/list", v1.GetDepts)
+		router.POST("/create", v1.CreateDept)
+		router.PATCH("/update/:deptId", v1.UpdateDeptById)
+		router.DELETE("/delete/batch", v1.BatchDeleteDeptByIds)
+	}
+	return router
+}
@@ -9,13 +9,15 @@ import (
 
 // 菜单路由
 func InitMenuRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
-	router := r.Group("menu").Use(authMiddleware.MiddlewareFunc()).Use(middleware.CasbinMiddleware)
+	router := r.Group("menu").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
 	{
 		router.GET("/tree", v1.GetMenuTree)
 		router.GET("/all/:roleId", v1.GetAllMenuByRoleId)
 		router.GET("/list", v1.GetMenus)
 		router.POST("/create", v1.CreateMenu)
 		router.PATCH("/update/:menuId", v1.UpdateMenuById)
+		router.GET("/apis/:menuId", v1.GetMenuApisById)
+		router.PATCH("/apis/update/:menuId", v1.UpdateMenuApisById)
 		router.DELETE("/delete/batch", v1.BatchDeleteMenuByIds)
 	}
 	return router
@@ -1,6 +1,7 @@
 package router
 
 import (
+	v1 "gin-web/api/v1"
 	"github.com/gin-gonic/gin"
 )
 
@@ -8,7 +9,9 @@ import (
 func InitPublicRouter(r *gin.RouterGroup) (R gin.IRoutes) {
 	router := r.Group("public")
 	{
-
+		// 找回密码
+		router.POST("/pwd/reset/mail", v1.SendPwdResetMail)
+		router.POST("/pwd/reset", v1.ResetPwd)
 	}
 	return router
 }
@@ -0,0 +1,19 @@
+package router
+
+import (
+	v1 "gin-web/api/v1"
+	"gin-web/middleware"
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// 权限配置包路由
+func InitRbacRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
+	router := r.Group("rbac").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
+	{
+		router.GET("/export", v1.ExportRbac)
+		router.POST("/diff", v1.DiffRbac)
+		router.POST("/apply", v1.ApplyRbac)
+	}
+	return router
+}
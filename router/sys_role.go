@@ -9,13 +9,15 @@ import (
 
 // 角色路由
 func InitRoleRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
-	router := r.Group("role").Use(authMiddleware.MiddlewareFunc()).Use(middleware.CasbinMiddleware)
+	router := r.Group("role").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
 	{
 		router.GET("/list", v1.GetRoles)
 		router.POST("/create", v1.CreateRole)
 		router.PATCH("/update/:roleId", v1.UpdateRoleById)
 		router.PATCH("/menus/update/:roleId", v1.UpdateRoleMenusById)
 		router.PATCH("/apis/update/:roleId", v1.UpdateRoleApisById)
+		router.GET("/parents/:roleId", v1.GetRoleParentsById)
+		router.PATCH("/parents/update/:roleId", v1.UpdateRoleParentsById)
 		router.DELETE("/delete/batch", v1.BatchDeleteRoleByIds)
 	}
 	return router
@@ -0,0 +1,20 @@
+package router
+
+import (
+	v1 "gin-web/api/v1"
+	"gin-web/middleware"
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// 临时授权路由
+func InitRoleGrantRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
+	router := r.Group("role/grant").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
+	{
+		router.GET("/list", v1.GetRoleGrants)
+		router.GET("/log/list", v1.GetRoleGrantLogs)
+		router.POST("/create", v1.CreateRoleGrant)
+		router.PATCH("/revoke/batch", v1.BatchRevokeRoleGrantByIds)
+	}
+	return router
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	v1 "gin-web/api/v1"
+	"gin-web/middleware"
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// 登录会话路由
+func InitSessionRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
+	router := r.Group("session").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
+	{
+		// 当前用户的会话
+		router.GET("/list", v1.GetSessions)
+		router.DELETE("/delete/batch", v1.BatchKillSessions)
+		// 在线用户
+		router.GET("/online/list", v1.GetOnlineSessions)
+		router.DELETE("/online/delete/batch", v1.BatchKillOnlineSessions)
+	}
+	return router
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	v1 "gin-web/api/v1"
+	"gin-web/middleware"
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// 租户路由
+func InitTenantRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
+	router := r.Group("tenant").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
+	{
+		router.GET("/list", v1.GetTenants)
+		router.POST("/create", v1.CreateTenant)
+		router.PATCH("/update/:tenantId", v1.UpdateTenantById)
+		router.DELETE("/delete/batch", v1.BatchDeleteTenantByIds)
+	}
+	return router
+}
@@ -9,7 +9,7 @@ import (
 
 // 用户路由
 func InitUserRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
-	router := r.Group("user").Use(authMiddleware.MiddlewareFunc()).Use(middleware.CasbinMiddleware)
+	router := r.Group("user").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
 	{
 		router.POST("/info", v1.GetUserInfo)
 		router.GET("/list", v1.GetUsers)
@@ -17,6 +17,11 @@ func InitUserRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R
 		router.POST("/create", v1.CreateUser)
 		router.PATCH("/update/:userId", v1.UpdateUserById)
 		router.DELETE("/delete/batch", v1.BatchDeleteUserByIds)
+		router.POST("/token/revoke/:userId", v1.RevokeUserTokensById)
+		router.PATCH("/unlock/:userId", v1.UnlockUserById)
+		router.POST("/totp/enroll", v1.EnrollTotp)
+		router.POST("/totp/verify", v1.VerifyTotp)
+		router.POST("/totp/disable", v1.DisableTotp)
 	}
 	return router
 }
@@ -9,16 +9,21 @@ import (
 
 // 工作流路由
 func InitWorkflowRouter(r *gin.RouterGroup, authMiddleware *jwt.GinJWTMiddleware) (R gin.IRoutes) {
-	router := r.Group("workflow").Use(authMiddleware.MiddlewareFunc()).Use(middleware.CasbinMiddleware)
+	router := r.Group("workflow").Use(middleware.AuthMiddleware(authMiddleware)).Use(middleware.CasbinMiddleware)
 	{
 		router.GET("/list", v1.GetWorkflows)
 		router.GET("/line/list", v1.GetWorkflowLines)
+		router.GET("/edge/list", v1.GetWorkflowEdges)
 		router.GET("/approving/list", v1.GetWorkflowApprovings)
+		router.GET("/delegate/list", v1.GetWorkflowDelegates)
+		router.POST("/delegate/create", v1.CreateWorkflowDelegate)
+		router.DELETE("/delegate/delete/batch", v1.BatchDeleteWorkflowDelegateByIds)
 		router.POST("/create", v1.CreateWorkflow)
 		router.PATCH("/update/:workflowId", v1.UpdateWorkflowById)
 		router.PATCH("/log/approval", v1.UpdateWorkflowLogApproval)
 		router.DELETE("/delete/batch", v1.BatchDeleteWorkflowByIds)
 		router.PATCH("/line/update", v1.UpdateWorkflowLineIncremental)
+		router.PATCH("/edge/update", v1.UpdateWorkflowEdges)
 	}
 	return router
 }
@@ -24,6 +24,8 @@ func Mysql() {
 	}
 	// 打印所有执行的sql
 	db.LogMode(global.Conf.Mysql.LogMode)
+	// 按租户隔离数据
+	models.RegisterTenantCallbacks(db)
 	global.Mysql = db
 	// 表结构
 	autoMigrate()